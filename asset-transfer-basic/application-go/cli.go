@@ -0,0 +1,358 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// Exit codes returned by the CLI so that shell scripts can react to the
+// different kinds of failure
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitNotFound     = 3
+	exitConflict     = 4
+	exitEndorsement  = 5
+	exitUnauthorized = 6
+	exitValidation   = 7
+)
+
+// command is a CLI subcommand that invokes one chaincode function
+type command struct {
+	name        string
+	args        []string
+	description string
+	submit      bool
+	function    string
+
+	// flags optionally registers command specific flags and returns a function
+	// that turns the command line arguments into the chaincode arguments
+	flags func(fs *flag.FlagSet) func(args []string) ([]string, error)
+	// check optionally turns a successful result into a failure
+	check func(result []byte) error
+}
+
+var commands = []*command{
+	{
+		name:        "create",
+		args:        []string{"id", "color", "size", "owner", "appraisedValue"},
+		description: "create a new asset",
+		submit:      true,
+		function:    "CreateAsset",
+	},
+	{
+		name:        "read",
+		args:        []string{"id"},
+		description: "read an asset",
+		function:    "ReadAsset",
+	},
+	{
+		name:        "update",
+		args:        []string{"id", "color", "size", "owner", "appraisedValue"},
+		description: "replace the details of an asset",
+		submit:      true,
+		function:    "UpdateAsset",
+	},
+	{
+		name:        "transfer",
+		args:        []string{"id", "newOwner"},
+		description: "transfer an asset to a new owner",
+		submit:      true,
+		function:    "TransferAsset",
+	},
+	{
+		name:        "delete",
+		args:        []string{"id"},
+		description: "delete an asset, keeping a tombstone",
+		submit:      true,
+		function:    "DeleteAsset",
+	},
+	{
+		name:        "delete-with-reason",
+		args:        []string{"id", "reason"},
+		description: "delete an asset, recording the reason in its tombstone",
+		submit:      true,
+		function:    "DeleteAssetWithReason",
+	},
+	{
+		name:        "list",
+		description: "list all assets",
+		function:    "GetAllAssets",
+	},
+	{
+		name:        "history",
+		args:        []string{"id"},
+		description: "show the history of an asset",
+		function:    "GetAssetHistory",
+	},
+	{
+		name:        "as-of",
+		args:        []string{"id", "timestamp"},
+		description: "read an asset as it was at an RFC 3339 time",
+		function:    "GetAssetAsOf",
+	},
+	{
+		name:        "at-tx",
+		args:        []string{"id", "txId"},
+		description: "read the value of an asset written by a transaction",
+		function:    "GetAssetAtTx",
+	},
+	{
+		name:        "diff-history",
+		args:        []string{"id"},
+		description: "show the fields each transaction changed in an asset",
+		function:    "DiffAssetHistory",
+	},
+	{
+		name:        "attach-document",
+		args:        []string{"id", "docType", "file", "uri"},
+		description: "anchor the SHA-256 hash of a local file to an asset",
+		submit:      true,
+		function:    "AttachDocument",
+		flags:       hashFileArg(2),
+	},
+	{
+		name:        "verify-document",
+		args:        []string{"id", "file"},
+		description: "check a local file against the documents attached to an asset",
+		function:    "VerifyDocument",
+		flags:       hashFileArg(1),
+		check: func(result []byte) error {
+			if string(result) != "true" {
+				return fmt.Errorf("the file does not match any document attached to the asset")
+			}
+			return nil
+		},
+	},
+}
+
+// transientFlag collects repeated -transient key=value flags. A value starting
+// with @ names a file whose contents are used instead.
+type transientFlag map[string][]byte
+
+func (t transientFlag) String() string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func (t transientFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value or key=@file, got %q", value)
+	}
+
+	if strings.HasPrefix(parts[1], "@") {
+		contents, err := ioutil.ReadFile(filepath.Clean(parts[1][1:]))
+		if err != nil {
+			return err
+		}
+		t[parts[0]] = contents
+		return nil
+	}
+
+	t[parts[0]] = []byte(parts[1])
+	return nil
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s [connection flags] <command> [command flags] [args]\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(w, "  %-16s %s\n", "listen", "print asset writes and chaincode events as they are committed")
+	fmt.Fprintf(w, "  %-16s %s\n", "mirror", "keep an SQLite database in sync with the assets on the ledger")
+	fmt.Fprintln(w, "\nWithout a command, a demonstration of the asset-transfer-basic contract is run.")
+	fmt.Fprintln(w, "Run with -h to list the connection flags, or <command> -h for the command flags.")
+}
+
+// invocation is a parsed subcommand command line
+type invocation struct {
+	cmd       *command
+	output    string
+	transient transientFlag
+	// args are the chaincode function arguments
+	args []string
+}
+
+// parseCommand parses the command line of a subcommand, writing any problem
+// to stderr. It returns nil and the process exit code if the command line is
+// invalid or only asks for help.
+func parseCommand(args []string, stderr io.Writer) (*invocation, int) {
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		printUsage(stderr)
+		return nil, exitUsage
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] %s\n\n%s\n\nflags:\n", cmd.name, strings.Join(cmd.args, " "), cmd.description)
+		fs.PrintDefaults()
+	}
+	inv := &invocation{cmd: cmd, output: "json", transient: transientFlag{}}
+	fs.StringVar(&inv.output, "output", inv.output, "output format: "+strings.Join(outputFormats, ", "))
+	fs.StringVar(&inv.output, "o", inv.output, "shorthand for -output")
+	fs.Var(inv.transient, "transient", "transient data passed to the chaincode as key=value or key=@file; may be repeated")
+	var buildArgs func(args []string) ([]string, error)
+	if cmd.flags != nil {
+		buildArgs = cmd.flags(fs)
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return nil, exitOK
+		}
+		return nil, exitUsage
+	}
+	if fs.NArg() != len(cmd.args) {
+		fmt.Fprintf(stderr, "%s expects %d arguments, got %d\n\n", cmd.name, len(cmd.args), fs.NArg())
+		fs.Usage()
+		return nil, exitUsage
+	}
+	if !isOutputFormat(inv.output) {
+		fmt.Fprintf(stderr, "unknown output format %q, expected one of %s\n", inv.output, strings.Join(outputFormats, ", "))
+		return nil, exitUsage
+	}
+
+	inv.args = fs.Args()
+	if buildArgs != nil {
+		var err error
+		inv.args, err = buildArgs(inv.args)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil, exitError
+		}
+	}
+
+	return inv, exitOK
+}
+
+// runCommand parses the command line of a subcommand, connects by calling
+// connect and invokes the chaincode function, writing the result to stdout.
+// It returns the process exit code.
+func runCommand(args []string, connect func() (*gateway.Contract, func(), error)) int {
+	inv, code := parseCommand(args, os.Stderr)
+	if inv == nil {
+		return code
+	}
+	cmd := inv.cmd
+
+	contract, closeConnection, err := connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer closeConnection()
+
+	result, err := invoke(contract, cmd.submit, cmd.function, inv.transient, inv.args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", cmd.function, err)
+		return exitCode(err)
+	}
+
+	if err := writeResult(os.Stdout, inv.output, result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if cmd.check != nil {
+		if err := cmd.check(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+
+	return exitOK
+}
+
+// invoke submits or evaluates a transaction, passing any transient data
+func invoke(contract *gateway.Contract, submit bool, function string, transient map[string][]byte, args []string) ([]byte, error) {
+	var options []gateway.TransactionOption
+	if len(transient) > 0 {
+		options = append(options, gateway.WithTransient(transient))
+	}
+
+	txn, err := contract.CreateTransaction(function, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if submit {
+		return txn.Submit(args...)
+	}
+	return txn.Evaluate(args...)
+}
+
+// exitCode maps a transaction error to the exit code reported by the CLI
+func exitCode(err error) int {
+	switch errorcode.CodeOf(err) {
+	case errorcode.NotFound:
+		return exitNotFound
+	case errorcode.AlreadyExists, errorcode.Conflict:
+		return exitConflict
+	case errorcode.Unauthorized:
+		return exitUnauthorized
+	case errorcode.Validation:
+		return exitValidation
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return exitError
+	}
+
+	switch s.Group {
+	case status.EventServerStatus:
+		switch peer.TxValidationCode(s.Code) {
+		case peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT:
+			return exitConflict
+		case peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE:
+			return exitEndorsement
+		}
+	case status.EndorserServerStatus, status.EndorserClientStatus, status.ChaincodeStatus:
+		return exitEndorsement
+	}
+
+	return exitError
+}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTempFile writes contents to a file that is removed when the test ends
+func writeTempFile(t *testing.T, name string, contents string) string {
+	dir, err := ioutil.TempDir("", "application-go")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestParseCommand(t *testing.T) {
+	secret := writeTempFile(t, "secret.json", `{"salt":"abc"}`)
+	invoice := writeTempFile(t, "invoice.pdf", "test")
+
+	tests := []struct {
+		args      []string
+		function  string
+		submit    bool
+		output    string
+		transient map[string][]byte
+		chaincode []string
+	}{
+		{
+			args:      []string{"read", "asset1"},
+			function:  "ReadAsset",
+			output:    "json",
+			chaincode: []string{"asset1"},
+		},
+		{
+			args:      []string{"list", "-o", "table"},
+			function:  "GetAllAssets",
+			output:    "table",
+			chaincode: []string{},
+		},
+		{
+			args:      []string{"transfer", "-output", "yaml", "asset1", "Tomoko"},
+			function:  "TransferAsset",
+			submit:    true,
+			output:    "yaml",
+			chaincode: []string{"asset1", "Tomoko"},
+		},
+		{
+			args:      []string{"create", "-transient", "note=hello", "-transient", "secret=@" + secret, "asset1", "blue", "5", "Tomoko", "300"},
+			function:  "CreateAsset",
+			submit:    true,
+			output:    "json",
+			transient: map[string][]byte{"note": []byte("hello"), "secret": []byte(`{"salt":"abc"}`)},
+			chaincode: []string{"asset1", "blue", "5", "Tomoko", "300"},
+		},
+		{
+			args:      []string{"attach-document", "asset1", "invoice", invoice, "https://example.com/invoice.pdf"},
+			function:  "AttachDocument",
+			submit:    true,
+			output:    "json",
+			chaincode: []string{"asset1", "invoice", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "https://example.com/invoice.pdf"},
+		},
+	}
+
+	for _, test := range tests {
+		var stderr bytes.Buffer
+		inv, code := parseCommand(test.args, &stderr)
+		require.Equal(t, exitOK, code, "%v: %s", test.args, stderr.String())
+		require.NotNil(t, inv, "%v", test.args)
+		require.Empty(t, stderr.String(), "%v", test.args)
+
+		require.Equal(t, test.function, inv.cmd.function, "%v", test.args)
+		require.Equal(t, test.submit, inv.cmd.submit, "%v", test.args)
+		require.Equal(t, test.output, inv.output, "%v", test.args)
+		require.Equal(t, test.chaincode, inv.args, "%v", test.args)
+		if test.transient == nil {
+			require.Empty(t, inv.transient, "%v", test.args)
+		} else {
+			require.Equal(t, transientFlag(test.transient), inv.transient, "%v", test.args)
+		}
+	}
+}
+
+func TestParseCommandErrors(t *testing.T) {
+	tests := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{args: []string{"frobnicate"}, code: exitUsage, stderr: `unknown command "frobnicate"`},
+		{args: []string{"read"}, code: exitUsage, stderr: "read expects 1 arguments, got 0"},
+		{args: []string{"transfer", "asset1", "Tomoko", "Max"}, code: exitUsage, stderr: "transfer expects 2 arguments, got 3"},
+		{args: []string{"read", "-o", "xml", "asset1"}, code: exitUsage, stderr: `unknown output format "xml", expected one of json, table, yaml`},
+		{args: []string{"read", "-transient", "novalue", "asset1"}, code: exitUsage, stderr: `expected key=value or key=@file, got "novalue"`},
+		{args: []string{"read", "-transient", "secret=@missing.json", "asset1"}, code: exitUsage, stderr: "missing.json"},
+		{args: []string{"read", "-verbose", "asset1"}, code: exitUsage, stderr: "flag provided but not defined: -verbose"},
+		{args: []string{"verify-document", "asset1", "missing.pdf"}, code: exitError, stderr: "missing.pdf"},
+		{args: []string{"read", "-h"}, code: exitOK, stderr: "usage: read [flags] id"},
+	}
+
+	for _, test := range tests {
+		var stderr bytes.Buffer
+		inv, code := parseCommand(test.args, &stderr)
+		require.Nil(t, inv, "%v", test.args)
+		require.Equal(t, test.code, code, "%v", test.args)
+		require.Contains(t, stderr.String(), test.stderr, "%v", test.args)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{err: errorcode.New(errorcode.NotFound, "the asset asset1 does not exist"), code: exitNotFound},
+		{err: errorcode.New(errorcode.AlreadyExists, "the asset asset1 already exists"), code: exitConflict},
+		{err: errorcode.New(errorcode.Conflict, "the asset asset1 is reserved"), code: exitConflict},
+		{err: errorcode.New(errorcode.Unauthorized, "client other does not own the asset asset1"), code: exitUnauthorized},
+		{err: errorcode.New(errorcode.Validation, "size must be >= 1"), code: exitValidation},
+		// the coded error is found in the message of the failed transaction
+		{err: fmt.Errorf("Failed to submit: %v", errorcode.New(errorcode.NotFound, "the asset asset1 does not exist")), code: exitNotFound},
+		{err: status.New(status.EventServerStatus, int32(peer.TxValidationCode_MVCC_READ_CONFLICT), "invalid transaction", nil), code: exitConflict},
+		{err: status.New(status.EventServerStatus, int32(peer.TxValidationCode_PHANTOM_READ_CONFLICT), "invalid transaction", nil), code: exitConflict},
+		{err: status.New(status.EventServerStatus, int32(peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE), "invalid transaction", nil), code: exitEndorsement},
+		{err: status.New(status.EventServerStatus, int32(peer.TxValidationCode_BAD_PAYLOAD), "invalid transaction", nil), code: exitError},
+		{err: status.New(status.EndorserServerStatus, 500, "endorsement failed", nil), code: exitEndorsement},
+		{err: status.New(status.ChaincodeStatus, 500, "chaincode failed", nil), code: exitEndorsement},
+		{err: errors.New("connection refused"), code: exitError},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.code, exitCode(test.err), "%v", test.err)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// hashFileArg replaces the file name at the given argument position with the
+// hex encoded SHA-256 digest of the file, so that documents can be anchored
+// to and verified against an asset without sending them to the ledger.
+func hashFileArg(position int) func(fs *flag.FlagSet) func(args []string) ([]string, error) {
+	return func(fs *flag.FlagSet) func(args []string) ([]string, error) {
+		return func(args []string) ([]string, error) {
+			digest, err := hashFile(args[position])
+			if err != nil {
+				return nil, err
+			}
+
+			hashed := append([]string{}, args...)
+			hashed[position] = digest
+			return hashed, nil
+		}
+	}
+}
+
+// hashFile returns the hex encoded SHA-256 digest of the file at path
+func hashFile(path string) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -2,4 +2,17 @@ module asset-transfer-basic
 
 go 1.14
 
-require github.com/hyperledger/fabric-sdk-go v1.0.0-beta3.0.20201006151309-9c426dcc5096
+require (
+	github.com/golang/protobuf v1.3.3
+	github.com/hyperledger/fabric-protos-go v0.0.0-20200707132912-fee30f3ccd23
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
+	github.com/hyperledger/fabric-samples/test-application/go v0.0.0
+	github.com/hyperledger/fabric-sdk-go v1.0.0-beta3.0.20201006151309-9c426dcc5096
+	github.com/mattn/go-sqlite3 v1.14.4
+	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.3.0
+)
+
+replace github.com/hyperledger/fabric-samples/test-application/go => ../../test-application/go
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"asset-transfer-basic/listener"
+	"asset-transfer-basic/mirror"
+
+	"github.com/hyperledger/fabric-samples/test-application/go/gatewayutil"
+)
+
+// runListen follows the blocks committed on the channel, printing the asset
+// writes and chaincode events of the chaincode as JSON lines until it is
+// interrupted. It returns the process exit code.
+func runListen(args []string, cfg *gatewayutil.Config) int {
+	fs := newListenerFlagSet("listen", "print asset writes and chaincode events as they are committed")
+	checkpoint := fs.String("checkpoint", "listener-checkpoint.json", "file recording the next block to process")
+	if code, ok := parseListenerFlags(fs, args); !ok {
+		return code
+	}
+
+	return runListener(cfg, listener.NewCheckpoint(*checkpoint), listener.NewLogHandler(os.Stdout))
+}
+
+// runMirror keeps an SQLite database in sync with the assets of the chaincode
+// until it is interrupted. It returns the process exit code.
+func runMirror(args []string, cfg *gatewayutil.Config) int {
+	fs := newListenerFlagSet("mirror", "keep an SQLite database in sync with the assets on the ledger")
+	db := fs.String("db", "assets.db", "SQLite database file")
+	replay := fs.Bool("replay", false, "empty the database and rebuild it from block 0")
+	if code, ok := parseListenerFlags(fs, args); !ok {
+		return code
+	}
+
+	m, err := mirror.Open(*db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer m.Close()
+
+	if *replay {
+		if err := m.Reset(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+
+	return runListener(cfg, m, m)
+}
+
+func newListenerFlagSet(name string, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags]\n\n%s\n\nflags:\n", name, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseListenerFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// runListener connects and dispatches blocks to the handlers until the
+// process is interrupted
+func runListener(cfg *gatewayutil.Config, checkpoint listener.Checkpointer, handlers ...listener.Handler) int {
+	conn, err := gatewayutil.Connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	l := &listener.Listener{
+		Source:     &listener.GatewayBlockSource{Network: conn.Network},
+		Checkpoint: checkpoint,
+		Chaincode:  cfg.Chaincode,
+		Handlers:   handlers,
+	}
+	if err := l.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	return exitOK
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package listener
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Transaction is an endorser transaction decoded from a block
+type Transaction struct {
+	BlockNumber    uint64
+	TxID           string
+	ValidationCode peer.TxValidationCode
+	Writes         []Write
+	Events         []*peer.ChaincodeEvent
+}
+
+// Valid reports whether the transaction was committed as valid, that is
+// whether its writes were applied to the world state
+func (t *Transaction) Valid() bool {
+	return t.ValidationCode == peer.TxValidationCode_VALID
+}
+
+// Write is a single key written or deleted by a transaction
+type Write struct {
+	Namespace string
+	Key       string
+	Value     []byte
+	IsDelete  bool
+}
+
+// DecodeBlock returns the endorser transactions in a block, together with
+// their validation codes, write sets and chaincode events. Configuration
+// transactions are skipped.
+func DecodeBlock(block *common.Block) ([]*Transaction, error) {
+	if block.Header == nil || block.Data == nil {
+		return nil, fmt.Errorf("block is missing its header or data")
+	}
+
+	var txFilter []byte
+	if block.Metadata != nil && len(block.Metadata.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		txFilter = block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var transactions []*Transaction
+	for i, envelopeBytes := range block.Data.Data {
+		envelope := &common.Envelope{}
+		if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal envelope %d of block %d: %v", i, block.Header.Number, err)
+		}
+
+		payload := &common.Payload{}
+		if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload %d of block %d: %v", i, block.Header.Number, err)
+		}
+		if payload.Header == nil {
+			continue
+		}
+
+		channelHeader := &common.ChannelHeader{}
+		if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal channel header %d of block %d: %v", i, block.Header.Number, err)
+		}
+		if common.HeaderType(channelHeader.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+
+		transaction := &Transaction{
+			BlockNumber:    block.Header.Number,
+			TxID:           channelHeader.TxId,
+			ValidationCode: peer.TxValidationCode_NOT_VALIDATED,
+		}
+		if i < len(txFilter) {
+			transaction.ValidationCode = peer.TxValidationCode(txFilter[i])
+		}
+
+		err := decodeActions(payload.Data, transaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %s: %v", transaction.TxID, err)
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
+
+func decodeActions(data []byte, transaction *Transaction) error {
+	tx := &peer.Transaction{}
+	if err := proto.Unmarshal(data, tx); err != nil {
+		return err
+	}
+
+	for _, action := range tx.Actions {
+		actionPayload := &peer.ChaincodeActionPayload{}
+		if err := proto.Unmarshal(action.Payload, actionPayload); err != nil {
+			return err
+		}
+		if actionPayload.Action == nil {
+			continue
+		}
+
+		responsePayload := &peer.ProposalResponsePayload{}
+		if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, responsePayload); err != nil {
+			return err
+		}
+
+		chaincodeAction := &peer.ChaincodeAction{}
+		if err := proto.Unmarshal(responsePayload.Extension, chaincodeAction); err != nil {
+			return err
+		}
+
+		if len(chaincodeAction.Events) > 0 {
+			event := &peer.ChaincodeEvent{}
+			if err := proto.Unmarshal(chaincodeAction.Events, event); err != nil {
+				return err
+			}
+			transaction.Events = append(transaction.Events, event)
+		}
+
+		txRWSet := &rwset.TxReadWriteSet{}
+		if err := proto.Unmarshal(chaincodeAction.Results, txRWSet); err != nil {
+			return err
+		}
+
+		for _, nsRWSet := range txRWSet.NsRwset {
+			kvRWSet := &kvrwset.KVRWSet{}
+			if err := proto.Unmarshal(nsRWSet.Rwset, kvRWSet); err != nil {
+				return err
+			}
+
+			for _, write := range kvRWSet.Writes {
+				transaction.Writes = append(transaction.Writes, Write{
+					Namespace: nsRWSet.Namespace,
+					Key:       write.Key,
+					Value:     write.Value,
+					IsDelete:  write.IsDelete,
+				})
+			}
+		}
+	}
+
+	return nil
+}
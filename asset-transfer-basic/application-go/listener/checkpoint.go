@@ -0,0 +1,73 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records the next block to process in a local file so that a
+// listener can resume where it stopped after a restart
+type Checkpoint struct {
+	path string
+}
+
+type checkpointFile struct {
+	NextBlock uint64 `json:"nextBlock"`
+}
+
+// NewCheckpoint returns a checkpoint kept in the file at path. The file is
+// created on the first call to Save.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path: path}
+}
+
+// NextBlock returns the number of the first block that has not been processed,
+// which is 0 when there is no checkpoint file yet
+func (c *Checkpoint) NextBlock() (uint64, error) {
+	contents, err := ioutil.ReadFile(filepath.Clean(c.path))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	var checkpoint checkpointFile
+	err = json.Unmarshal(contents, &checkpoint)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse checkpoint %s: %v", c.path, err)
+	}
+
+	return checkpoint.NextBlock, nil
+}
+
+// Save records that the block with the given number has been processed. The
+// file is replaced atomically so that a crash never leaves it half written.
+func (c *Checkpoint) Save(blockNumber uint64) error {
+	contents, err := json.Marshal(checkpointFile{NextBlock: blockNumber + 1})
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	err = ioutil.WriteFile(tmp, contents, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+
+	err = os.Rename(tmp, c.path)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+
+	return nil
+}
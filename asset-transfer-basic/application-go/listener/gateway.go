@@ -0,0 +1,101 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package listener
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// GatewayBlockSource delivers blocks through a gateway network connection.
+// Block events only start at the newest block, so blocks committed while the
+// listener was stopped are fetched from the query system chaincode first.
+type GatewayBlockSource struct {
+	Network *gateway.Network
+}
+
+// Blocks implements BlockSource
+func (s *GatewayBlockSource) Blocks(ctx context.Context, startBlock uint64) (<-chan *common.Block, <-chan error) {
+	blocks := make(chan *common.Block)
+	errs := make(chan error, 1)
+
+	registration, events, err := s.Network.RegisterBlockEvent()
+	if err != nil {
+		errs <- fmt.Errorf("failed to register for block events: %v", err)
+		close(blocks)
+		return blocks, errs
+	}
+
+	qscc := s.Network.GetContract("qscc")
+
+	go func() {
+		defer close(blocks)
+		defer s.Network.Unregister(registration)
+
+		send := func(block *common.Block) bool {
+			select {
+			case blocks <- block:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		next := startBlock
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				number := event.Block.Header.Number
+				for ; next < number; next++ {
+					block, err := s.fetchBlock(qscc, next)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if !send(block) {
+						return
+					}
+				}
+
+				if number == next {
+					if !send(event.Block) {
+						return
+					}
+					next++
+				}
+			}
+		}
+	}()
+
+	return blocks, errs
+}
+
+func (s *GatewayBlockSource) fetchBlock(qscc *gateway.Contract, number uint64) (*common.Block, error) {
+	result, err := qscc.EvaluateTransaction("GetBlockByNumber", s.Network.Name(), strconv.FormatUint(number, 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block %d: %v", number, err)
+	}
+
+	block := &common.Block{}
+	err = proto.Unmarshal(result, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block %d: %v", number, err)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package listener follows the blocks committed on a channel, decodes the
+// asset writes and chaincode events of one chaincode and hands them to
+// pluggable handlers, checkpointing its progress so that it can resume after
+// a restart.
+package listener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+)
+
+// compositeKeyNamespace prefixes composite keys, such as tombstones, which are
+// not assets themselves
+const compositeKeyNamespace = "\x00"
+
+// BlockSource delivers the committed blocks of a channel in order, starting at
+// a given block number, until the context is cancelled
+type BlockSource interface {
+	Blocks(ctx context.Context, startBlock uint64) (<-chan *common.Block, <-chan error)
+}
+
+// Checkpointer records the progress of a listener. Checkpoint keeps it in a
+// file; a handler that persists its own state may implement it to checkpoint
+// atomically with that state.
+type Checkpointer interface {
+	NextBlock() (uint64, error)
+	Save(blockNumber uint64) error
+}
+
+// Asset mirrors the asset stored by the asset-transfer-basic chaincode
+type Asset struct {
+	ID             string `json:"ID"`
+	Color          string `json:"color"`
+	Size           int    `json:"size"`
+	Owner          string `json:"owner"`
+	AppraisedValue int    `json:"appraisedValue"`
+}
+
+// AssetWrite is an asset created, updated or deleted by a valid transaction
+type AssetWrite struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txId"`
+	Key         string `json:"key"`
+	Asset       *Asset `json:"asset,omitempty"`
+	IsDelete    bool   `json:"isDelete"`
+}
+
+// ChaincodeEvent is an event set by the chaincode in a valid transaction
+type ChaincodeEvent struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txId"`
+	EventName   string `json:"eventName"`
+	Payload     []byte `json:"payload"`
+}
+
+// Handler receives what the listener decodes from each block
+type Handler interface {
+	HandleAssetWrite(write *AssetWrite) error
+	HandleChaincodeEvent(event *ChaincodeEvent) error
+	// HandleBlockEnd is called after every write and event of a block has been
+	// handled, before the block is checkpointed
+	HandleBlockEnd(blockNumber uint64) error
+}
+
+// Listener dispatches the asset writes and chaincode events of a chaincode to
+// its handlers, one block at a time
+type Listener struct {
+	Source     BlockSource
+	Checkpoint Checkpointer
+	Chaincode  string
+	Handlers   []Handler
+}
+
+// Run processes blocks from the checkpoint onwards until the context is
+// cancelled or a block cannot be handled. A block is checkpointed only after
+// all handlers have processed it, so a failed block is retried on restart.
+func (l *Listener) Run(ctx context.Context) error {
+	startBlock, err := l.Checkpoint.NextBlock()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	blocks, errs := l.Source.Blocks(ctx, startBlock)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if err != nil {
+				return err
+			}
+		case block, ok := <-blocks:
+			if !ok {
+				// a source sends its error before closing blocks, and select
+				// may pick the closed channel first
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return nil
+				}
+			}
+			if err := l.ProcessBlock(block); err != nil {
+				return err
+			}
+			if err := l.Checkpoint.Save(block.Header.Number); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ProcessBlock decodes a block and dispatches the writes and events of its
+// valid transactions to the handlers
+func (l *Listener) ProcessBlock(block *common.Block) error {
+	transactions, err := DecodeBlock(block)
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		if !transaction.Valid() {
+			continue
+		}
+
+		for _, write := range transaction.Writes {
+			if write.Namespace != l.Chaincode || strings.HasPrefix(write.Key, compositeKeyNamespace) {
+				continue
+			}
+
+			assetWrite := &AssetWrite{
+				BlockNumber: transaction.BlockNumber,
+				TxID:        transaction.TxID,
+				Key:         write.Key,
+				IsDelete:    write.IsDelete,
+			}
+			if !write.IsDelete {
+				assetWrite.Asset = &Asset{}
+				if err := json.Unmarshal(write.Value, assetWrite.Asset); err != nil {
+					return fmt.Errorf("failed to decode asset %s written by transaction %s: %v", write.Key, transaction.TxID, err)
+				}
+			}
+
+			for _, handler := range l.Handlers {
+				if err := handler.HandleAssetWrite(assetWrite); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, event := range transaction.Events {
+			if event.ChaincodeId != l.Chaincode {
+				continue
+			}
+
+			chaincodeEvent := &ChaincodeEvent{
+				BlockNumber: transaction.BlockNumber,
+				TxID:        transaction.TxID,
+				EventName:   event.EventName,
+				Payload:     event.Payload,
+			}
+			for _, handler := range l.Handlers {
+				if err := handler.HandleChaincodeEvent(chaincodeEvent); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, handler := range l.Handlers {
+		if err := handler.HandleBlockEnd(block.Header.Number); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
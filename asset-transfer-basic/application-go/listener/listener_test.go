@@ -0,0 +1,220 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package listener_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"asset-transfer-basic/listener"
+	"asset-transfer-basic/listener/listenertest"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+)
+
+// tempDir returns a directory that is removed when the test ends
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "listener")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func newBlock(t *testing.T, number uint64, txs ...listenertest.Tx) *common.Block {
+	for i := range txs {
+		txs[i].Chaincode = "basic"
+	}
+	block, err := listenertest.NewBlock(number, txs...)
+	require.NoError(t, err)
+	return block
+}
+
+func assetJSON(t *testing.T, asset listener.Asset) []byte {
+	bytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	return bytes
+}
+
+type recordingHandler struct {
+	writes []*listener.AssetWrite
+	events []*listener.ChaincodeEvent
+	blocks []uint64
+}
+
+func (h *recordingHandler) HandleAssetWrite(write *listener.AssetWrite) error {
+	h.writes = append(h.writes, write)
+	return nil
+}
+
+func (h *recordingHandler) HandleChaincodeEvent(event *listener.ChaincodeEvent) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHandler) HandleBlockEnd(blockNumber uint64) error {
+	h.blocks = append(h.blocks, blockNumber)
+	return nil
+}
+
+func TestDecodeBlock(t *testing.T) {
+	asset := listener.Asset{ID: "asset1", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300}
+	block := newBlock(t, 7,
+		listenertest.Tx{
+			TxID:           "tx1",
+			ValidationCode: peer.TxValidationCode_VALID,
+			Writes:         []*kvrwset.KVWrite{{Key: "asset1", Value: assetJSON(t, asset)}},
+			Event:          &peer.ChaincodeEvent{ChaincodeId: "basic", TxId: "tx1", EventName: "CreateAsset"},
+		},
+		listenertest.Tx{
+			TxID:           "tx2",
+			ValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT,
+			Writes:         []*kvrwset.KVWrite{{Key: "asset2", IsDelete: true}},
+		},
+	)
+
+	transactions, err := listener.DecodeBlock(block)
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+
+	require.Equal(t, uint64(7), transactions[0].BlockNumber)
+	require.Equal(t, "tx1", transactions[0].TxID)
+	require.True(t, transactions[0].Valid())
+	require.Equal(t, []listener.Write{{Namespace: "basic", Key: "asset1", Value: assetJSON(t, asset)}}, transactions[0].Writes)
+	require.Len(t, transactions[0].Events, 1)
+	require.Equal(t, "CreateAsset", transactions[0].Events[0].EventName)
+
+	require.False(t, transactions[1].Valid())
+	require.Equal(t, []listener.Write{{Namespace: "basic", Key: "asset2", IsDelete: true}}, transactions[1].Writes)
+}
+
+func TestProcessBlock(t *testing.T) {
+	asset := listener.Asset{ID: "asset1", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300}
+	block := newBlock(t, 3,
+		listenertest.Tx{
+			TxID:           "tx1",
+			ValidationCode: peer.TxValidationCode_VALID,
+			Writes: []*kvrwset.KVWrite{
+				{Key: "asset1", Value: assetJSON(t, asset)},
+				{Key: "\x00tombstone\x00asset9\x00", Value: []byte("{}")},
+				{Key: "asset9", IsDelete: true},
+			},
+			Event: &peer.ChaincodeEvent{ChaincodeId: "basic", EventName: "Transfer", Payload: []byte("payload")},
+		},
+		listenertest.Tx{
+			TxID:           "tx2",
+			ValidationCode: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE,
+			Writes:         []*kvrwset.KVWrite{{Key: "asset2", Value: []byte("not an asset")}},
+		},
+	)
+
+	handler := &recordingHandler{}
+	l := &listener.Listener{Chaincode: "basic", Handlers: []listener.Handler{handler}}
+	err := l.ProcessBlock(block)
+	require.NoError(t, err)
+
+	require.Equal(t, []*listener.AssetWrite{
+		{BlockNumber: 3, TxID: "tx1", Key: "asset1", Asset: &asset},
+		{BlockNumber: 3, TxID: "tx1", Key: "asset9", IsDelete: true},
+	}, handler.writes)
+	require.Equal(t, []*listener.ChaincodeEvent{
+		{BlockNumber: 3, TxID: "tx1", EventName: "Transfer", Payload: []byte("payload")},
+	}, handler.events)
+	require.Equal(t, []uint64{3}, handler.blocks)
+
+	l.Chaincode = "other"
+	handler.writes = nil
+	err = l.ProcessBlock(block)
+	require.NoError(t, err)
+	require.Empty(t, handler.writes)
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	tx := func(id string) listenertest.Tx {
+		return listenertest.Tx{
+			TxID:           "tx-" + id,
+			ValidationCode: peer.TxValidationCode_VALID,
+			Writes:         []*kvrwset.KVWrite{{Key: id, Value: assetJSON(t, listener.Asset{ID: id})}},
+		}
+	}
+	source := &listenertest.BlockSource{}
+	source.Append(newBlock(t, 0), newBlock(t, 1, tx("asset1")), newBlock(t, 2, tx("asset2")))
+	checkpoint := listener.NewCheckpoint(filepath.Join(tempDir(t), "checkpoint.json"))
+
+	next, err := checkpoint.NextBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), next)
+
+	handler := &recordingHandler{}
+	l := &listener.Listener{Source: source, Checkpoint: checkpoint, Chaincode: "basic", Handlers: []listener.Handler{handler}}
+	err = l.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 1, 2}, handler.blocks)
+	require.Len(t, handler.writes, 2)
+
+	next, err = checkpoint.NextBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), next)
+
+	source.Append(newBlock(t, 3, tx("asset3")))
+	handler = &recordingHandler{}
+	l.Handlers = []listener.Handler{handler}
+	err = l.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 3}, source.Starts())
+	require.Equal(t, []uint64{3}, handler.blocks)
+	require.Equal(t, "asset3", handler.writes[0].Key)
+}
+
+// failingSource reports an error and closes its blocks, as GatewayBlockSource
+// does when a block cannot be fetched
+type failingSource struct {
+	err error
+}
+
+func (s failingSource) Blocks(ctx context.Context, startBlock uint64) (<-chan *common.Block, <-chan error) {
+	blocks := make(chan *common.Block)
+	errs := make(chan error, 1)
+	errs <- s.err
+	close(blocks)
+	return blocks, errs
+}
+
+type memoryCheckpoint struct{}
+
+func (memoryCheckpoint) NextBlock() (uint64, error) { return 0, nil }
+func (memoryCheckpoint) Save(uint64) error          { return nil }
+
+func TestRunReturnsSourceError(t *testing.T) {
+	l := &listener.Listener{Source: failingSource{err: errors.New("failed to fetch block 3")}, Checkpoint: memoryCheckpoint{}, Chaincode: "basic"}
+	for i := 0; i < 100; i++ {
+		require.EqualError(t, l.Run(context.Background()), "failed to fetch block 3", "the error is not lost when blocks is closed")
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := listener.NewLogHandler(&buffer)
+
+	err := handler.HandleAssetWrite(&listener.AssetWrite{BlockNumber: 1, TxID: "tx1", Key: "asset1", IsDelete: true})
+	require.NoError(t, err)
+	err = handler.HandleChaincodeEvent(&listener.ChaincodeEvent{BlockNumber: 1, TxID: "tx1", EventName: "Deleted"})
+	require.NoError(t, err)
+
+	require.Equal(t,
+		`{"type":"assetWrite","data":{"blockNumber":1,"txId":"tx1","key":"asset1","isDelete":true}}`+"\n"+
+			`{"type":"chaincodeEvent","data":{"blockNumber":1,"txId":"tx1","eventName":"Deleted","payload":null}}`+"\n",
+		buffer.String())
+}
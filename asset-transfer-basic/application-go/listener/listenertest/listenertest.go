@@ -0,0 +1,158 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package listenertest provides blocks and a block source that stand in for a
+// Fabric network when testing listener handlers.
+package listenertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Tx describes an endorser transaction of a single chaincode
+type Tx struct {
+	TxID           string
+	Chaincode      string
+	ValidationCode peer.TxValidationCode
+	Writes         []*kvrwset.KVWrite
+	Event          *peer.ChaincodeEvent
+}
+
+// NewBlock builds a block containing the given transactions, encoded the way
+// a peer delivers them
+func NewBlock(number uint64, txs ...Tx) (*common.Block, error) {
+	block := &common.Block{
+		Header:   &common.BlockHeader{Number: number},
+		Data:     &common.BlockData{},
+		Metadata: &common.BlockMetadata{Metadata: make([][]byte, len(common.BlockMetadataIndex_name))},
+	}
+
+	filter := make([]byte, len(txs))
+	for i, tx := range txs {
+		filter[i] = byte(tx.ValidationCode)
+
+		envelope, err := newEnvelope(tx)
+		if err != nil {
+			return nil, err
+		}
+		block.Data.Data = append(block.Data.Data, envelope)
+	}
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = filter
+
+	return block, nil
+}
+
+func newEnvelope(tx Tx) ([]byte, error) {
+	kvRWSet, err := proto.Marshal(&kvrwset.KVRWSet{Writes: tx.Writes})
+	if err != nil {
+		return nil, err
+	}
+	results, err := proto.Marshal(&rwset.TxReadWriteSet{
+		NsRwset: []*rwset.NsReadWriteSet{{Namespace: tx.Chaincode, Rwset: kvRWSet}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []byte
+	if tx.Event != nil {
+		events, err = proto.Marshal(tx.Event)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	extension, err := proto.Marshal(&peer.ChaincodeAction{Results: results, Events: events})
+	if err != nil {
+		return nil, err
+	}
+	responsePayload, err := proto.Marshal(&peer.ProposalResponsePayload{Extension: extension})
+	if err != nil {
+		return nil, err
+	}
+	actionPayload, err := proto.Marshal(&peer.ChaincodeActionPayload{
+		Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: responsePayload},
+	})
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := proto.Marshal(&peer.Transaction{
+		Actions: []*peer.TransactionAction{{Payload: actionPayload}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type: int32(common.HeaderType_ENDORSER_TRANSACTION),
+		TxId: tx.TxID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&common.Payload{
+		Header: &common.Header{ChannelHeader: channelHeader},
+		Data:   transaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&common.Envelope{Payload: payload})
+}
+
+// BlockSource replays the blocks appended to it, starting at the requested
+// block number, and closes its channel once they have all been delivered
+type BlockSource struct {
+	mutex  sync.Mutex
+	blocks []*common.Block
+	starts []uint64
+}
+
+// Append adds blocks to the end of the chain
+func (s *BlockSource) Append(blocks ...*common.Block) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.blocks = append(s.blocks, blocks...)
+}
+
+// Starts returns the start block of every call to Blocks
+func (s *BlockSource) Starts() []uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]uint64{}, s.starts...)
+}
+
+// Blocks implements listener.BlockSource
+func (s *BlockSource) Blocks(ctx context.Context, startBlock uint64) (<-chan *common.Block, <-chan error) {
+	s.mutex.Lock()
+	s.starts = append(s.starts, startBlock)
+	chain := append([]*common.Block{}, s.blocks...)
+	s.mutex.Unlock()
+
+	blocks := make(chan *common.Block)
+	go func() {
+		defer close(blocks)
+		for _, block := range chain {
+			if block.Header.Number < startBlock {
+				continue
+			}
+			select {
+			case blocks <- block:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return blocks, make(chan error)
+}
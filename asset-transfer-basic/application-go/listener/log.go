@@ -0,0 +1,42 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package listener
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// LogHandler writes every asset write and chaincode event as a line of JSON
+type LogHandler struct {
+	encoder *json.Encoder
+}
+
+// NewLogHandler returns a handler that writes to w
+func NewLogHandler(w io.Writer) *LogHandler {
+	return &LogHandler{encoder: json.NewEncoder(w)}
+}
+
+type logEntry struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// HandleAssetWrite implements Handler
+func (h *LogHandler) HandleAssetWrite(write *AssetWrite) error {
+	return h.encoder.Encode(logEntry{Type: "assetWrite", Data: write})
+}
+
+// HandleChaincodeEvent implements Handler
+func (h *LogHandler) HandleChaincodeEvent(event *ChaincodeEvent) error {
+	return h.encoder.Encode(logEntry{Type: "chaincodeEvent", Data: event})
+}
+
+// HandleBlockEnd implements Handler
+func (h *LogHandler) HandleBlockEnd(blockNumber uint64) error {
+	return nil
+}
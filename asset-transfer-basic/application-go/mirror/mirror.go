@@ -0,0 +1,255 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mirror keeps an SQLite database in sync with the assets of the
+// asset-transfer-basic chaincode, so that they can be reported on with SQL.
+// It is a listener handler: each block is applied in a single database
+// transaction together with the checkpoint, so the mirror never reflects a
+// partially applied block and can be rebuilt by replaying from block 0.
+package mirror
+
+import (
+	"database/sql"
+	"fmt"
+
+	"asset-transfer-basic/listener"
+
+	// registers the sqlite3 database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS assets (
+	id              TEXT PRIMARY KEY,
+	color           TEXT NOT NULL,
+	size            INTEGER NOT NULL,
+	owner           TEXT NOT NULL,
+	appraised_value INTEGER NOT NULL,
+	block_number    INTEGER NOT NULL,
+	tx_id           TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS asset_history (
+	seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+	id              TEXT NOT NULL,
+	block_number    INTEGER NOT NULL,
+	tx_id           TEXT NOT NULL,
+	is_delete       INTEGER NOT NULL,
+	color           TEXT,
+	size            INTEGER,
+	owner           TEXT,
+	appraised_value INTEGER
+);
+CREATE INDEX IF NOT EXISTS asset_history_id ON asset_history (id, seq);
+CREATE TABLE IF NOT EXISTS checkpoint (
+	id         INTEGER PRIMARY KEY CHECK (id = 0),
+	next_block INTEGER NOT NULL
+);
+INSERT OR IGNORE INTO checkpoint (id, next_block) VALUES (0, 0);
+`
+
+// Record is the state of an asset as mirrored, with the block and
+// transaction that last wrote it
+type Record struct {
+	listener.Asset
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txId"`
+	IsDelete    bool   `json:"isDelete,omitempty"`
+}
+
+// Mirror applies asset writes to an SQLite database. It implements both
+// listener.Handler and listener.Checkpointer.
+type Mirror struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// Open opens, creating if needed, the mirror database at path
+func Open(path string) (*Mirror, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror database: %v", err)
+	}
+	// a single connection keeps in-memory databases alive and serializes writes
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create mirror schema: %v", err)
+	}
+
+	return &Mirror{db: db}, nil
+}
+
+// Close closes the database, discarding any partially applied block
+func (m *Mirror) Close() error {
+	if m.tx != nil {
+		m.tx.Rollback()
+		m.tx = nil
+	}
+	return m.db.Close()
+}
+
+// Reset empties the mirror so that it is rebuilt from block 0
+func (m *Mirror) Reset() error {
+	_, err := m.db.Exec(`DELETE FROM assets; DELETE FROM asset_history; UPDATE checkpoint SET next_block = 0`)
+	if err != nil {
+		return fmt.Errorf("failed to reset mirror: %v", err)
+	}
+	return nil
+}
+
+// NextBlock implements listener.Checkpointer
+func (m *Mirror) NextBlock() (uint64, error) {
+	var next uint64
+	err := m.db.QueryRow(`SELECT next_block FROM checkpoint WHERE id = 0`).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read mirror checkpoint: %v", err)
+	}
+	return next, nil
+}
+
+// Save implements listener.Checkpointer. The checkpoint has already been
+// committed with the block by HandleBlockEnd.
+func (m *Mirror) Save(blockNumber uint64) error {
+	return nil
+}
+
+func (m *Mirror) begin() (*sql.Tx, error) {
+	if m.tx == nil {
+		tx, err := m.db.Begin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin mirror transaction: %v", err)
+		}
+		m.tx = tx
+	}
+	return m.tx, nil
+}
+
+// HandleAssetWrite implements listener.Handler
+func (m *Mirror) HandleAssetWrite(write *listener.AssetWrite) error {
+	tx, err := m.begin()
+	if err != nil {
+		return err
+	}
+
+	if write.IsDelete {
+		_, err = tx.Exec(`DELETE FROM assets WHERE id = ?`, write.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete asset %s: %v", write.Key, err)
+		}
+		_, err = tx.Exec(`INSERT INTO asset_history (id, block_number, tx_id, is_delete) VALUES (?, ?, ?, 1)`,
+			write.Key, write.BlockNumber, write.TxID)
+		if err != nil {
+			return fmt.Errorf("failed to record history of asset %s: %v", write.Key, err)
+		}
+		return nil
+	}
+
+	asset := write.Asset
+	_, err = tx.Exec(`INSERT OR REPLACE INTO assets (id, color, size, owner, appraised_value, block_number, tx_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		write.Key, asset.Color, asset.Size, asset.Owner, asset.AppraisedValue, write.BlockNumber, write.TxID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert asset %s: %v", write.Key, err)
+	}
+	_, err = tx.Exec(`INSERT INTO asset_history (id, block_number, tx_id, is_delete, color, size, owner, appraised_value) VALUES (?, ?, ?, 0, ?, ?, ?, ?)`,
+		write.Key, write.BlockNumber, write.TxID, asset.Color, asset.Size, asset.Owner, asset.AppraisedValue)
+	if err != nil {
+		return fmt.Errorf("failed to record history of asset %s: %v", write.Key, err)
+	}
+
+	return nil
+}
+
+// HandleChaincodeEvent implements listener.Handler. Events are not mirrored.
+func (m *Mirror) HandleChaincodeEvent(event *listener.ChaincodeEvent) error {
+	return nil
+}
+
+// HandleBlockEnd implements listener.Handler by committing the writes of the
+// block together with the checkpoint
+func (m *Mirror) HandleBlockEnd(blockNumber uint64) error {
+	tx, err := m.begin()
+	if err != nil {
+		return err
+	}
+	m.tx = nil
+
+	_, err = tx.Exec(`UPDATE checkpoint SET next_block = ? WHERE id = 0`, blockNumber+1)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update mirror checkpoint: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit block %d to the mirror: %v", blockNumber, err)
+	}
+
+	return nil
+}
+
+// Asset returns the mirrored state of an asset, or nil if it does not exist
+func (m *Mirror) Asset(id string) (*Record, error) {
+	record := &Record{}
+	err := m.db.QueryRow(`SELECT id, color, size, owner, appraised_value, block_number, tx_id FROM assets WHERE id = ?`, id).
+		Scan(&record.ID, &record.Color, &record.Size, &record.Owner, &record.AppraisedValue, &record.BlockNumber, &record.TxID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset %s: %v", id, err)
+	}
+	return record, nil
+}
+
+// Assets returns every mirrored asset ordered by ID
+func (m *Mirror) Assets() ([]*Record, error) {
+	rows, err := m.db.Query(`SELECT id, color, size, owner, appraised_value, block_number, tx_id FROM assets ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read assets: %v", err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		record := &Record{}
+		err := rows.Scan(&record.ID, &record.Color, &record.Size, &record.Owner, &record.AppraisedValue, &record.BlockNumber, &record.TxID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// History returns every write of an asset, oldest first
+func (m *Mirror) History(id string) ([]*Record, error) {
+	rows, err := m.db.Query(`SELECT id, block_number, tx_id, is_delete, color, size, owner, appraised_value FROM asset_history WHERE id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of asset %s: %v", id, err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		record := &Record{}
+		var color, owner sql.NullString
+		var size, appraisedValue sql.NullInt64
+		err := rows.Scan(&record.ID, &record.BlockNumber, &record.TxID, &record.IsDelete, &color, &size, &owner, &appraisedValue)
+		if err != nil {
+			return nil, err
+		}
+		record.Color = color.String
+		record.Size = int(size.Int64)
+		record.Owner = owner.String
+		record.AppraisedValue = int(appraisedValue.Int64)
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
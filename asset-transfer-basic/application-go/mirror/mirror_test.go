@@ -0,0 +1,168 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirror_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"asset-transfer-basic/listener"
+	"asset-transfer-basic/listener/listenertest"
+	"asset-transfer-basic/mirror"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+)
+
+// tempDir returns a directory that is removed when the test ends
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mirror")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func put(t *testing.T, asset listener.Asset) *kvrwset.KVWrite {
+	value, err := json.Marshal(asset)
+	require.NoError(t, err)
+	return &kvrwset.KVWrite{Key: asset.ID, Value: value}
+}
+
+func appendBlock(t *testing.T, source *listenertest.BlockSource, number uint64, txs ...listenertest.Tx) {
+	for i := range txs {
+		txs[i].Chaincode = "basic"
+	}
+	block, err := listenertest.NewBlock(number, txs...)
+	require.NoError(t, err)
+	source.Append(block)
+}
+
+func run(t *testing.T, source listener.BlockSource, m *mirror.Mirror) {
+	l := &listener.Listener{Source: source, Checkpoint: m, Chaincode: "basic", Handlers: []listener.Handler{m}}
+	require.NoError(t, l.Run(context.Background()))
+}
+
+func TestMirror(t *testing.T) {
+	asset1 := listener.Asset{ID: "asset1", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300}
+	asset2 := listener.Asset{ID: "asset2", Color: "red", Size: 5, Owner: "Brad", AppraisedValue: 400}
+	transferred := asset1
+	transferred.Owner = "Max"
+
+	source := &listenertest.BlockSource{}
+	appendBlock(t, source, 0)
+	appendBlock(t, source, 1, listenertest.Tx{
+		TxID:           "tx1",
+		ValidationCode: peer.TxValidationCode_VALID,
+		Writes:         []*kvrwset.KVWrite{put(t, asset1), put(t, asset2)},
+	})
+	appendBlock(t, source, 2,
+		listenertest.Tx{
+			TxID:           "tx2",
+			ValidationCode: peer.TxValidationCode_VALID,
+			Writes:         []*kvrwset.KVWrite{put(t, transferred)},
+		},
+		listenertest.Tx{
+			TxID:           "tx3",
+			ValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT,
+			Writes:         []*kvrwset.KVWrite{{Key: "asset2", IsDelete: true}},
+		},
+	)
+
+	m, err := mirror.Open(filepath.Join(tempDir(t), "mirror.db"))
+	require.NoError(t, err)
+	defer m.Close()
+
+	run(t, source, m)
+
+	next, err := m.NextBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), next)
+
+	assets, err := m.Assets()
+	require.NoError(t, err)
+	require.Equal(t, []*mirror.Record{
+		{Asset: transferred, BlockNumber: 2, TxID: "tx2"},
+		{Asset: asset2, BlockNumber: 1, TxID: "tx1"},
+	}, assets)
+
+	appendBlock(t, source, 3, listenertest.Tx{
+		TxID:           "tx4",
+		ValidationCode: peer.TxValidationCode_VALID,
+		Writes:         []*kvrwset.KVWrite{{Key: "asset2", IsDelete: true}},
+	})
+	run(t, source, m)
+
+	record, err := m.Asset("asset2")
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	history, err := m.History("asset2")
+	require.NoError(t, err)
+	require.Equal(t, []*mirror.Record{
+		{Asset: asset2, BlockNumber: 1, TxID: "tx1"},
+		{Asset: listener.Asset{ID: "asset2"}, BlockNumber: 3, TxID: "tx4", IsDelete: true},
+	}, history)
+
+	history, err = m.History("asset1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	require.NoError(t, m.Reset())
+	next, err = m.NextBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), next)
+
+	run(t, source, m)
+	require.Equal(t, []uint64{0, 3, 0}, source.Starts())
+
+	assets, err = m.Assets()
+	require.NoError(t, err)
+	require.Equal(t, []*mirror.Record{{Asset: transferred, BlockNumber: 2, TxID: "tx2"}}, assets)
+
+	history, err = m.History("asset1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+}
+
+func TestMirrorDiscardsFailedBlock(t *testing.T) {
+	source := &listenertest.BlockSource{}
+	appendBlock(t, source, 0, listenertest.Tx{
+		TxID:           "tx1",
+		ValidationCode: peer.TxValidationCode_VALID,
+		Writes: []*kvrwset.KVWrite{
+			put(t, listener.Asset{ID: "asset1", Color: "blue"}),
+			{Key: "asset2", Value: []byte("not an asset")},
+		},
+	})
+
+	path := filepath.Join(tempDir(t), "mirror.db")
+	m, err := mirror.Open(path)
+	require.NoError(t, err)
+
+	l := &listener.Listener{Source: source, Checkpoint: m, Chaincode: "basic", Handlers: []listener.Handler{m}}
+	err = l.Run(context.Background())
+	require.EqualError(t, err, "failed to decode asset asset2 written by transaction tx1: invalid character 'o' in literal null (expecting 'u')")
+
+	require.NoError(t, m.Close())
+
+	m, err = mirror.Open(path)
+	require.NoError(t, err)
+	defer m.Close()
+
+	record, err := m.Asset("asset1")
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	next, err := m.NextBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), next)
+}
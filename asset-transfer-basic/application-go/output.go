@@ -0,0 +1,169 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// outputFormats lists the values accepted by the -output flag
+var outputFormats = []string{"json", "table", "yaml"}
+
+// writeResult prints a transaction result in the requested format. Results
+// that are not JSON, such as empty submit responses, are printed unchanged.
+func writeResult(w io.Writer, format string, result []byte) error {
+	if len(bytes.TrimSpace(result)) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		_, err = fmt.Fprintln(w, string(result))
+		return err
+	}
+
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+
+	case "yaml":
+		out, err := yaml.Marshal(toYAMLValue(value))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+
+	case "table":
+		return writeTable(w, value)
+	}
+
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// writeTable prints an object, or a list of objects, with one row per object
+// and one column per field. Nested values are printed as compact JSON.
+func writeTable(w io.Writer, value interface{}) error {
+	var rows []map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rows = append(rows, v)
+	case []interface{}:
+		for _, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				row = map[string]interface{}{"value": item}
+			}
+			rows = append(rows, row)
+		}
+	default:
+		_, err := fmt.Fprintln(w, formatCell(v))
+		return err
+	}
+
+	columns := tableColumns(rows)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if cell, ok := row[column]; ok {
+				cells[i] = formatCell(cell)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// tableColumns returns the union of the row fields, with ID first and the
+// rest sorted so that the layout is stable between runs
+func tableColumns(rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i] == "ID" || columns[j] == "ID" {
+			return columns[i] == "ID"
+		}
+		return columns[i] < columns[j]
+	})
+
+	return columns
+}
+
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+// toYAMLValue converts JSON numbers so that they are written as YAML numbers
+// rather than quoted strings
+func toYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = toYAMLValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = toYAMLValue(item)
+		}
+		return converted
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+
+	return value
+}
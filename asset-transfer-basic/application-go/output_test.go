@@ -0,0 +1,76 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const assetsResult = `[{"ID":"asset1","color":"blue","size":5,"owner":"Tomoko","appraisedValue":300},` +
+	`{"ID":"asset2","color":"red","size":5,"owner":"Brad","appraisedValue":400.5,"reservation":{"reservedFor":"Max"}}]`
+
+func TestWriteResult(t *testing.T) {
+	tests := []struct {
+		format string
+		result string
+		output string
+	}{
+		{
+			format: "json",
+			result: `{"ID":"asset1","size":5}`,
+			output: "{\n  \"ID\": \"asset1\",\n  \"size\": 5\n}\n",
+		},
+		{
+			format: "yaml",
+			result: assetsResult,
+			output: `- ID: asset1
+  appraisedValue: 300
+  color: blue
+  owner: Tomoko
+  size: 5
+- ID: asset2
+  appraisedValue: 400.5
+  color: red
+  owner: Brad
+  reservation:
+    reservedFor: Max
+  size: 5
+`,
+		},
+		{
+			format: "table",
+			result: assetsResult,
+			output: `ID      APPRAISEDVALUE  COLOR  OWNER   RESERVATION            SIZE
+asset1  300             blue   Tomoko                         5
+asset2  400.5           red    Brad    {"reservedFor":"Max"}  5
+`,
+		},
+		{
+			format: "table",
+			result: `["asset1",{"ID":"asset2"}]`,
+			output: "ID      VALUE\n        asset1\nasset2  \n",
+		},
+		{format: "table", result: `true`, output: "true\n"},
+		// results that are not JSON are printed unchanged in every format
+		{format: "yaml", result: `not json`, output: "not json\n"},
+		{format: "json", result: "", output: ""},
+		{format: "table", result: " \n", output: ""},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		require.NoError(t, writeResult(&out, test.format, []byte(test.result)), "%s %s", test.format, test.result)
+		require.Equal(t, test.output, out.String(), "%s %s", test.format, test.result)
+	}
+
+	var out bytes.Buffer
+	err := writeResult(&out, "xml", []byte(`{}`))
+	require.EqualError(t, err, `unknown output format "xml", expected one of json, table, yaml`)
+}
@@ -5,20 +5,20 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
 )
 
-type serverConfig struct {
-	CCID    string
-	Address string
-}
-
 // SmartContract provides functions for managing an asset
 type SmartContract struct {
 	contractapi.Contract
@@ -208,28 +208,68 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 }
 
 func main() {
-	// See chaincode.env.example
-	config := serverConfig{
-		CCID:    os.Getenv("CHAINCODE_ID"),
-		Address: os.Getenv("CHAINCODE_SERVER_ADDRESS"),
+	configFile := flag.String("config", os.Getenv("CHAINCODE_CONFIG_FILE"), "file of KEY=VALUE settings such as chaincode.env, overridden by environment variables")
+	flag.Parse()
+
+	var fileVars map[string]string
+	if *configFile != "" {
+		var err error
+		fileVars, err = serverconfig.ReadFile(*configFile)
+		if err != nil {
+			log.Fatalf("error loading asset-transfer-basic chaincode configuration: %s", err)
+		}
+	}
+
+	config, err := loadConfig(serverconfig.WithFallback(os.LookupEnv, fileVars))
+	if err != nil {
+		log.Fatalf("error loading asset-transfer-basic chaincode configuration: %s", err)
 	}
 
 	chaincode, err := contractapi.NewChaincode(&SmartContract{})
 
 	if err != nil {
-		log.Panicf("error create asset-transfer-basic chaincode: %s", err)
+		log.Fatalf("error create asset-transfer-basic chaincode: %s", err)
 	}
 
-	server := &shim.ChaincodeServer{
-		CCID:    config.CCID,
-		Address: config.Address,
-		CC:      chaincode,
-		TLSProps: shim.TLSProperties{
-			Disabled: true,
-		},
+	cc := &gracefulChaincode{cc: chaincode}
+	var metricsServer *http.Server
+	metricsErrs := make(chan error, 1)
+	if config.MetricsAddress != "" {
+		metrics := newChaincodeMetrics()
+		cc.cc = &instrumentedChaincode{cc: chaincode, metrics: metrics, functions: contractFunctions(&SmartContract{})}
+
+		metricsServer = &http.Server{Addr: config.MetricsAddress, Handler: newMetricsHandler(metrics, cc.ready)}
+		go func() {
+			metricsErrs <- metricsServer.ListenAndServe()
+		}()
 	}
 
-	if err := server.Start(); err != nil {
-		log.Panicf("error starting asset-transfer-basic chaincode: %s", err)
+	// a signal or a failure of the metrics server drains the chaincode
+	// server, and a failure is reported once it has stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	failures := make(chan error, 1)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("received %s", sig)
+		case err := <-metricsErrs:
+			failures <- fmt.Errorf("error serving asset-transfer-basic chaincode metrics: %s", err)
+		}
+		cancel()
+	}()
+
+	err = serve(ctx, config, cc)
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+	if err != nil {
+		log.Fatalf("error running asset-transfer-basic chaincode: %s", err)
+	}
+	select {
+	case err := <-failures:
+		log.Fatal(err)
+	default:
 	}
 }
@@ -0,0 +1,69 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
+)
+
+type serverConfig struct {
+	serverconfig.Config
+
+	// MetricsAddress optionally enables an HTTP port serving health,
+	// readiness and Prometheus metrics endpoints
+	MetricsAddress string
+
+	// ShutdownTimeout is how long in-flight transactions are given to
+	// complete after a termination signal
+	ShutdownTimeout time.Duration
+}
+
+// defaultShutdownTimeout leaves time to exit within the 10s grace period
+// docker stop gives a container before killing it
+const defaultShutdownTimeout = 8 * time.Second
+
+// loadConfig reads the server configuration using getenv. The settings shared
+// with other external chaincode are read by serverconfig.Load.
+func loadConfig(getenv func(string) string) (serverConfig, error) {
+	// See chaincode.env
+	base, err := serverconfig.Load(getenv)
+	if err != nil {
+		return serverConfig{}, err
+	}
+	config := serverConfig{
+		Config:          base,
+		MetricsAddress:  getenv("CHAINCODE_METRICS_ADDRESS"),
+		ShutdownTimeout: defaultShutdownTimeout,
+	}
+
+	if value := getenv("CHAINCODE_SHUTDOWN_TIMEOUT"); value != "" {
+		config.ShutdownTimeout, err = time.ParseDuration(value)
+		if err != nil || config.ShutdownTimeout < 0 {
+			return config, fmt.Errorf("CHAINCODE_SHUTDOWN_TIMEOUT must be a duration such as 8s, got %q", value)
+		}
+	}
+
+	return config, config.validate()
+}
+
+// validate reports every problem with the configuration at once, so that the
+// server fails at startup rather than when the peer first connects
+func (c serverConfig) validate() error {
+	problems := c.Problems()
+	if c.MetricsAddress != "" {
+		if err := serverconfig.ValidateAddress(c.MetricsAddress); err != nil {
+			problems = append(problems, fmt.Sprintf("CHAINCODE_METRICS_ADDRESS %v", err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a getenv function for vars, defaulting the variables that must
+// always be set
+func env(vars map[string]string) func(string) string {
+	defaults := map[string]string{
+		"CHAINCODE_ID":             "basic_1.0:abc",
+		"CHAINCODE_SERVER_ADDRESS": "0.0.0.0:9999",
+	}
+	return func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return defaults[name]
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := loadConfig(env(nil))
+	require.NoError(t, err)
+	require.Equal(t, "basic_1.0:abc", config.CCID)
+	require.Equal(t, "0.0.0.0:9999", config.Address)
+	require.Empty(t, config.MetricsAddress)
+	require.Equal(t, defaultShutdownTimeout, config.ShutdownTimeout)
+	require.Less(t, int64(defaultShutdownTimeout), int64(10*time.Second), "docker stop kills the container after 10s")
+
+	config, err = loadConfig(env(map[string]string{
+		"CHAINCODE_METRICS_ADDRESS":  "0.0.0.0:9443",
+		"CHAINCODE_SHUTDOWN_TIMEOUT": "2s",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:9443", config.MetricsAddress)
+	require.Equal(t, 2*time.Second, config.ShutdownTimeout)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		vars map[string]string
+		err  string
+	}{
+		{
+			vars: map[string]string{"CHAINCODE_ID": "", "CHAINCODE_METRICS_ADDRESS": "localhost:http"},
+			err:  `invalid configuration: CHAINCODE_ID must be set to the package ID of the installed chaincode; CHAINCODE_METRICS_ADDRESS "localhost:http" does not have a port between 1 and 65535`,
+		},
+		{
+			vars: map[string]string{"CHAINCODE_SHUTDOWN_TIMEOUT": "30"},
+			err:  `CHAINCODE_SHUTDOWN_TIMEOUT must be a duration such as 8s, got "30"`,
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "no"},
+			err:  `CHAINCODE_TLS_DISABLED must be true or false, got "no"`,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := loadConfig(env(test.vars))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaincode-external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "chaincode.env")
+	contents := `
+CHAINCODE_SERVER_ADDRESS=asset-transfer-basic.org1.example.com:9999
+CHAINCODE_ID=basic_1.0:abc
+CHAINCODE_SHUTDOWN_TIMEOUT=5s
+`
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+	vars, err := serverconfig.ReadFile(path)
+	require.NoError(t, err)
+
+	environment := map[string]string{"CHAINCODE_SERVER_ADDRESS": "0.0.0.0:7052"}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := environment[name]
+		return value, ok
+	}
+
+	config, err := loadConfig(serverconfig.WithFallback(lookupEnv, vars))
+	require.NoError(t, err)
+	require.Equal(t, "basic_1.0:abc", config.CCID)
+	require.Equal(t, "0.0.0.0:7052", config.Address)
+	require.Equal(t, 5*time.Second, config.ShutdownTimeout)
+}
@@ -5,4 +5,11 @@ go 1.14
 require (
 	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
 	github.com/hyperledger/fabric-contract-api-go v1.1.0
+	github.com/hyperledger/fabric-protos-go v0.0.0-20200424173316-dd554ba3746e
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/stretchr/testify v1.5.1
+	google.golang.org/grpc v1.23.0
 )
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -0,0 +1,131 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unknownFunction is the label of invocations of names that are not
+// transaction functions, which clients can choose freely
+const unknownFunction = "unknown"
+
+// chaincodeMetrics are the Prometheus metrics collected for each transaction
+// function
+type chaincodeMetrics struct {
+	registry     *prometheus.Registry
+	transactions *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+}
+
+func newChaincodeMetrics() *chaincodeMetrics {
+	m := &chaincodeMetrics{
+		registry: prometheus.NewRegistry(),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chaincode",
+			Name:      "transactions_total",
+			Help:      "Number of transactions invoked, by function and status.",
+		}, []string{"function", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chaincode",
+			Name:      "transaction_duration_seconds",
+			Help:      "Time taken to execute a transaction, by function.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"function"}),
+	}
+
+	m.registry.MustRegister(
+		m.transactions,
+		m.duration,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// instrumentedChaincode records metrics for every Init and Invoke of the
+// chaincode it wraps
+type instrumentedChaincode struct {
+	cc      shim.Chaincode
+	metrics *chaincodeMetrics
+	// functions are the transaction functions recorded under their own name
+	functions map[string]bool
+}
+
+// contractFunctions returns the transaction functions of a contract, which are
+// its exported methods other than those of contractapi.Contract
+func contractFunctions(contract contractapi.ContractInterface) map[string]bool {
+	base := reflect.TypeOf(&contractapi.Contract{})
+	contractType := reflect.TypeOf(contract)
+
+	functions := make(map[string]bool)
+	for i := 0; i < contractType.NumMethod(); i++ {
+		name := contractType.Method(i).Name
+		if _, ok := base.MethodByName(name); !ok {
+			functions[name] = true
+		}
+	}
+	return functions
+}
+
+// Init implements shim.Chaincode
+func (c *instrumentedChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return c.observe("Init", func() pb.Response {
+		return c.cc.Init(stub)
+	})
+}
+
+// Invoke implements shim.Chaincode
+func (c *instrumentedChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, _ := stub.GetFunctionAndParameters()
+	if !c.functions[function] {
+		function = unknownFunction
+	}
+	return c.observe(function, func() pb.Response {
+		return c.cc.Invoke(stub)
+	})
+}
+
+func (c *instrumentedChaincode) observe(function string, call func() pb.Response) pb.Response {
+	start := time.Now()
+	response := call()
+	c.metrics.duration.WithLabelValues(function).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if response.Status >= shim.ERRORTHRESHOLD {
+		status = "error"
+	}
+	c.metrics.transactions.WithLabelValues(function, status).Inc()
+
+	return response
+}
+
+// newMetricsHandler serves /healthz, which reports that the process is alive,
+// /readyz, which reports whether ready returns no error, and /metrics
+func newMetricsHandler(metrics *chaincodeMetrics, ready func() error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+	return mux
+}
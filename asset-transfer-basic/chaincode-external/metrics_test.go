@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedChaincode(t *testing.T) {
+	chaincode, err := contractapi.NewChaincode(&SmartContract{})
+	require.NoError(t, err)
+
+	metrics := newChaincodeMetrics()
+	stub := shimtest.NewMockStub("basic", &instrumentedChaincode{cc: chaincode, metrics: metrics, functions: contractFunctions(&SmartContract{})})
+
+	response := stub.MockInvoke("tx1", [][]byte{[]byte("InitLedger")})
+	require.Equal(t, int32(200), response.Status)
+	response = stub.MockInvoke("tx2", [][]byte{[]byte("ReadAsset"), []byte("asset1")})
+	require.Equal(t, int32(200), response.Status)
+	response = stub.MockInvoke("tx3", [][]byte{[]byte("ReadAsset"), []byte("asset99")})
+	require.Equal(t, int32(500), response.Status)
+	response = stub.MockInvoke("tx4", [][]byte{[]byte("NoSuchFunction")})
+	require.Equal(t, int32(500), response.Status)
+	response = stub.MockInvoke("tx5", [][]byte{[]byte("GetName")})
+	require.Equal(t, int32(500), response.Status)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.transactions.WithLabelValues("InitLedger", "success")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.transactions.WithLabelValues("ReadAsset", "success")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.transactions.WithLabelValues("ReadAsset", "error")))
+	require.Equal(t, float64(2), testutil.ToFloat64(metrics.transactions.WithLabelValues(unknownFunction, "error")), "names clients choose share one label")
+	require.Equal(t, 3, testutil.CollectAndCount(metrics.duration))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := newChaincodeMetrics()
+	metrics.transactions.WithLabelValues("ReadAsset", "success").Inc()
+
+	var readyErr error
+	server := httptest.NewServer(newMetricsHandler(metrics, func() error { return readyErr }))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		response, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(body)
+	}
+
+	status, _ := get("/healthz")
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = get("/readyz")
+	require.Equal(t, http.StatusOK, status)
+
+	readyErr = fmt.Errorf("the chaincode is shutting down")
+	status, _ = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, body := get("/metrics")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, body, `chaincode_transactions_total{function="ReadAsset",status="success"} 1`)
+}
@@ -0,0 +1,162 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// gracefulChaincode tracks the transactions in flight so that they can
+// complete on shutdown, and rejects new ones once shutdown has begun
+type gracefulChaincode struct {
+	cc shim.Chaincode
+
+	mutex    sync.RWMutex
+	started  bool
+	draining bool
+	inFlight sync.WaitGroup
+}
+
+// Init implements shim.Chaincode
+func (c *gracefulChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	if !c.begin() {
+		return shim.Error("the chaincode is shutting down")
+	}
+	defer c.inFlight.Done()
+
+	return c.cc.Init(stub)
+}
+
+// Invoke implements shim.Chaincode
+func (c *gracefulChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	if !c.begin() {
+		return shim.Error("the chaincode is shutting down")
+	}
+	defer c.inFlight.Done()
+
+	return c.cc.Invoke(stub)
+}
+
+func (c *gracefulChaincode) begin() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.draining {
+		return false
+	}
+	c.inFlight.Add(1)
+	return true
+}
+
+func (c *gracefulChaincode) start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.started = true
+}
+
+// ready reports whether the server is accepting transactions
+func (c *gracefulChaincode) ready() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.started {
+		return fmt.Errorf("the chaincode server has not started")
+	}
+	if c.draining {
+		return fmt.Errorf("the chaincode is shutting down")
+	}
+	return nil
+}
+
+// drain stops accepting transactions and waits up to timeout for those in
+// flight to complete
+func (c *gracefulChaincode) drain(timeout time.Duration) error {
+	c.mutex.Lock()
+	c.draining = true
+	c.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s waiting for in-flight transactions to complete", timeout)
+	}
+}
+
+// serve runs the chaincode server until ctx is cancelled, then drains the
+// transactions in flight before stopping. shim.ChaincodeServer cannot be
+// stopped, so it is registered with a gRPC server configured the same way.
+func serve(ctx context.Context, config serverConfig, cc *gracefulChaincode) error {
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	serverOptions := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    1 * time.Minute,
+			Timeout: 20 * time.Second,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             1 * time.Minute,
+			PermitWithoutStream: true,
+		}),
+		grpc.MaxSendMsgSize(100 * 1024 * 1024),
+		grpc.MaxRecvMsgSize(100 * 1024 * 1024),
+		grpc.ConnectionTimeout(5 * time.Second),
+	}
+	if tlsConfig != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", config.Address, err)
+	}
+
+	server := grpc.NewServer(serverOptions...)
+	pb.RegisterChaincodeServer(server, &shim.ChaincodeServer{
+		CCID:    config.CCID,
+		Address: config.Address,
+		CC:      cc,
+	})
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	cc.start()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %s for in-flight transactions", config.ShutdownTimeout)
+	err = cc.drain(config.ShutdownTimeout)
+	// the peer keeps its stream open, so the server is stopped rather than
+	// gracefully stopped once the transactions have drained
+	server.Stop()
+
+	return err
+}
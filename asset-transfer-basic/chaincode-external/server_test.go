@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingChaincode holds each invocation until it is released
+type blockingChaincode struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c *blockingChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	c.started <- struct{}{}
+	<-c.release
+	return shim.Success([]byte("done"))
+}
+
+func TestGracefulChaincodeDrain(t *testing.T) {
+	blocking := &blockingChaincode{started: make(chan struct{}), release: make(chan struct{})}
+	cc := &gracefulChaincode{cc: blocking}
+	require.EqualError(t, cc.ready(), "the chaincode server has not started")
+	cc.start()
+	require.NoError(t, cc.ready())
+
+	responses := make(chan pb.Response)
+	go func() {
+		responses <- cc.Invoke(nil)
+	}()
+	<-blocking.started
+
+	drained := make(chan error)
+	go func() {
+		drained <- cc.drain(time.Minute)
+	}()
+
+	require.Eventually(t, func() bool { return cc.ready() != nil }, time.Second, time.Millisecond)
+	require.EqualError(t, cc.ready(), "the chaincode is shutting down")
+	response := cc.Invoke(nil)
+	require.Equal(t, int32(shim.ERROR), response.Status)
+	require.Equal(t, "the chaincode is shutting down", response.Message)
+
+	close(blocking.release)
+	require.Equal(t, "done", string((<-responses).Payload))
+	require.NoError(t, <-drained)
+}
+
+func TestGracefulChaincodeDrainTimeout(t *testing.T) {
+	blocking := &blockingChaincode{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(blocking.release)
+	cc := &gracefulChaincode{cc: blocking}
+
+	go cc.Invoke(nil)
+	<-blocking.started
+
+	require.EqualError(t, cc.drain(10*time.Millisecond), "timed out after 10ms waiting for in-flight transactions to complete")
+}
+
+func TestServe(t *testing.T) {
+	// the server is ready on a wildcard address, which clients cannot dial
+	config := serverConfig{
+		Config: serverconfig.Config{
+			CCID:        "basic_1.0:abc",
+			Address:     "0.0.0.0:0",
+			TLSDisabled: true,
+		},
+		ShutdownTimeout: time.Second,
+	}
+
+	cc := &gracefulChaincode{cc: &blockingChaincode{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- serve(ctx, config, cc)
+	}()
+
+	require.Eventually(t, func() bool { return cc.ready() == nil }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-errs)
+	require.EqualError(t, cc.ready(), "the chaincode is shutting down")
+}
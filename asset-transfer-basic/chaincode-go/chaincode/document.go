@@ -0,0 +1,131 @@
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+const documentObjectType = "document"
+
+// Document anchors an off-chain file to an asset by its SHA-256 digest
+type Document struct {
+	AssetID    string `json:"assetID"`
+	DocType    string `json:"docType"`
+	SHA256     string `json:"sha256"`
+	URI        string `json:"uri"`
+	AttachedBy string `json:"attachedBy"`
+	TxID       string `json:"txID"`
+}
+
+// AttachDocument records the hash and location of an off-chain file, such as an
+// inspection certificate or invoice, against an existing asset.
+func (s *SmartContract) AttachDocument(ctx contractapi.TransactionContextInterface, assetID string, docType string, sha256 string, uri string) error {
+	exists, err := s.AssetExists(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errorcode.New(errorcode.NotFound, "the asset %s does not exist", assetID)
+	}
+
+	digest, err := normalizeDigest(sha256)
+	if err != nil {
+		return err
+	}
+
+	documentKey, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{assetID, digest})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	existing, err := ctx.GetStub().GetState(documentKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return errorcode.New(errorcode.AlreadyExists, "the document %s is already attached to asset %s", digest, assetID)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+
+	document := Document{
+		AssetID:    assetID,
+		DocType:    docType,
+		SHA256:     digest,
+		URI:        uri,
+		AttachedBy: clientID,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(documentKey, documentJSON)
+}
+
+// ListDocuments returns all documents attached to the asset with given id
+func (s *SmartContract) ListDocuments(ctx contractapi.TransactionContextInterface, assetID string) ([]*Document, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(documentObjectType, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var documents []*Document
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var document Document
+		err = json.Unmarshal(queryResponse.Value, &document)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, &document)
+	}
+
+	return documents, nil
+}
+
+// VerifyDocument returns true when a file with the given SHA-256 digest has been
+// attached to the asset with given id
+func (s *SmartContract) VerifyDocument(ctx contractapi.TransactionContextInterface, assetID string, sha256 string) (bool, error) {
+	digest, err := normalizeDigest(sha256)
+	if err != nil {
+		return false, err
+	}
+
+	documentKey, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{assetID, digest})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	documentJSON, err := ctx.GetStub().GetState(documentKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return documentJSON != nil, nil
+}
+
+// normalizeDigest checks that the digest is a hex encoded SHA-256 hash and
+// returns it in lower case so that lookups do not depend on the client's encoding.
+func normalizeDigest(digest string) (string, error) {
+	decoded, err := hex.DecodeString(digest)
+	if err != nil || len(decoded) != 32 {
+		return "", errorcode.New(errorcode.Validation, "sha256 must be a 64 character hex string, got %q", digest)
+	}
+
+	return strings.ToLower(digest), nil
+}
@@ -0,0 +1,91 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+const invoiceDigest = "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"
+
+func TestAttachDocument(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"})
+	asClient(transactionContext, "inspector")
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.AttachDocument(transactionContext, "asset1", "invoice", invoiceDigest, "https://example.com/invoice.pdf")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	key, err := chaincodeStub.CreateCompositeKey("document", []string{"asset1", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"})
+	require.NoError(t, err)
+	documentJSON, err := chaincodeStub.GetState(key)
+	require.NoError(t, err)
+	var document chaincode.Document
+	require.NoError(t, json.Unmarshal(documentJSON, &document))
+	require.Equal(t, chaincode.Document{
+		AssetID:    "asset1",
+		DocType:    "invoice",
+		SHA256:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		URI:        "https://example.com/invoice.pdf",
+		AttachedBy: "inspector",
+		TxID:       "tx2",
+	}, document)
+
+	err = assetTransfer.AttachDocument(transactionContext, "asset1", "invoice", invoiceDigest, "")
+	requireErrorCode(t, err, errorcode.AlreadyExists, "the document 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 is already attached to asset asset1")
+
+	err = assetTransfer.AttachDocument(transactionContext, "asset1", "invoice", "abc", "")
+	requireErrorCode(t, err, errorcode.Validation, `sha256 must be a 64 character hex string, got "abc"`)
+
+	err = assetTransfer.AttachDocument(transactionContext, "asset2", "invoice", invoiceDigest, "")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset2 does not exist")
+}
+
+func TestListDocuments(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"}, chaincode.Asset{ID: "asset2"})
+	asClient(transactionContext, "inspector")
+
+	assetTransfer := &chaincode.SmartContract{}
+	require.NoError(t, assetTransfer.AttachDocument(transactionContext, "asset1", "certificate", invoiceDigest, ""))
+	require.NoError(t, assetTransfer.AttachDocument(transactionContext, "asset2", "invoice", invoiceDigest, ""))
+	chaincodeStub.Commit()
+
+	documents, err := assetTransfer.ListDocuments(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	require.Equal(t, "asset1", documents[0].AssetID)
+	require.Equal(t, "certificate", documents[0].DocType)
+
+	documents, err = assetTransfer.ListDocuments(transactionContext, "asset3")
+	require.NoError(t, err)
+	require.Empty(t, documents)
+
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("failed retrieving documents")})
+	documents, err = assetTransfer.ListDocuments(transactionContext, "asset1")
+	require.EqualError(t, err, "failed retrieving documents")
+	require.Nil(t, documents)
+}
+
+func TestVerifyDocument(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"})
+
+	assetTransfer := chaincode.SmartContract{}
+	require.NoError(t, assetTransfer.AttachDocument(transactionContext, "asset1", "invoice", invoiceDigest, ""))
+	chaincodeStub.Commit()
+
+	verified, err := assetTransfer.VerifyDocument(transactionContext, "asset1", invoiceDigest)
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	verified, err = assetTransfer.VerifyDocument(transactionContext, "asset2", invoiceDigest)
+	require.NoError(t, err)
+	require.False(t, verified)
+
+	_, err = assetTransfer.VerifyDocument(transactionContext, "asset1", "not-a-digest")
+	requireErrorCode(t, err, errorcode.Validation, `sha256 must be a 64 character hex string, got "not-a-digest"`)
+}
@@ -0,0 +1,122 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+// prepLedger returns a transaction context backed by an in-memory world state
+// holding assets
+func prepLedger(t *testing.T, assets ...chaincode.Asset) (*contractapi.TransactionContext, *stubtest.ChaincodeStub) {
+	chaincodeStub := stubtest.New()
+	for _, asset := range assets {
+		assetJSON, err := json.Marshal(asset)
+		require.NoError(t, err)
+		require.NoError(t, chaincodeStub.PutState(asset.ID, assetJSON))
+	}
+	chaincodeStub.Commit()
+
+	transactionContext := &contractapi.TransactionContext{}
+	transactionContext.SetStub(chaincodeStub)
+	asClient(transactionContext, "client1")
+
+	return transactionContext, chaincodeStub
+}
+
+// clientIdentity is a client identity with a fixed ID
+type clientIdentity struct {
+	cid.ClientIdentity
+	id  string
+	err error
+}
+
+func (c clientIdentity) GetID() (string, error) {
+	return c.id, c.err
+}
+
+// asClient makes the client with the given ID submit the later transactions
+func asClient(transactionContext *contractapi.TransactionContext, clientID string) {
+	transactionContext.SetClientIdentity(clientIdentity{id: clientID})
+}
+
+// failingStub is a chaincode stub whose world state reads fail with readErr,
+// whose writes fail with writeErr and whose range query results fail with
+// nextErr. Nil errors leave the in-memory world state working.
+type failingStub struct {
+	*stubtest.ChaincodeStub
+	readErr  error
+	writeErr error
+	nextErr  error
+}
+
+func (s failingStub) GetState(key string) ([]byte, error) {
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
+	return s.ChaincodeStub.GetState(key)
+}
+
+func (s failingStub) PutState(key string, value []byte) error {
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	return s.ChaincodeStub.PutState(key, value)
+}
+
+func (s failingStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
+	if s.nextErr != nil {
+		return failingIterator{err: s.nextErr}, nil
+	}
+	return s.ChaincodeStub.GetStateByRange(startKey, endKey)
+}
+
+func (s failingStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
+	return s.ChaincodeStub.GetStateByPartialCompositeKey(objectType, keys)
+}
+
+func (s failingStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
+	return s.ChaincodeStub.GetHistoryForKey(key)
+}
+
+// failingIterator is a state query iterator whose next result fails
+type failingIterator struct {
+	err error
+}
+
+func (i failingIterator) HasNext() bool {
+	return true
+}
+
+func (i failingIterator) Next() (*queryresult.KV, error) {
+	return nil, i.err
+}
+
+func (i failingIterator) Close() error {
+	return nil
+}
+
+// requireErrorCode checks that err carries the given code and message
+func requireErrorCode(t *testing.T, err error, code errorcode.Code, message string) {
+	t.Helper()
+	coded, ok := errorcode.FromError(err)
+	require.True(t, ok, "expected an error with code %s, got %v", code, err)
+	require.Equal(t, &errorcode.Error{Code: code, Message: message}, coded)
+}
@@ -0,0 +1,227 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+// HistoryQueryResult structure used for returning result of history query
+type HistoryQueryResult struct {
+	Record    *Asset    `json:"record"`
+	TxID      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"isDelete"`
+}
+
+// AssetChange lists the fields of an asset that a transaction changed
+type AssetChange struct {
+	TxID      string        `json:"txId"`
+	Timestamp time.Time     `json:"timestamp"`
+	IsDelete  bool          `json:"isDelete"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// FieldChange is the value of a field before and after a transaction. A value
+// is null when the field was not set.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// GetAssetHistory returns the chain of custody for an asset since issuance.
+func (s *SmartContract) GetAssetHistory(ctx contractapi.TransactionContextInterface, id string) ([]HistoryQueryResult, error) {
+	var records []HistoryQueryResult
+	err := assetHistory(ctx, id, func(record HistoryQueryResult, _ []byte) bool {
+		records = append(records, record)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// GetAssetAsOf returns an asset as it was at the given RFC 3339 time, which is
+// the value written by the last transaction at or before that time.
+func (s *SmartContract) GetAssetAsOf(ctx contractapi.TransactionContextInterface, id string, timestamp string) (*Asset, error) {
+	asOf, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, errorcode.New(errorcode.Validation, "the timestamp must be in RFC 3339 format, got %q", timestamp)
+	}
+
+	var found *HistoryQueryResult
+	err = assetHistory(ctx, id, func(record HistoryQueryResult, _ []byte) bool {
+		if record.Timestamp.After(asOf) {
+			return true
+		}
+		found = &record
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s did not exist at %s", id, timestamp)
+	}
+	if found.IsDelete {
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s was deleted at %s", id, found.Timestamp.Format(time.RFC3339))
+	}
+	return found.Record, nil
+}
+
+// GetAssetAtTx returns the value of an asset written by the given transaction.
+func (s *SmartContract) GetAssetAtTx(ctx contractapi.TransactionContextInterface, id string, txID string) (*Asset, error) {
+	var found *HistoryQueryResult
+	err := assetHistory(ctx, id, func(record HistoryQueryResult, _ []byte) bool {
+		if record.TxID != txID {
+			return true
+		}
+		found = &record
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s was not written by transaction %s", id, txID)
+	}
+	if found.IsDelete {
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s was deleted by transaction %s", id, txID)
+	}
+	return found.Record, nil
+}
+
+// DiffAssetHistory returns the fields each transaction changed in an asset,
+// oldest first. A transaction that created the asset changes every field
+// from null, and one that deleted it changes every field to null.
+func (s *SmartContract) DiffAssetHistory(ctx contractapi.TransactionContextInterface, id string) ([]AssetChange, error) {
+	var records []HistoryQueryResult
+	var values [][]byte
+	err := assetHistory(ctx, id, func(record HistoryQueryResult, value []byte) bool {
+		records = append(records, record)
+		values = append(values, value)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []AssetChange{}
+	var previous map[string]interface{}
+	for i := len(records) - 1; i >= 0; i-- {
+		var current map[string]interface{}
+		if !records[i].IsDelete {
+			current, err = decodeFields(values[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		changes = append(changes, AssetChange{
+			TxID:      records[i].TxID,
+			Timestamp: records[i].Timestamp,
+			IsDelete:  records[i].IsDelete,
+			Changes:   diffFields(previous, current),
+		})
+		previous = current
+	}
+
+	return changes, nil
+}
+
+// assetHistory calls fn with each value written to an asset, newest first,
+// until fn returns false. Values are upgraded to the current format, and the
+// value of a deletion is nil.
+func assetHistory(ctx contractapi.TransactionContextInterface, id string, fn func(record HistoryQueryResult, value []byte) bool) error {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var value []byte
+		var asset Asset
+		if len(response.Value) > 0 {
+			value, _, err = assetType.Upgrade(response.Value)
+			if err != nil {
+				return err
+			}
+			err = json.Unmarshal(value, &asset)
+			if err != nil {
+				return err
+			}
+		} else {
+			asset = Asset{
+				ID: id,
+			}
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		record := HistoryQueryResult{
+			TxID:      response.TxId,
+			Timestamp: timestamp,
+			Record:    &asset,
+			IsDelete:  response.IsDelete,
+		}
+		if !fn(record, value) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// decodeFields decodes the top level fields of an asset's JSON
+func decodeFields(value []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	err := decoder.Decode(&fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// diffFields returns the fields whose values differ, sorted by name
+func diffFields(previous, current map[string]interface{}) []FieldChange {
+	var names []string
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := previous[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(previous[name], current[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: previous[name], New: current[name]})
+		}
+	}
+	return changes
+}
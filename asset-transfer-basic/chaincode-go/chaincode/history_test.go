@@ -0,0 +1,127 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAssetHistory(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
+	require.NoError(t, chaincodeStub.DelState("asset1"))
+	chaincodeStub.Commit()
+
+	assetTransfer := &chaincode.SmartContract{}
+	history, err := assetTransfer.GetAssetHistory(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.HistoryQueryResult{
+		{Record: &chaincode.Asset{ID: "asset1"}, TxID: "tx2", Timestamp: stubtest.StartTime.Add(time.Second), IsDelete: true},
+		{Record: &chaincode.Asset{ID: "asset1", Owner: "Tomoko", DataVersion: 1}, TxID: "tx1", Timestamp: stubtest.StartTime},
+	}, history)
+
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("failed retrieving history")})
+	history, err = assetTransfer.GetAssetHistory(transactionContext, "asset1")
+	require.EqualError(t, err, "failed retrieving history")
+	require.Nil(t, history)
+}
+
+// prepHistory writes an asset in three transactions and deletes it in a fourth
+func prepHistory(t *testing.T) (*contractapi.TransactionContext, *stubtest.ChaincodeStub) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300})
+	for _, asset := range []chaincode.Asset{
+		{ID: "asset1", Color: "blue", Size: 5, Owner: "Brad", AppraisedValue: 300},
+		{ID: "asset1", Color: "red", Size: 5, Owner: "Brad", AppraisedValue: 350},
+	} {
+		assetJSON, err := json.Marshal(asset)
+		require.NoError(t, err)
+		require.NoError(t, chaincodeStub.PutState(asset.ID, assetJSON))
+		chaincodeStub.Commit()
+	}
+	require.NoError(t, chaincodeStub.DelState("asset1"))
+	chaincodeStub.Commit()
+
+	return transactionContext, chaincodeStub
+}
+
+func TestGetAssetAsOf(t *testing.T) {
+	transactionContext, _ := prepHistory(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	asset, err := assetTransfer.GetAssetAsOf(transactionContext, "asset1", "2020-01-01T00:00:01Z")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{ID: "asset1", Color: "blue", Size: 5, Owner: "Brad", AppraisedValue: 300, DataVersion: 1}, asset)
+
+	asset, err = assetTransfer.GetAssetAsOf(transactionContext, "asset1", "2020-01-01T01:00:02.5+01:00")
+	require.NoError(t, err)
+	require.Equal(t, "red", asset.Color)
+
+	for timestamp, message := range map[string]string{
+		"2019-12-31T23:59:59Z": "the asset asset1 did not exist at 2019-12-31T23:59:59Z",
+		"2020-01-02T00:00:00Z": "the asset asset1 was deleted at 2020-01-01T00:00:03Z",
+		"2020-01-02":           `the timestamp must be in RFC 3339 format, got "2020-01-02"`,
+	} {
+		_, err = assetTransfer.GetAssetAsOf(transactionContext, "asset1", timestamp)
+		coded, ok := errorcode.FromError(err)
+		require.True(t, ok, timestamp)
+		require.Equal(t, message, coded.Message, timestamp)
+	}
+}
+
+func TestGetAssetAtTx(t *testing.T) {
+	transactionContext, _ := prepHistory(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	asset, err := assetTransfer.GetAssetAtTx(transactionContext, "asset1", "tx1")
+	require.NoError(t, err)
+	require.Equal(t, "Tomoko", asset.Owner)
+
+	for txID, message := range map[string]string{
+		"tx4": "the asset asset1 was deleted by transaction tx4",
+		"tx5": "the asset asset1 was not written by transaction tx5",
+	} {
+		_, err = assetTransfer.GetAssetAtTx(transactionContext, "asset1", txID)
+		coded, ok := errorcode.FromError(err)
+		require.True(t, ok, txID)
+		require.Equal(t, errorcode.NotFound, coded.Code, txID)
+		require.Equal(t, message, coded.Message, txID)
+	}
+}
+
+func TestDiffAssetHistory(t *testing.T) {
+	transactionContext, _ := prepHistory(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	changes, err := assetTransfer.DiffAssetHistory(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	require.Equal(t, "tx1", changes[0].TxID)
+	require.Equal(t, stubtest.StartTime, changes[0].Timestamp)
+	require.Equal(t, []chaincode.FieldChange{
+		{Field: "ID", New: "asset1"},
+		{Field: "appraisedValue", New: json.Number("300")},
+		{Field: "color", New: "blue"},
+		{Field: "dataVersion", New: json.Number("1")},
+		{Field: "owner", New: "Tomoko"},
+		{Field: "size", New: json.Number("5")},
+	}, changes[0].Changes)
+	require.Equal(t, []chaincode.FieldChange{{Field: "owner", Old: "Tomoko", New: "Brad"}}, changes[1].Changes)
+	require.Equal(t, []chaincode.FieldChange{
+		{Field: "appraisedValue", Old: json.Number("300"), New: json.Number("350")},
+		{Field: "color", Old: "blue", New: "red"},
+	}, changes[2].Changes)
+	require.True(t, changes[3].IsDelete)
+	require.Len(t, changes[3].Changes, 6)
+	require.Nil(t, changes[3].Changes[0].New)
+
+	changes, err = assetTransfer.DiffAssetHistory(transactionContext, "asset2")
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
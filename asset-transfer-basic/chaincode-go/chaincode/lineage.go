@@ -0,0 +1,294 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+)
+
+const lineageObjectType = "lineage"
+
+// LineageNode records how an asset was derived from, or consumed into, other assets
+type LineageNode struct {
+	AssetID   string   `json:"assetID"`
+	Operation string   `json:"operation"`
+	Parents   []string `json:"parents,omitempty"`
+	Children  []string `json:"children,omitempty"`
+	TxID      string   `json:"txID"`
+}
+
+// SplitAsset divides an asset into the given number of parts. The children are
+// named <id>-1 ... <id>-<parts>, share the size as evenly as possible and
+// receive a share of the appraised value proportional to their size. The
+// original asset is deleted with the reason "split".
+func (s *SmartContract) SplitAsset(ctx contractapi.TransactionContextInterface, id string, parts int) ([]*Asset, error) {
+	var children []*Asset
+	err := idempotency.Do(ctx, &children, func() error {
+		var err error
+		children, err = s.splitAsset(ctx, id, parts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func (s *SmartContract) splitAsset(ctx contractapi.TransactionContextInterface, id string, parts int) ([]*Asset, error) {
+	if parts < 2 {
+		return nil, errorcode.New(errorcode.Validation, "an asset must be split into at least 2 parts")
+	}
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkReservation(ctx, asset, "")
+	if err != nil {
+		return nil, err
+	}
+	if asset.Size < parts {
+		return nil, errorcode.New(errorcode.Validation, "the asset %s of size %d cannot be split into %d parts", id, asset.Size, parts)
+	}
+
+	var children []*Asset
+	remainingValue := asset.AppraisedValue
+	for i := 0; i < parts; i++ {
+		childID := fmt.Sprintf("%s-%d", id, i+1)
+		exists, err := s.AssetExists(ctx, childID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, errorcode.New(errorcode.AlreadyExists, "the asset %s already exists", childID)
+		}
+
+		size := asset.Size / parts
+		if i < asset.Size%parts {
+			size++
+		}
+		value := asset.AppraisedValue * size / asset.Size
+		remainingValue -= value
+
+		children = append(children, &Asset{
+			ID:             childID,
+			Color:          asset.Color,
+			Size:           size,
+			Owner:          asset.Owner,
+			AppraisedValue: value,
+			DataVersion:    assetType.Version(),
+		})
+	}
+	// integer division may leave part of the value unassigned
+	children[0].AppraisedValue += remainingValue
+
+	err = s.recordDerivation(ctx, "split", "split", []string{id}, children)
+	if err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// MergeAssets combines assets of the same owner and color into a single new
+// asset whose size and appraised value are the sums of its parents. The
+// merged assets are deleted with the reason "merged".
+func (s *SmartContract) MergeAssets(ctx contractapi.TransactionContextInterface, ids []string, newID string) (*Asset, error) {
+	var merged *Asset
+	err := idempotency.Do(ctx, &merged, func() error {
+		var err error
+		merged, err = s.mergeAssets(ctx, ids, newID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+func (s *SmartContract) mergeAssets(ctx contractapi.TransactionContextInterface, ids []string, newID string) (*Asset, error) {
+	if len(ids) < 2 {
+		return nil, errorcode.New(errorcode.Validation, "at least 2 assets are required for a merge")
+	}
+
+	exists, err := s.AssetExists(ctx, newID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errorcode.New(errorcode.AlreadyExists, "the asset %s already exists", newID)
+	}
+
+	merged := &Asset{ID: newID, DataVersion: assetType.Version()}
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		if seen[id] {
+			return nil, errorcode.New(errorcode.Validation, "the asset %s is listed more than once", id)
+		}
+		seen[id] = true
+
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		err = checkReservation(ctx, asset, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			merged.Color = asset.Color
+			merged.Owner = asset.Owner
+		} else if asset.Owner != merged.Owner {
+			return nil, errorcode.New(errorcode.Validation, "the asset %s is owned by %s, not %s", id, asset.Owner, merged.Owner)
+		} else if asset.Color != merged.Color {
+			return nil, errorcode.New(errorcode.Validation, "the asset %s is %s, not %s", id, asset.Color, merged.Color)
+		}
+
+		merged.Size += asset.Size
+		merged.AppraisedValue += asset.AppraisedValue
+	}
+
+	err = s.recordDerivation(ctx, "merge", "merged", ids, []*Asset{merged})
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// GetAssetLineage walks the split and merge history of an asset back to its
+// origin assets. The first node returned is the asset itself; origin assets
+// appear as nodes without parents.
+func (s *SmartContract) GetAssetLineage(ctx contractapi.TransactionContextInterface, id string) ([]*LineageNode, error) {
+	var lineage []*LineageNode
+	visited := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		assetID := queue[0]
+		queue = queue[1:]
+
+		node, err := s.readLineageNode(ctx, assetID)
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			if assetID == id {
+				exists, err := s.AssetExists(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				if !exists {
+					return nil, errorcode.New(errorcode.NotFound, "the asset %s does not exist", id)
+				}
+			}
+			node = &LineageNode{AssetID: assetID, Operation: "origin"}
+		}
+		lineage = append(lineage, node)
+
+		for _, parent := range node.Parents {
+			if !visited[parent] {
+				visited[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	return lineage, nil
+}
+
+// recordDerivation replaces the parent assets with the children in world state
+// and writes the lineage nodes linking them. The parents are deleted like
+// DeleteAssetWithReason, so that their tombstones record the reason.
+func (s *SmartContract) recordDerivation(ctx contractapi.TransactionContextInterface, operation string, reason string, parentIDs []string, children []*Asset) error {
+	txID := ctx.GetStub().GetTxID()
+
+	var childIDs []string
+	for _, child := range children {
+		childIDs = append(childIDs, child.ID)
+	}
+
+	for _, parentID := range parentIDs {
+		node, err := s.readLineageNode(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			node = &LineageNode{AssetID: parentID, Operation: "origin"}
+		}
+		node.Children = childIDs
+		err = s.putLineageNode(ctx, node)
+		if err != nil {
+			return err
+		}
+
+		err = s.deleteAsset(ctx, parentID, reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range children {
+		err := s.putAsset(ctx, child)
+		if err != nil {
+			return err
+		}
+
+		err = s.putLineageNode(ctx, &LineageNode{
+			AssetID:   child.ID,
+			Operation: operation,
+			Parents:   parentIDs,
+			TxID:      txID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) readLineageNode(ctx contractapi.TransactionContextInterface, id string) (*LineageNode, error) {
+	lineageKey, err := ctx.GetStub().CreateCompositeKey(lineageObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	nodeJSON, err := ctx.GetStub().GetState(lineageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if nodeJSON == nil {
+		return nil, nil
+	}
+
+	var node LineageNode
+	err = json.Unmarshal(nodeJSON, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (s *SmartContract) putLineageNode(ctx contractapi.TransactionContextInterface, node *LineageNode) error {
+	lineageKey, err := ctx.GetStub().CreateCompositeKey(lineageObjectType, []string{node.AssetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	nodeJSON, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(lineageKey, nodeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAsset(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t,
+		chaincode.Asset{ID: "pallet1", Color: "blue", Size: 10, Owner: "Tomoko", AppraisedValue: 1000},
+		chaincode.Asset{ID: "pallet2-2", Size: 1},
+		chaincode.Asset{ID: "pallet2", Size: 3, AppraisedValue: 100},
+	)
+	assetTransfer := chaincode.SmartContract{}
+
+	children, err := assetTransfer.SplitAsset(transactionContext, "pallet1", 3)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{
+		{ID: "pallet1-1", Color: "blue", Size: 4, Owner: "Tomoko", AppraisedValue: 400, DataVersion: 1},
+		{ID: "pallet1-2", Color: "blue", Size: 3, Owner: "Tomoko", AppraisedValue: 300, DataVersion: 1},
+		{ID: "pallet1-3", Color: "blue", Size: 3, Owner: "Tomoko", AppraisedValue: 300, DataVersion: 1},
+	}, children)
+	chaincodeStub.Commit()
+
+	assetJSON, err := chaincodeStub.GetState("pallet1")
+	require.NoError(t, err)
+	require.Nil(t, assetJSON)
+	assetJSON, err = chaincodeStub.GetState("pallet1-3")
+	require.NoError(t, err)
+	require.NotNil(t, assetJSON)
+
+	tombstones, err := assetTransfer.ListDeletedAssets(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	require.Equal(t, "pallet1", tombstones[0].Asset.ID)
+	require.Equal(t, "split", tombstones[0].Reason)
+	require.Equal(t, "client1", tombstones[0].DeletedBy)
+
+	_, err = assetTransfer.RestoreAsset(transactionContext, "pallet1")
+	requireErrorCode(t, err, errorcode.Conflict, "the asset pallet1 was split into pallet1-1, pallet1-2, pallet1-3 and cannot be restored")
+
+	children, err = assetTransfer.SplitAsset(transactionContext, "pallet2", 2)
+	requireErrorCode(t, err, errorcode.AlreadyExists, "the asset pallet2-2 already exists")
+	require.Nil(t, children)
+
+	_, err = assetTransfer.SplitAsset(transactionContext, "pallet2", 4)
+	requireErrorCode(t, err, errorcode.Validation, "the asset pallet2 of size 3 cannot be split into 4 parts")
+
+	_, err = assetTransfer.SplitAsset(transactionContext, "pallet2", 1)
+	requireErrorCode(t, err, errorcode.Validation, "an asset must be split into at least 2 parts")
+
+	_, err = assetTransfer.SplitAsset(transactionContext, "pallet1", 2)
+	requireErrorCode(t, err, errorcode.NotFound, "the asset pallet1 does not exist")
+}
+
+func TestSplitAssetConservesValue(t *testing.T) {
+	transactionContext, _ := prepLedger(t,
+		chaincode.Asset{ID: "lot1", Size: 7, AppraisedValue: 1000},
+	)
+	assetTransfer := chaincode.SmartContract{}
+
+	children, err := assetTransfer.SplitAsset(transactionContext, "lot1", 3)
+	require.NoError(t, err)
+
+	var size, value int
+	for _, child := range children {
+		size += child.Size
+		value += child.AppraisedValue
+	}
+	require.Equal(t, 7, size)
+	require.Equal(t, 1000, value)
+}
+
+func TestMergeAssets(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t,
+		chaincode.Asset{ID: "lot1", Color: "red", Size: 5, Owner: "Brad", AppraisedValue: 400},
+		chaincode.Asset{ID: "lot2", Color: "red", Size: 3, Owner: "Brad", AppraisedValue: 250},
+		chaincode.Asset{ID: "lot3", Color: "red", Size: 3, Owner: "Max", AppraisedValue: 250},
+		chaincode.Asset{ID: "lot4", Color: "green", Size: 3, Owner: "Max", AppraisedValue: 250},
+	)
+	assetTransfer := chaincode.SmartContract{}
+
+	merged, err := assetTransfer.MergeAssets(transactionContext, []string{"lot1", "lot2"}, "pallet1")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{ID: "pallet1", Color: "red", Size: 8, Owner: "Brad", AppraisedValue: 650, DataVersion: 1}, merged)
+	chaincodeStub.Commit()
+
+	iterator, err := chaincodeStub.GetStateByRange("", "")
+	require.NoError(t, err)
+	defer iterator.Close()
+	var ids []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		require.NoError(t, err)
+		ids = append(ids, kv.Key)
+	}
+	require.Equal(t, []string{"lot3", "lot4", "pallet1"}, ids)
+
+	tombstones, err := assetTransfer.ListDeletedAssets(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 2)
+	for i, id := range []string{"lot1", "lot2"} {
+		require.Equal(t, id, tombstones[i].Asset.ID)
+		require.Equal(t, "merged", tombstones[i].Reason)
+	}
+
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"lot3", "lot4"}, "pallet1")
+	requireErrorCode(t, err, errorcode.AlreadyExists, "the asset pallet1 already exists")
+
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"pallet1", "lot3"}, "pallet2")
+	requireErrorCode(t, err, errorcode.Validation, "the asset lot3 is owned by Max, not Brad")
+
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"lot3", "lot4"}, "pallet2")
+	requireErrorCode(t, err, errorcode.Validation, "the asset lot4 is green, not red")
+
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"lot3", "lot3"}, "pallet2")
+	requireErrorCode(t, err, errorcode.Validation, "the asset lot3 is listed more than once")
+
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"lot3"}, "pallet2")
+	requireErrorCode(t, err, errorcode.Validation, "at least 2 assets are required for a merge")
+}
+
+func TestGetAssetLineage(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t,
+		chaincode.Asset{ID: "pallet1", Color: "blue", Size: 4, Owner: "Tomoko"},
+		chaincode.Asset{ID: "lot9", Color: "blue", Size: 1, Owner: "Tomoko"},
+	)
+	assetTransfer := chaincode.SmartContract{}
+
+	_, err := assetTransfer.SplitAsset(transactionContext, "pallet1", 2)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	_, err = assetTransfer.MergeAssets(transactionContext, []string{"pallet1-2", "lot9"}, "pallet2")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	lineage, err := assetTransfer.GetAssetLineage(transactionContext, "pallet2")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.LineageNode{
+		{AssetID: "pallet2", Operation: "merge", Parents: []string{"pallet1-2", "lot9"}, TxID: "tx3"},
+		{AssetID: "pallet1-2", Operation: "split", Parents: []string{"pallet1"}, Children: []string{"pallet2"}, TxID: "tx2"},
+		{AssetID: "lot9", Operation: "origin", Children: []string{"pallet2"}},
+		{AssetID: "pallet1", Operation: "origin", Children: []string{"pallet1-1", "pallet1-2"}},
+	}, lineage)
+
+	lineage, err = assetTransfer.GetAssetLineage(transactionContext, "pallet1-1")
+	require.NoError(t, err)
+	require.Len(t, lineage, 2)
+
+	_, err = assetTransfer.GetAssetLineage(transactionContext, "unknown")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset unknown does not exist")
+}
@@ -0,0 +1,50 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/migration"
+)
+
+// migrationBatchSize is the most assets MigrateBatch reads at once
+const migrationBatchSize = 100
+
+// assetType upgrades assets stored by earlier versions of the chaincode. When
+// the format of Asset changes, append a step that converts the JSON of the
+// previous version, and assets are converted when they are read.
+//
+// Version 0 assets may have been written by the JavaScript contract of this
+// sample, which names the fields in upper camel case, such as "AppraisedValue".
+// Version 1 uses the field names of Asset, which rich queries match case
+// sensitively.
+var assetType = &migration.Type{
+	Name: "asset",
+	Steps: []migration.Step{
+		renameFields,
+	},
+}
+
+var migrations = migration.NewRegistry(assetType)
+
+// MigrateBatch rewrites up to 100 assets stored in an earlier format, starting
+// from the bookmark returned by the previous batch
+func (s *SmartContract) MigrateBatch(ctx contractapi.TransactionContextInterface, bookmark string) (*migration.BatchResult, error) {
+	return migrations.MigrateBatch(ctx.GetStub(), bookmark, migrationBatchSize)
+}
+
+// MigrationStatus counts the assets stored in each format
+func (s *SmartContract) MigrationStatus(ctx contractapi.TransactionContextInterface) ([]*migration.Status, error) {
+	return migrations.Status(ctx.GetStub())
+}
+
+// renameFields upgrades an asset to version 1. Unmarshaling matches the field
+// names of Asset case insensitively, and marshaling writes them as declared.
+func renameFields(value []byte) ([]byte, error) {
+	var asset Asset
+	err := json.Unmarshal(value, &asset)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(asset)
+}
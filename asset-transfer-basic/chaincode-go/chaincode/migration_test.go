@@ -0,0 +1,65 @@
+package chaincode_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/migration"
+	"github.com/stretchr/testify/require"
+)
+
+// javaScriptAsset is an asset as stored by the JavaScript contract
+const javaScriptAsset = `{"ID":"asset150","Color":"blue","Size":5,"Owner":"Tomoko","AppraisedValue":300}`
+
+func TestMigrateBatch(t *testing.T) {
+	var assets []chaincode.Asset
+	for i := 0; i < 150; i++ {
+		assets = append(assets, chaincode.Asset{ID: fmt.Sprintf("asset%03d", i), Owner: "Tomoko"})
+	}
+	transactionContext, chaincodeStub := prepLedger(t, assets...)
+	require.NoError(t, chaincodeStub.PutState("asset150", []byte(javaScriptAsset)))
+	chaincodeStub.Commit()
+	assetTransfer := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "req1", "TransferAsset", "asset000", "Brad")
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset000", "Brad"))
+	chaincodeStub.Commit()
+
+	statuses, err := assetTransfer.MigrationStatus(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, []*migration.Status{{
+		Type:           "asset",
+		CurrentVersion: 1,
+		Total:          151,
+		Pending:        150,
+		Versions:       []migration.VersionCount{{Version: 0, Count: 150}, {Version: 1, Count: 1}},
+	}}, statuses, "request records are not assets")
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset150")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{ID: "asset150", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300, DataVersion: 1}, asset)
+
+	result, err := assetTransfer.MigrateBatch(transactionContext, "")
+	require.NoError(t, err)
+	require.Equal(t, &migration.BatchResult{Scanned: 100, Migrated: 99, Bookmark: `{"type":"asset","key":"asset100"}`}, result)
+	chaincodeStub.Commit()
+
+	result, err = assetTransfer.MigrateBatch(transactionContext, result.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, &migration.BatchResult{Scanned: 51, Migrated: 51, Done: true}, result)
+	chaincodeStub.Commit()
+
+	assetJSON, err := chaincodeStub.GetState("asset150")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ID":"asset150","color":"blue","size":5,"owner":"Tomoko","appraisedValue":300,"dataVersion":1}`, string(assetJSON))
+
+	statuses, err = assetTransfer.MigrationStatus(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, []*migration.Status{{
+		Type:           "asset",
+		CurrentVersion: 1,
+		Total:          151,
+		Versions:       []migration.VersionCount{{Version: 1, Count: 151}},
+	}}, statuses)
+}
@@ -0,0 +1,119 @@
+package chaincode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+func withRequestID(chaincodeStub *stubtest.ChaincodeStub, requestID string, args ...string) {
+	chaincodeStub.SetArgs(args...)
+	chaincodeStub.SetTransient(map[string][]byte{idempotency.TransientKey: []byte(requestID)})
+}
+
+func TestCreateAssetRetry(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "req1", "CreateAsset", "asset1", "blue", "5", "Tomoko", "300")
+	require.NoError(t, assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300))
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "req1", "CreateAsset", "asset1", "blue", "5", "Tomoko", "300")
+	require.NoError(t, assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300), "a retry succeeds")
+	chaincodeStub.Commit()
+
+	chaincodeStub.SetArgs("CreateAsset", "asset1", "blue", "5", "Tomoko", "300")
+	err := assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300)
+	require.Equal(t, errorcode.AlreadyExists, errorcode.CodeOf(err), "a request without an ID is not a retry")
+	chaincodeStub.Rollback()
+
+	withRequestID(chaincodeStub, "req1", "CreateAsset", "asset2", "red", "5", "Brad", "400")
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "red", 5, "Brad", 400)
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+
+	record, err := assetTransfer.GetRequest(transactionContext, "req1")
+	require.NoError(t, err)
+	require.Equal(t, "CreateAsset", record.Function)
+	require.Equal(t, "tx2", record.TxID)
+
+	_, err = assetTransfer.GetRequest(transactionContext, "req2")
+	require.EqualError(t, err, `{"code":"NOT_FOUND","message":"the request req2 has no record"}`)
+}
+
+func TestTransferAssetRetry(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
+	assetTransfer := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "req1", "TransferAsset", "asset1", "Brad")
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset1", "Brad"))
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "req2", "TransferAsset", "asset1", "Max")
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset1", "Max"))
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "req1", "TransferAsset", "asset1", "Brad")
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset1", "Brad"))
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, "Max", asset.Owner, "the late retry does not transfer the asset back")
+}
+
+func TestSplitAssetRetry(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "pallet1", Size: 4, Owner: "Tomoko"})
+	assetTransfer := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "req1", "SplitAsset", "pallet1", "2")
+	children, err := assetTransfer.SplitAsset(transactionContext, "pallet1", 2)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "req1", "SplitAsset", "pallet1", "2")
+	retried, err := assetTransfer.SplitAsset(transactionContext, "pallet1", 2)
+	require.NoError(t, err, "a retry succeeds although pallet1 is gone")
+	require.Equal(t, children, retried)
+	require.Empty(t, chaincodeStub.Endorse().Writes())
+	chaincodeStub.Rollback()
+
+	withRequestID(chaincodeStub, "req2", "MergeAssets", `["pallet1-1","pallet1-2"]`, "pallet2")
+	merged, err := assetTransfer.MergeAssets(transactionContext, []string{"pallet1-1", "pallet1-2"}, "pallet2")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "req2", "MergeAssets", `["pallet1-1","pallet1-2"]`, "pallet2")
+	retriedMerge, err := assetTransfer.MergeAssets(transactionContext, []string{"pallet1-1", "pallet1-2"}, "pallet2")
+	require.NoError(t, err)
+	require.Equal(t, merged, retriedMerge)
+}
+
+func TestPruneRequests(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
+	assetTransfer := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "req1", "TransferAsset", "asset1", "Brad")
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset1", "Brad"))
+	chaincodeStub.Commit()
+
+	pruned, err := assetTransfer.PruneRequests(transactionContext)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+	chaincodeStub.Commit()
+
+	chaincodeStub.SetTxTimestamp(stubtest.StartTime.Add(8 * 24 * time.Hour))
+	pruned, err = assetTransfer.PruneRequests(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, 1, pruned)
+	chaincodeStub.Commit()
+
+	_, err = assetTransfer.GetRequest(transactionContext, "req1")
+	require.Equal(t, errorcode.NotFound, errorcode.CodeOf(err))
+}
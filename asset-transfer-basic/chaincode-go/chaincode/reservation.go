@@ -0,0 +1,123 @@
+package chaincode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+// maxReservationSeconds is the longest an asset can be reserved for, 30 days
+const maxReservationSeconds = 30 * 24 * 60 * 60
+
+// Reservation holds an asset for a buyer until it expires or is released.
+// ReservedBy and ReservedFor are client IDs.
+type Reservation struct {
+	ReservedBy  string    `json:"reservedBy"`
+	ReservedFor string    `json:"reservedFor"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ReserveAsset holds an asset for the buyer with the client ID reservedFor, for
+// the given number of seconds measured from the transaction timestamp. While the
+// hold lasts, only the buyer can change the asset, and the client that reserved
+// it can only transfer it to the buyer. Owners in this sample are names rather
+// than client identities, so like TransferAsset, any client can reserve an
+// asset that is not already held.
+func (s *SmartContract) ReserveAsset(ctx contractapi.TransactionContextInterface, id string, reservedFor string, durationSeconds int) error {
+	if durationSeconds <= 0 || durationSeconds > maxReservationSeconds {
+		return errorcode.New(errorcode.Validation, "durationSeconds must be between 1 and %d", maxReservationSeconds)
+	}
+	if reservedFor == "" {
+		return errorcode.New(errorcode.Validation, "reservedFor must be the client ID of the buyer")
+	}
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = checkReservation(ctx, asset, "")
+	if err != nil {
+		return err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	asset.Reservation = &Reservation{
+		ReservedBy:  clientID,
+		ReservedFor: reservedFor,
+		ExpiresAt:   now.Add(time.Duration(durationSeconds) * time.Second),
+	}
+	return s.putAsset(ctx, asset)
+}
+
+// ReleaseReservation removes the reservation on an asset. An active reservation
+// can only be released by the buyer it is held for; an expired one by anyone.
+func (s *SmartContract) ReleaseReservation(ctx contractapi.TransactionContextInterface, id string) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset.Reservation == nil {
+		return errorcode.New(errorcode.Conflict, "the asset %s is not reserved", id)
+	}
+
+	err = checkReservation(ctx, asset, "")
+	if err != nil {
+		return err
+	}
+
+	asset.Reservation = nil
+	return s.putAsset(ctx, asset)
+}
+
+// checkReservation returns an error when the asset is held by an unexpired
+// reservation for a buyer other than the submitter. transferTo is the new owner
+// when the change is a transfer, which the client that made the reservation may
+// make to the buyer, and empty for any other change.
+func checkReservation(ctx contractapi.TransactionContextInterface, asset *Asset, transferTo string) error {
+	if asset.Reservation == nil {
+		return nil
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !now.Before(asset.Reservation.ExpiresAt) {
+		return nil
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if clientID == asset.Reservation.ReservedFor {
+		return nil
+	}
+	if clientID == asset.Reservation.ReservedBy && transferTo == asset.Reservation.ReservedFor {
+		return nil
+	}
+
+	return errorcode.New(errorcode.Conflict, "the asset %s is reserved for %s until %s", asset.ID, asset.Reservation.ReservedFor, asset.Reservation.ExpiresAt.Format(time.RFC3339))
+}
+
+// txTime returns the transaction timestamp, which is the same on every endorsing peer
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return ptypes.Timestamp(txTimestamp)
+}
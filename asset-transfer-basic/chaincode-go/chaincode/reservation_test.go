@@ -0,0 +1,163 @@
+package chaincode_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+// Client IDs as returned by cid.GetID for x509 identities
+const (
+	sellerID = "x509::CN=seller,OU=client::CN=ca.org1.example.com,O=org1.example.com"
+	buyerID  = "x509::CN=buyer,OU=client::CN=ca.org2.example.com,O=org2.example.com"
+	otherID  = "x509::CN=other,OU=client::CN=ca.org2.example.com,O=org2.example.com"
+)
+
+const heldUntil = "the asset asset1 is reserved for " + buyerID + " until 2020-10-01T12:01:00Z"
+
+func TestReserveAsset(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
+	asClient(transactionContext, sellerID)
+
+	start := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	chaincodeStub.SetTxTimestamp(start)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Reservation{ReservedBy: sellerID, ReservedFor: buyerID, ExpiresAt: start.Add(time.Minute)}, asset.Reservation)
+
+	for _, durationSeconds := range []int{0, -1, 30*24*60*60 + 1, math.MaxInt64} {
+		err = assetTransfer.ReserveAsset(transactionContext, "asset1", otherID, durationSeconds)
+		requireErrorCode(t, err, errorcode.Validation, "durationSeconds must be between 1 and 2592000")
+	}
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", "", 60)
+	requireErrorCode(t, err, errorcode.Validation, "reservedFor must be the client ID of the buyer")
+
+	// neither the seller nor another client can replace the buyer's hold
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", otherID, 60)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	asClient(transactionContext, otherID)
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", otherID, 60)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+
+	chaincodeStub.SetTxTimestamp(start.Add(time.Minute))
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", otherID, 60)
+	require.NoError(t, err)
+}
+
+func TestReservationProtectsBuyer(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko", Size: 10})
+	asClient(transactionContext, sellerID)
+
+	start := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	chaincodeStub.SetTxTimestamp(start)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	// the seller can neither change the asset nor sell it to someone else
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "red", 10, "Tomoko", 1000)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "blue", 10, "Max", 0)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	err = assetTransfer.TransferAsset(transactionContext, "asset1", "Max")
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	_, err = assetTransfer.SplitAsset(transactionContext, "asset1", 2)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	err = assetTransfer.ReleaseReservation(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+
+	// only the seller can transfer the asset to the buyer
+	asClient(transactionContext, otherID)
+	err = assetTransfer.TransferAsset(transactionContext, "asset1", buyerID)
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+	chaincodeStub.Rollback()
+
+	asClient(transactionContext, buyerID)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "red", 10, "Tomoko", 0)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, "red", asset.Color)
+	require.NotNil(t, asset.Reservation)
+
+	asClient(transactionContext, sellerID)
+	err = assetTransfer.TransferAsset(transactionContext, "asset1", buyerID)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, buyerID, asset.Owner)
+	require.Nil(t, asset.Reservation)
+}
+
+func TestUpdateAssetClearsReservation(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko", Size: 10})
+	asClient(transactionContext, sellerID)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	// an update that gives the asset to the buyer ends the hold like a transfer
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "blue", 10, buyerID, 300)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, buyerID, asset.Owner)
+	require.Nil(t, asset.Reservation)
+}
+
+func TestReleaseReservation(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
+	asClient(transactionContext, sellerID)
+
+	start := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	chaincodeStub.SetTxTimestamp(start)
+
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.ReleaseReservation(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.Conflict, "the asset asset1 is not reserved")
+
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	err = assetTransfer.ReleaseReservation(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.Conflict, heldUntil)
+
+	asClient(transactionContext, buyerID)
+	err = assetTransfer.ReleaseReservation(transactionContext, "asset1")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asClient(transactionContext, sellerID)
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	asClient(transactionContext, otherID)
+	chaincodeStub.SetTxTimestamp(start.Add(2 * time.Minute))
+	err = assetTransfer.ReleaseReservation(transactionContext, "asset1")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Nil(t, asset.Reservation)
+}
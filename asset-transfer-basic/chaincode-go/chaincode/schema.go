@@ -0,0 +1,405 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+const (
+	assetDocType         = "asset"
+	schemaObjectType     = "schema"
+	schemaHeadObjectType = "schemaHead"
+)
+
+// AssetSchema is a registered version of the JSON schema for a document type
+type AssetSchema struct {
+	DocType string          `json:"docType"`
+	Version int             `json:"version"`
+	Schema  json.RawMessage `json:"schema"`
+	TxID    string          `json:"txID"`
+}
+
+// jsonSchema is the subset of JSON Schema understood by the registry: type,
+// required, properties, items, minimum, maximum, minLength, maxLength, enum
+// and pattern. Other keywords are accepted and ignored.
+type jsonSchema struct {
+	Type       string                 `json:"type,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Items      *jsonSchema            `json:"items,omitempty"`
+	Minimum    *float64               `json:"minimum,omitempty"`
+	Maximum    *float64               `json:"maximum,omitempty"`
+	MinLength  *int                   `json:"minLength,omitempty"`
+	MaxLength  *int                   `json:"maxLength,omitempty"`
+	Enum       []interface{}          `json:"enum,omitempty"`
+	Pattern    string                 `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// RegisterAssetSchema stores a new version of the schema that assets of the
+// given document type are validated against. Earlier versions are kept so that
+// assets written under them can still be validated.
+func (s *SmartContract) RegisterAssetSchema(ctx contractapi.TransactionContextInterface, docType string, schemaJSON string) (*AssetSchema, error) {
+	if docType == "" {
+		return nil, errorcode.New(errorcode.Validation, "docType must be a non-empty string")
+	}
+
+	_, err := parseSchema([]byte(schemaJSON))
+	if err != nil {
+		return nil, errorcode.New(errorcode.Validation, "%v", err)
+	}
+
+	current, err := s.latestSchemaVersion(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &AssetSchema{
+		DocType: docType,
+		Version: current + 1,
+		Schema:  json.RawMessage(schemaJSON),
+		TxID:    ctx.GetStub().GetTxID(),
+	}
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaKey, err := ctx.GetStub().CreateCompositeKey(schemaObjectType, []string{docType, strconv.Itoa(schema.Version)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(schemaKey, schemaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state. %v", err)
+	}
+
+	headKey, err := ctx.GetStub().CreateCompositeKey(schemaHeadObjectType, []string{docType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(headKey, []byte(strconv.Itoa(schema.Version)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state. %v", err)
+	}
+
+	return schema, nil
+}
+
+// GetAssetSchema returns the given version of the schema for a document type.
+// Version 0 returns the latest version.
+func (s *SmartContract) GetAssetSchema(ctx contractapi.TransactionContextInterface, docType string, version int) (*AssetSchema, error) {
+	if version == 0 {
+		latest, err := s.latestSchemaVersion(ctx, docType)
+		if err != nil {
+			return nil, err
+		}
+		if latest == 0 {
+			return nil, errorcode.New(errorcode.NotFound, "no schema is registered for %s", docType)
+		}
+		version = latest
+	}
+
+	schemaKey, err := ctx.GetStub().CreateCompositeKey(schemaObjectType, []string{docType, strconv.Itoa(version)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	schemaBytes, err := ctx.GetStub().GetState(schemaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if schemaBytes == nil {
+		return nil, errorcode.New(errorcode.NotFound, "version %d of the %s schema does not exist", version, docType)
+	}
+
+	var schema AssetSchema
+	err = json.Unmarshal(schemaBytes, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+// ValidateAsset checks a stored asset against the schema version it was written under
+func (s *SmartContract) ValidateAsset(ctx contractapi.TransactionContextInterface, id string) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset.SchemaVersion == 0 {
+		return nil
+	}
+
+	schema, err := s.GetAssetSchema(ctx, assetDocType, asset.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	return validateAgainst(schema, asset)
+}
+
+// validateAsset validates an asset against the latest registered asset schema
+// and stamps it with that schema version. Assets are not validated when no
+// schema has been registered.
+func (s *SmartContract) validateAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	asset.SchemaVersion = 0
+
+	latest, err := s.latestSchemaVersion(ctx, assetDocType)
+	if err != nil {
+		return err
+	}
+	if latest == 0 {
+		return nil
+	}
+
+	schema, err := s.GetAssetSchema(ctx, assetDocType, latest)
+	if err != nil {
+		return err
+	}
+
+	err = validateAgainst(schema, asset)
+	if err != nil {
+		return err
+	}
+
+	asset.SchemaVersion = latest
+	return nil
+}
+
+// putAsset validates an asset and writes it to the world state. Every write
+// of an asset goes through it, so no stored asset violates its schema.
+func (s *SmartContract) putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	err := s.validateAsset(ctx, asset)
+	if err != nil {
+		return err
+	}
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+	return nil
+}
+
+func (s *SmartContract) latestSchemaVersion(ctx contractapi.TransactionContextInterface, docType string) (int, error) {
+	headKey, err := ctx.GetStub().CreateCompositeKey(schemaHeadObjectType, []string{docType})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	headBytes, err := ctx.GetStub().GetState(headKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if headBytes == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(headBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse latest %s schema version: %v", docType, err)
+	}
+
+	return version, nil
+}
+
+func validateAgainst(schema *AssetSchema, asset *Asset) error {
+	parsed, err := parseSchema(schema.Schema)
+	if err != nil {
+		return err
+	}
+
+	unstamped := *asset
+	unstamped.SchemaVersion = 0
+	unstamped.DataVersion = 0
+	assetJSON, err := json.Marshal(unstamped)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	err = json.Unmarshal(assetJSON, &value)
+	if err != nil {
+		return err
+	}
+
+	err = parsed.validate(value, "")
+	if err != nil {
+		return errorcode.New(errorcode.Validation, "the asset %s does not match version %d of the %s schema: %v", asset.ID, schema.Version, schema.DocType, err)
+	}
+
+	return nil
+}
+
+func parseSchema(schemaJSON []byte) (*jsonSchema, error) {
+	var schema jsonSchema
+	err := json.Unmarshal(schemaJSON, &schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+
+	err = schema.compile("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+// compile checks the schema for unsupported types and compiles its patterns
+func (js *jsonSchema) compile(path string) error {
+	switch js.Type {
+	case "", "object", "array", "string", "integer", "number", "boolean":
+	default:
+		return fmt.Errorf("invalid schema at %s: unsupported type %q", describePath(path), js.Type)
+	}
+
+	if js.Pattern != "" {
+		pattern, err := regexp.Compile(js.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema at %s: %v", describePath(path), err)
+		}
+		js.pattern = pattern
+	}
+
+	for name, property := range js.Properties {
+		if property == nil {
+			return fmt.Errorf("invalid schema at %s: property must be an object", describePath(joinPath(path, name)))
+		}
+		err := property.compile(joinPath(path, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	if js.Items != nil {
+		return js.Items.compile(path + "[]")
+	}
+
+	return nil
+}
+
+func (js *jsonSchema) validate(value interface{}, path string) error {
+	if len(js.Enum) > 0 && !inEnum(js.Enum, value) {
+		return fmt.Errorf("%s must be one of %v", describePath(path), js.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if js.Type != "" && js.Type != "object" {
+			return fmt.Errorf("%s must be of type %s", describePath(path), js.Type)
+		}
+		for _, name := range js.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s is required", describePath(joinPath(path, name)))
+			}
+		}
+		// properties are checked in a fixed order so that every endorser
+		// reports the same error
+		names := make([]string, 0, len(js.Properties))
+		for name := range js.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := v[name]; ok {
+				err := js.Properties[name].validate(field, joinPath(path, name))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+	case []interface{}:
+		if js.Type != "" && js.Type != "array" {
+			return fmt.Errorf("%s must be of type %s", describePath(path), js.Type)
+		}
+		if js.Items != nil {
+			for i, item := range v {
+				err := js.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+	case string:
+		if js.Type != "" && js.Type != "string" {
+			return fmt.Errorf("%s must be of type %s", describePath(path), js.Type)
+		}
+		if js.MinLength != nil && len(v) < *js.MinLength {
+			return fmt.Errorf("%s must be at least %d characters long", describePath(path), *js.MinLength)
+		}
+		if js.MaxLength != nil && len(v) > *js.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters long", describePath(path), *js.MaxLength)
+		}
+		if js.pattern != nil && !js.pattern.MatchString(v) {
+			return fmt.Errorf("%s must match pattern %s", describePath(path), js.Pattern)
+		}
+
+	case float64:
+		switch js.Type {
+		case "", "number":
+		case "integer":
+			if v != float64(int64(v)) {
+				return fmt.Errorf("%s must be of type integer", describePath(path))
+			}
+		default:
+			return fmt.Errorf("%s must be of type %s", describePath(path), js.Type)
+		}
+		if js.Minimum != nil && v < *js.Minimum {
+			return fmt.Errorf("%s must be >= %v", describePath(path), *js.Minimum)
+		}
+		if js.Maximum != nil && v > *js.Maximum {
+			return fmt.Errorf("%s must be <= %v", describePath(path), *js.Maximum)
+		}
+
+	case bool:
+		if js.Type != "" && js.Type != "boolean" {
+			return fmt.Errorf("%s must be of type %s", describePath(path), js.Type)
+		}
+	}
+
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range enum {
+		allowedJSON, err := json.Marshal(allowed)
+		if err == nil && string(allowedJSON) == string(valueJSON) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return path
+}
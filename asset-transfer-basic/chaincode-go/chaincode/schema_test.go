@@ -0,0 +1,163 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+const assetSchemaV1 = `{
+	"type": "object",
+	"required": ["ID", "color", "size", "owner"],
+	"properties": {
+		"ID": {"type": "string", "pattern": "^asset[0-9]+$"},
+		"color": {"type": "string", "enum": ["blue", "red", "green"]},
+		"size": {"type": "integer", "minimum": 1, "maximum": 100},
+		"owner": {"type": "string", "minLength": 1}
+	}
+}`
+
+const assetSchemaV2 = `{
+	"type": "object",
+	"properties": {
+		"size": {"type": "integer", "minimum": 50}
+	}
+}`
+
+func TestRegisterAssetSchema(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	schema, err := assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV1)
+	require.NoError(t, err)
+	require.Equal(t, 1, schema.Version)
+	chaincodeStub.Commit()
+
+	schema, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV2)
+	require.NoError(t, err)
+	require.Equal(t, 2, schema.Version)
+	chaincodeStub.Commit()
+
+	latest, err := assetTransfer.GetAssetSchema(transactionContext, "asset", 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, latest.Version)
+	require.JSONEq(t, assetSchemaV2, string(latest.Schema))
+
+	first, err := assetTransfer.GetAssetSchema(transactionContext, "asset", 1)
+	require.NoError(t, err)
+	require.JSONEq(t, assetSchemaV1, string(first.Schema))
+
+	_, err = assetTransfer.GetAssetSchema(transactionContext, "asset", 3)
+	requireErrorCode(t, err, errorcode.NotFound, "version 3 of the asset schema does not exist")
+
+	_, err = assetTransfer.GetAssetSchema(transactionContext, "marble", 0)
+	requireErrorCode(t, err, errorcode.NotFound, "no schema is registered for marble")
+
+	_, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", `{"type": "date"}`)
+	requireErrorCode(t, err, errorcode.Validation, `invalid schema at document: unsupported type "date"`)
+
+	_, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", `{"properties": {"ID": {"pattern": "("}}}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid schema at ID")
+
+	_, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", `not json`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse schema")
+}
+
+func TestCreateAssetValidatesSchema(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	_, err := assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV1)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 1, asset.SchemaVersion)
+
+	err = assetTransfer.CreateAsset(transactionContext, "pallet", "blue", 5, "Tomoko", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset pallet does not match version 1 of the asset schema: ID must match pattern ^asset[0-9]+$")
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "purple", 5, "Tomoko", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset2 does not match version 1 of the asset schema: color must be one of [blue red green]")
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "red", 0, "Tomoko", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset2 does not match version 1 of the asset schema: size must be >= 1")
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "red", 5, "", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset2 does not match version 1 of the asset schema: owner must be at least 1 characters long")
+
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "blue", 500, "Tomoko", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset1 does not match version 1 of the asset schema: size must be <= 100")
+}
+
+func TestValidateAssetUsesWrittenVersion(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	_, err := assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV1)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	_, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV2)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	err = assetTransfer.ValidateAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset1 does not match version 2 of the asset schema: size must be >= 50")
+
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "blue", 60, "Tomoko", 300)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, 2, asset.SchemaVersion)
+}
+
+func TestWritesValidateSchema(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t)
+	assetTransfer := chaincode.SmartContract{}
+
+	_, err := assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV1)
+	require.NoError(t, err)
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", "blue", 5, "Tomoko", 300)
+	require.NoError(t, err)
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "red", 5, "Brad", 400)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+	err = assetTransfer.DeleteAsset(transactionContext, "asset2")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	err = assetTransfer.TransferAsset(transactionContext, "asset1", "")
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset1 does not match version 1 of the asset schema: owner must be at least 1 characters long")
+	chaincodeStub.Rollback()
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, "Tomoko", asset.Owner)
+
+	_, err = assetTransfer.RegisterAssetSchema(transactionContext, "asset", assetSchemaV2)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asClient(transactionContext, sellerID)
+	err = assetTransfer.ReserveAsset(transactionContext, "asset1", buyerID, 60)
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset1 does not match version 2 of the asset schema: size must be >= 50")
+	chaincodeStub.Rollback()
+
+	_, err = assetTransfer.RestoreAsset(transactionContext, "asset2")
+	requireErrorCode(t, err, errorcode.Validation, "the asset asset2 does not match version 2 of the asset schema: size must be >= 50")
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
 )
 
 // SmartContract provides functions for managing an Asset
@@ -14,11 +16,14 @@ type SmartContract struct {
 
 // Asset describes basic details of what makes up a simple asset
 type Asset struct {
-	ID             string `json:"ID"`
-	Color          string `json:"color"`
-	Size           int    `json:"size"`
-	Owner          string `json:"owner"`
-	AppraisedValue int    `json:"appraisedValue"`
+	ID             string       `json:"ID"`
+	Color          string       `json:"color"`
+	Size           int          `json:"size"`
+	Owner          string       `json:"owner"`
+	AppraisedValue int          `json:"appraisedValue"`
+	Reservation    *Reservation `json:"reservation,omitempty"`
+	SchemaVersion  int          `json:"schemaVersion,omitempty"`
+	DataVersion    int          `json:"dataVersion,omitempty"`
 }
 
 // InitLedger adds a base set of assets to the ledger
@@ -33,15 +38,11 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 	}
 
 	for _, asset := range assets {
-		assetJSON, err := json.Marshal(asset)
+		asset.DataVersion = assetType.Version()
+		err := s.putAsset(ctx, &asset)
 		if err != nil {
 			return err
 		}
-
-		err = ctx.GetStub().PutState(asset.ID, assetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put to world state. %v", err)
-		}
 	}
 
 	return nil
@@ -49,12 +50,18 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 
 // CreateAsset issues a new asset to the world state with given details.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, owner string, appraisedValue int) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.createAsset(ctx, id, color, size, owner, appraisedValue)
+	})
+}
+
+func (s *SmartContract) createAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, owner string, appraisedValue int) error {
 	exists, err := s.AssetExists(ctx, id)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return fmt.Errorf("the asset %s already exists", id)
+		return errorcode.New(errorcode.AlreadyExists, "the asset %s already exists", id)
 	}
 
 	asset := Asset{
@@ -63,23 +70,19 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		Size:           size,
 		Owner:          owner,
 		AppraisedValue: appraisedValue,
+		DataVersion:    assetType.Version(),
 	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	return ctx.GetStub().PutState(id, assetJSON)
+	return s.putAsset(ctx, &asset)
 }
 
 // ReadAsset returns the asset stored in the world state with given id.
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
-	assetJSON, err := ctx.GetStub().GetState(id)
+	assetJSON, err := assetType.Get(ctx.GetStub(), id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, err
 	}
 	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s does not exist", id)
 	}
 
 	var asset Asset
@@ -93,41 +96,40 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, i
 
 // UpdateAsset updates an existing asset in the world state with provided parameters.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, owner string, appraisedValue int) error {
-	exists, err := s.AssetExists(ctx, id)
+	return idempotency.Do(ctx, nil, func() error {
+		return s.updateAsset(ctx, id, color, size, owner, appraisedValue)
+	})
+}
+
+func (s *SmartContract) updateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, owner string, appraisedValue int) error {
+	existing, err := s.ReadAsset(ctx, id)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
+
+	transferTo := ""
+	if owner != existing.Owner {
+		transferTo = owner
+	}
+	err = checkReservation(ctx, existing, transferTo)
+	if err != nil {
+		return err
 	}
 
-	// overwriting original asset with new asset
+	// overwriting original asset with new asset, keeping any reservation
+	// unless the asset changes hands
 	asset := Asset{
 		ID:             id,
 		Color:          color,
 		Size:           size,
 		Owner:          owner,
 		AppraisedValue: appraisedValue,
+		DataVersion:    assetType.Version(),
 	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	return ctx.GetStub().PutState(id, assetJSON)
-}
-
-// DeleteAsset deletes an given asset from the world state.
-func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.AssetExists(ctx, id)
-	if err != nil {
-		return err
+	if owner == existing.Owner {
+		asset.Reservation = existing.Reservation
 	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	return ctx.GetStub().DelState(id)
+	return s.putAsset(ctx, &asset)
 }
 
 // AssetExists returns true when asset with given ID exists in world state
@@ -141,19 +143,27 @@ func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface,
 }
 
 // TransferAsset updates the owner field of asset with given id in world state.
+// A reservation on the asset is consumed by the transfer.
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.transferAsset(ctx, id, newOwner)
+	})
+}
+
+func (s *SmartContract) transferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
 	asset, err := s.ReadAsset(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	asset.Owner = newOwner
-	assetJSON, err := json.Marshal(asset)
+	err = checkReservation(ctx, asset, newOwner)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+	asset.Owner = newOwner
+	asset.Reservation = nil
+	return s.putAsset(ctx, asset)
 }
 
 // GetAllAssets returns all assets found in world state
@@ -173,8 +183,13 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 			return nil, err
 		}
 
+		assetJSON, _, err := assetType.Upgrade(queryResponse.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", queryResponse.Key, err)
+		}
+
 		var asset Asset
-		err = json.Unmarshal(queryResponse.Value, &asset)
+		err = json.Unmarshal(assetJSON, &asset)
 		if err != nil {
 			return nil, err
 		}
@@ -1,183 +1,121 @@
 package chaincode_test
 
 import (
-	"encoding/json"
 	"fmt"
 	"testing"
 
-	"github.com/hyperledger/fabric-chaincode-go/shim"
-	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
-	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
 	"github.com/stretchr/testify/require"
 )
 
-//go:generate counterfeiter -o mocks/transaction.go -fake-name TransactionContext . transactionContext
-type transactionContext interface {
-	contractapi.TransactionContextInterface
-}
-
-//go:generate counterfeiter -o mocks/chaincodestub.go -fake-name ChaincodeStub . chaincodeStub
-type chaincodeStub interface {
-	shim.ChaincodeStubInterface
-}
-
-//go:generate counterfeiter -o mocks/statequeryiterator.go -fake-name StateQueryIterator . stateQueryIterator
-type stateQueryIterator interface {
-	shim.StateQueryIteratorInterface
-}
-
 func TestInitLedger(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext, chaincodeStub := prepLedger(t)
 
 	assetTransfer := chaincode.SmartContract{}
 	err := assetTransfer.InitLedger(transactionContext)
 	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	assets, err := assetTransfer.GetAllAssets(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, assets, 6)
 
-	chaincodeStub.PutStateReturns(fmt.Errorf("failed inserting key"))
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, writeErr: fmt.Errorf("failed inserting key")})
 	err = assetTransfer.InitLedger(transactionContext)
 	require.EqualError(t, err, "failed to put to world state. failed inserting key")
 }
 
 func TestCreateAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"})
 
 	assetTransfer := chaincode.SmartContract{}
-	err := assetTransfer.CreateAsset(transactionContext, "", "", 0, "", 0)
+	err := assetTransfer.CreateAsset(transactionContext, "asset2", "blue", 5, "Tomoko", 300)
 	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset2")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{ID: "asset2", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300, DataVersion: 1}, asset)
 
-	chaincodeStub.GetStateReturns([]byte{}, nil)
 	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 already exists")
+	requireErrorCode(t, err, errorcode.AlreadyExists, "the asset asset1 already exists")
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("unable to retrieve asset")})
 	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestReadAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	expectedAsset := chaincode.Asset{ID: "asset1", DataVersion: 1}
+	transactionContext, chaincodeStub := prepLedger(t, expectedAsset)
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(expectedAsset)
-	require.NoError(t, err)
-
-	chaincodeStub.GetStateReturns(bytes, nil)
 	assetTransfer := chaincode.SmartContract{}
-	asset, err := assetTransfer.ReadAsset(transactionContext, "")
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
 	require.NoError(t, err)
-	require.Equal(t, expectedAsset, asset)
+	require.Equal(t, &expectedAsset, asset)
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	_, err = assetTransfer.ReadAsset(transactionContext, "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
-
-	chaincodeStub.GetStateReturns(nil, nil)
-	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	asset, err = assetTransfer.ReadAsset(transactionContext, "asset2")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset2 does not exist")
 	require.Nil(t, asset)
-}
-
-func TestUpdateAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(expectedAsset)
-	require.NoError(t, err)
-
-	chaincodeStub.GetStateReturns(bytes, nil)
-	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.UpdateAsset(transactionContext, "", "", 0, "", 0)
-	require.NoError(t, err)
-
-	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 does not exist")
-
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("unable to retrieve asset")})
+	_, err = assetTransfer.ReadAsset(transactionContext, "asset1")
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
-func TestDeleteAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+func TestUpdateAsset(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"})
 
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.UpdateAsset(transactionContext, "asset1", "red", 10, "Brad", 400)
 	require.NoError(t, err)
+	chaincodeStub.Commit()
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	chaincodeStub.DelStateReturns(nil)
-	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.DeleteAsset(transactionContext, "")
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
 	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{ID: "asset1", Color: "red", Size: 10, Owner: "Brad", AppraisedValue: 400, DataVersion: 1}, asset)
 
-	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	err = assetTransfer.UpdateAsset(transactionContext, "asset2", "", 0, "", 0)
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset2 does not exist")
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.DeleteAsset(transactionContext, "")
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("unable to retrieve asset")})
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestTransferAsset(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1", Owner: "Tomoko"})
 
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.TransferAsset(transactionContext, "asset1", "Brad")
 	require.NoError(t, err)
+	chaincodeStub.Commit()
 
-	chaincodeStub.GetStateReturns(bytes, nil)
-	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.TransferAsset(transactionContext, "", "")
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
 	require.NoError(t, err)
+	require.Equal(t, "Brad", asset.Owner)
 
-	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.TransferAsset(transactionContext, "", "")
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("unable to retrieve asset")})
+	err = assetTransfer.TransferAsset(transactionContext, "asset1", "Max")
 	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestGetAllAssets(t *testing.T) {
-	asset := &chaincode.Asset{ID: "asset1"}
-	bytes, err := json.Marshal(asset)
-	require.NoError(t, err)
-
-	iterator := &mocks.StateQueryIterator{}
-	iterator.HasNextReturnsOnCall(0, true)
-	iterator.HasNextReturnsOnCall(1, false)
-	iterator.NextReturns(&queryresult.KV{Value: bytes}, nil)
-
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
+	asset := chaincode.Asset{ID: "asset1", DataVersion: 1}
+	transactionContext, chaincodeStub := prepLedger(t, asset)
 
-	chaincodeStub.GetStateByRangeReturns(iterator, nil)
 	assetTransfer := &chaincode.SmartContract{}
 	assets, err := assetTransfer.GetAllAssets(transactionContext)
 	require.NoError(t, err)
-	require.Equal(t, []*chaincode.Asset{asset}, assets)
+	require.Equal(t, []*chaincode.Asset{&asset}, assets)
 
-	iterator.HasNextReturns(true)
-	iterator.NextReturns(nil, fmt.Errorf("failed retrieving next item"))
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, nextErr: fmt.Errorf("failed retrieving next item")})
 	assets, err = assetTransfer.GetAllAssets(transactionContext)
 	require.EqualError(t, err, "failed retrieving next item")
 	require.Nil(t, assets)
 
-	chaincodeStub.GetStateByRangeReturns(nil, fmt.Errorf("failed retrieving all assets"))
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("failed retrieving all assets")})
 	assets, err = assetTransfer.GetAllAssets(transactionContext)
 	require.EqualError(t, err, "failed retrieving all assets")
 	require.Nil(t, assets)
@@ -0,0 +1,159 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+)
+
+const tombstoneObjectType = "tombstone"
+
+// DeletedAsset is the tombstone kept in world state for an asset removed by DeleteAsset
+type DeletedAsset struct {
+	Asset     Asset     `json:"asset"`
+	DeletedBy string    `json:"deletedBy"`
+	Reason    string    `json:"reason"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// DeleteAsset removes an asset from the world state and records a tombstone
+// holding its last value, the deleting client and the transaction time.
+func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
+	return s.DeleteAssetWithReason(ctx, id, "")
+}
+
+// DeleteAssetWithReason deletes an asset like DeleteAsset, and also records
+// the reason in the tombstone.
+func (s *SmartContract) DeleteAssetWithReason(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.deleteAsset(ctx, id, reason)
+	})
+}
+
+func (s *SmartContract) deleteAsset(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = checkReservation(ctx, asset, "")
+	if err != nil {
+		return err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+
+	deletedAt, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	tombstone := DeletedAsset{
+		Asset:     *asset,
+		DeletedBy: clientID,
+		Reason:    reason,
+		DeletedAt: deletedAt,
+	}
+	tombstoneJSON, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+
+	tombstoneKey, err := ctx.GetStub().CreateCompositeKey(tombstoneObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(tombstoneKey, tombstoneJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+
+	return ctx.GetStub().DelState(id)
+}
+
+// RestoreAsset puts a deleted asset back into the world state and removes its tombstone.
+// Assets consumed by SplitAsset or MergeAssets cannot be restored, as their
+// value now belongs to the derived assets.
+func (s *SmartContract) RestoreAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+	exists, err := s.AssetExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errorcode.New(errorcode.AlreadyExists, "the asset %s already exists", id)
+	}
+
+	tombstoneKey, err := ctx.GetStub().CreateCompositeKey(tombstoneObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	tombstoneJSON, err := ctx.GetStub().GetState(tombstoneKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if tombstoneJSON == nil {
+		return nil, errorcode.New(errorcode.NotFound, "the asset %s has not been deleted", id)
+	}
+
+	var tombstone DeletedAsset
+	err = json.Unmarshal(tombstoneJSON, &tombstone)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := s.readLineageNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil && len(node.Children) > 0 {
+		return nil, errorcode.New(errorcode.Conflict, "the asset %s was %s into %s and cannot be restored", id, tombstone.Reason, strings.Join(node.Children, ", "))
+	}
+
+	err = s.putAsset(ctx, &tombstone.Asset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetStub().DelState(tombstoneKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tombstone: %v", err)
+	}
+
+	return &tombstone.Asset, nil
+}
+
+// ListDeletedAssets returns the tombstones of all assets removed from world state
+func (s *SmartContract) ListDeletedAssets(ctx contractapi.TransactionContextInterface) ([]*DeletedAsset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(tombstoneObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var tombstones []*DeletedAsset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var tombstone DeletedAsset
+		err = json.Unmarshal(queryResponse.Value, &tombstone)
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, &tombstone)
+	}
+
+	return tombstones, nil
+}
@@ -0,0 +1,87 @@
+package chaincode_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAsset(t *testing.T) {
+	asset := chaincode.Asset{ID: "asset1", DataVersion: 1}
+	transactionContext, chaincodeStub := prepLedger(t, asset, chaincode.Asset{ID: "asset2"})
+	asClient(transactionContext, "auditor")
+
+	deletedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	chaincodeStub.SetTxTimestamp(deletedAt)
+	assetTransfer := chaincode.SmartContract{}
+	err := assetTransfer.DeleteAssetWithReason(transactionContext, "asset1", "damaged")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	tombstones, err := assetTransfer.ListDeletedAssets(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.DeletedAsset{{Asset: asset, DeletedBy: "auditor", Reason: "damaged", DeletedAt: deletedAt}}, tombstones)
+	_, err = assetTransfer.ReadAsset(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset1 does not exist")
+
+	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset1 does not exist")
+
+	transactionContext.SetClientIdentity(clientIdentity{err: fmt.Errorf("no identity")})
+	err = assetTransfer.DeleteAsset(transactionContext, "asset2")
+	require.EqualError(t, err, "failed to get client identity: no identity")
+
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("unable to retrieve asset")})
+	err = assetTransfer.DeleteAsset(transactionContext, "asset2")
+	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+}
+
+func TestRestoreAsset(t *testing.T) {
+	asset := chaincode.Asset{ID: "asset1", Color: "blue", DataVersion: 1}
+	transactionContext, chaincodeStub := prepLedger(t, asset)
+
+	assetTransfer := chaincode.SmartContract{}
+	require.NoError(t, assetTransfer.DeleteAssetWithReason(transactionContext, "asset1", "damaged"))
+	chaincodeStub.Commit()
+
+	restored, err := assetTransfer.RestoreAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, &asset, restored)
+	chaincodeStub.Commit()
+
+	stored, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, &asset, stored)
+	tombstones, err := assetTransfer.ListDeletedAssets(transactionContext)
+	require.NoError(t, err)
+	require.Empty(t, tombstones)
+
+	_, err = assetTransfer.RestoreAsset(transactionContext, "asset1")
+	requireErrorCode(t, err, errorcode.AlreadyExists, "the asset asset1 already exists")
+
+	_, err = assetTransfer.RestoreAsset(transactionContext, "asset2")
+	requireErrorCode(t, err, errorcode.NotFound, "the asset asset2 has not been deleted")
+}
+
+func TestListDeletedAssets(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, chaincode.Asset{ID: "asset1"}, chaincode.Asset{ID: "asset2"})
+
+	assetTransfer := &chaincode.SmartContract{}
+	require.NoError(t, assetTransfer.DeleteAssetWithReason(transactionContext, "asset1", "sold"))
+	chaincodeStub.Commit()
+
+	tombstones, err := assetTransfer.ListDeletedAssets(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	require.Equal(t, chaincode.Asset{ID: "asset1", DataVersion: 1}, tombstones[0].Asset)
+	require.Equal(t, "sold", tombstones[0].Reason)
+
+	transactionContext.SetStub(failingStub{ChaincodeStub: chaincodeStub, readErr: fmt.Errorf("failed retrieving tombstones")})
+	tombstones, err = assetTransfer.ListDeletedAssets(transactionContext)
+	require.EqualError(t, err, "failed retrieving tombstones")
+	require.Nil(t, tombstones)
+}
@@ -0,0 +1,211 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
+)
+
+// aggregatePageSize is the number of assets AggregateAssets reads per page.
+// It must not exceed the MaxLimit of assetQuerySchema, which bounds the
+// matches Evaluate returns from a page on LevelDB.
+const aggregatePageSize = 100
+
+// AssetAggregate is the value of a metric over the assets of a group
+type AssetAggregate struct {
+	Group string  `json:"group"`
+	Count int     `json:"count"`
+	Value float64 `json:"value"`
+}
+
+// assetGroups gets the attribute assets can be grouped by
+var assetGroups = map[string]func(asset *Asset) string{
+	"owner": func(asset *Asset) string { return asset.Owner },
+	"color": func(asset *Asset) string { return asset.Color },
+}
+
+// assetNumbers gets the numeric fields metrics can be computed over
+var assetNumbers = map[string]func(asset *Asset) float64{
+	"size":           func(asset *Asset) float64 { return float64(asset.Size) },
+	"appraisedValue": func(asset *Asset) float64 { return float64(asset.AppraisedValue) },
+}
+
+// AggregateAssets groups the assets matching a filter by owner or color and computes a metric
+// for each group. The metric is count, or sum, min, max or avg of a numeric field such as
+// sum:appraisedValue. The filter takes the filters of QueryAssetsBy, such as
+// {"filters":[{"field":"size","operator":"gt","value":4}]}, or is empty to aggregate every asset.
+// The assets are read a page at a time, with a paginated rich query or, on state databases
+// that do not support rich query (e.g. LevelDB), a paginated range query whose pages are
+// filtered by the chaincode. Paginated queries are only valid for read only transactions.
+// Example: Aggregation over paginated queries
+func (t *SimpleChaincode) AggregateAssets(ctx contractapi.TransactionContextInterface, groupBy, metric, filter string) ([]*AssetAggregate, error) {
+	group, ok := assetGroups[groupBy]
+	if !ok {
+		return nil, errorcode.New(errorcode.Validation, "assets can be grouped by owner or color, got %q", groupBy)
+	}
+	function, number, err := parseMetric(metric)
+	if err != nil {
+		return nil, err
+	}
+	query, queryString, err := compileAggregateFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates := make(map[string]*AssetAggregate)
+	err = forEachAssetPage(ctx, query, queryString, func(assets []*Asset) {
+		for _, asset := range assets {
+			name := group(asset)
+			aggregate, ok := aggregates[name]
+			if !ok {
+				aggregate = &AssetAggregate{Group: name}
+				aggregates[name] = aggregate
+			}
+
+			var value float64
+			if number != nil {
+				value = number(asset)
+			}
+			switch {
+			case function == "count":
+				aggregate.Value++
+			case function == "sum" || function == "avg":
+				aggregate.Value += value
+			case aggregate.Count == 0:
+				aggregate.Value = value
+			case function == "min":
+				aggregate.Value = math.Min(aggregate.Value, value)
+			case function == "max":
+				aggregate.Value = math.Max(aggregate.Value, value)
+			}
+			aggregate.Count++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*AssetAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		if function == "avg" {
+			aggregate.Value /= float64(aggregate.Count)
+		}
+		results = append(results, aggregate)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Group < results[j].Group })
+
+	return results, nil
+}
+
+// parseMetric returns the function of a metric and the numeric field it is computed over,
+// which is nil for count
+func parseMetric(metric string) (string, func(asset *Asset) float64, error) {
+	if metric == "count" {
+		return metric, nil, nil
+	}
+
+	parts := strings.SplitN(metric, ":", 2)
+	if len(parts) == 2 {
+		switch parts[0] {
+		case "sum", "min", "max", "avg":
+			if number, ok := assetNumbers[parts[1]]; ok {
+				return parts[0], number, nil
+			}
+		}
+	}
+	return "", nil, errorcode.New(errorcode.Validation, "the metric must be count, or sum, min, max or avg of size or appraisedValue such as sum:size, got %q", metric)
+}
+
+// compileAggregateFilter compiles a filter with assetQuerySchema and returns the query
+// string without its limit, which pagination replaces
+func compileAggregateFilter(filter string) (*querybuilder.Query, string, error) {
+	var criteria querybuilder.Criteria
+	if strings.TrimSpace(filter) != "" && json.Unmarshal([]byte(filter), &criteria) == nil && (len(criteria.Sort) > 0 || criteria.Limit != 0) {
+		return nil, "", errorcode.New(errorcode.Validation, "an aggregation filter cannot sort or limit the assets")
+	}
+	query, err := assetQuerySchema.Compile(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var queryJSON map[string]json.RawMessage
+	err = json.Unmarshal([]byte(query.QueryString), &queryJSON)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode query: %v", err)
+	}
+	delete(queryJSON, "limit")
+	queryString, err := json.Marshal(queryJSON)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	return query, string(queryString), nil
+}
+
+// forEachAssetPage calls fn with each page of assets matching a query
+func forEachAssetPage(ctx contractapi.TransactionContextInterface, query *querybuilder.Query, queryString string, fn func(assets []*Asset)) error {
+	bookmark := ""
+	for {
+		resultsIterator, responseMetadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, aggregatePageSize, bookmark)
+		if err != nil && richQueryUnsupported(err) {
+			return forEachAssetRangePage(ctx, query, fn)
+		}
+		if err != nil {
+			return err
+		}
+
+		assets, err := constructQueryResponseFromIterator(resultsIterator)
+		resultsIterator.Close()
+		if err != nil {
+			return err
+		}
+		fn(assets)
+
+		if responseMetadata.FetchedRecordsCount < aggregatePageSize || responseMetadata.Bookmark == "" {
+			return nil
+		}
+		bookmark = responseMetadata.Bookmark
+	}
+}
+
+// forEachAssetRangePage calls fn with the assets matching a query in each page of a range
+// query over every asset
+func forEachAssetRangePage(ctx contractapi.TransactionContextInterface, query *querybuilder.Query, fn func(assets []*Asset)) error {
+	bookmark := ""
+	for {
+		resultsIterator, responseMetadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", aggregatePageSize, bookmark)
+		if err != nil {
+			return err
+		}
+
+		values, err := readValues(resultsIterator)
+		resultsIterator.Close()
+		if err != nil {
+			return err
+		}
+		matches, err := query.Evaluate(values)
+		if err != nil {
+			return err
+		}
+		assets, err := unmarshalAssets(matches)
+		if err != nil {
+			return err
+		}
+		fn(assets)
+
+		if responseMetadata.FetchedRecordsCount < aggregatePageSize || responseMetadata.Bookmark == "" {
+			return nil
+		}
+		bookmark = responseMetadata.Bookmark
+	}
+}
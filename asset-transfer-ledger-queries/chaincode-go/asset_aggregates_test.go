@@ -0,0 +1,167 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+// stateDatabases runs a test against a state database with rich queries, like
+// CouchDB, and one without, like LevelDB
+func stateDatabases(t *testing.T, test func(t *testing.T, richQueries bool)) {
+	t.Run("CouchDB", func(t *testing.T) { test(t, true) })
+	t.Run("LevelDB", func(t *testing.T) { test(t, false) })
+}
+
+// prepLedger returns a transaction context backed by an in-memory world state
+// holding the given assets, created with CreateAsset so that they are indexed
+func prepLedger(t *testing.T, richQueries bool, assets ...*Asset) (*contractapi.TransactionContext, *stubtest.ChaincodeStub) {
+	chaincodeStub := stubtest.New()
+	if richQueries {
+		chaincodeStub.EnableRichQueries()
+	}
+	transactionContext := &contractapi.TransactionContext{}
+	transactionContext.SetStub(chaincodeStub)
+
+	chaincode := SimpleChaincode{}
+	for _, asset := range assets {
+		require.NoError(t, chaincode.CreateAsset(transactionContext, asset.ID, asset.Color, asset.Size, asset.Owner, asset.AppraisedValue))
+	}
+	chaincodeStub.Commit()
+
+	return transactionContext, chaincodeStub
+}
+
+// numberedAssets returns n blue assets owned by tom
+func numberedAssets(n int) []*Asset {
+	assets := make([]*Asset, n)
+	for i := range assets {
+		assets[i] = &Asset{ID: fmt.Sprintf("asset%03d", i), Color: "blue", Size: i, Owner: "tom", AppraisedValue: 100}
+	}
+	return assets
+}
+
+var aggregatedAssets = []*Asset{
+	{ID: "asset1", Color: "blue", Size: 5, Owner: "tom", AppraisedValue: 100},
+	{ID: "asset2", Color: "blue", Size: 10, Owner: "tom", AppraisedValue: 300},
+	{ID: "asset3", Color: "red", Size: 15, Owner: "jerry", AppraisedValue: 200},
+	{ID: "asset4", Color: "green", Size: 20, Owner: "jerry", AppraisedValue: 400},
+	{ID: "asset5", Color: "red", Size: 25, Owner: "tom", AppraisedValue: 500},
+}
+
+func TestAggregateAssets(t *testing.T) {
+	stateDatabases(t, func(t *testing.T, richQueries bool) {
+		transactionContext, _ := prepLedger(t, richQueries, aggregatedAssets...)
+		chaincode := SimpleChaincode{}
+
+		tests := []struct {
+			groupBy  string
+			metric   string
+			filter   string
+			expected []*AssetAggregate
+		}{
+			{
+				groupBy:  "owner",
+				metric:   "count",
+				expected: []*AssetAggregate{{Group: "jerry", Count: 2, Value: 2}, {Group: "tom", Count: 3, Value: 3}},
+			},
+			{
+				groupBy:  "owner",
+				metric:   "sum:size",
+				expected: []*AssetAggregate{{Group: "jerry", Count: 2, Value: 35}, {Group: "tom", Count: 3, Value: 40}},
+			},
+			{
+				groupBy:  "owner",
+				metric:   "min:appraisedValue",
+				expected: []*AssetAggregate{{Group: "jerry", Count: 2, Value: 200}, {Group: "tom", Count: 3, Value: 100}},
+			},
+			{
+				groupBy:  "owner",
+				metric:   "max:size",
+				expected: []*AssetAggregate{{Group: "jerry", Count: 2, Value: 20}, {Group: "tom", Count: 3, Value: 25}},
+			},
+			{
+				groupBy:  "owner",
+				metric:   "avg:appraisedValue",
+				expected: []*AssetAggregate{{Group: "jerry", Count: 2, Value: 300}, {Group: "tom", Count: 3, Value: 300}},
+			},
+			{
+				groupBy:  "color",
+				metric:   "count",
+				expected: []*AssetAggregate{{Group: "blue", Count: 2, Value: 2}, {Group: "green", Count: 1, Value: 1}, {Group: "red", Count: 2, Value: 2}},
+			},
+			{
+				groupBy:  "color",
+				metric:   "avg:size",
+				filter:   `{"filters":[{"field":"size","operator":"gt","value":10}]}`,
+				expected: []*AssetAggregate{{Group: "green", Count: 1, Value: 20}, {Group: "red", Count: 2, Value: 20}},
+			},
+			{
+				groupBy:  "owner",
+				metric:   "count",
+				filter:   `{"filters":[{"field":"size","operator":"gt","value":100}]}`,
+				expected: []*AssetAggregate{},
+			},
+		}
+
+		for _, test := range tests {
+			aggregates, err := chaincode.AggregateAssets(transactionContext, test.groupBy, test.metric, test.filter)
+			require.NoError(t, err, "%s %s %s", test.groupBy, test.metric, test.filter)
+			require.Equal(t, test.expected, aggregates, "%s %s %s", test.groupBy, test.metric, test.filter)
+		}
+	})
+}
+
+func TestAggregateAssetsPages(t *testing.T) {
+	stateDatabases(t, func(t *testing.T, richQueries bool) {
+		// pages that end exactly on the page size, and one that goes past it
+		for _, n := range []int{aggregatePageSize, 2 * aggregatePageSize, aggregatePageSize + 1} {
+			transactionContext, _ := prepLedger(t, richQueries, numberedAssets(n)...)
+			chaincode := SimpleChaincode{}
+
+			aggregates, err := chaincode.AggregateAssets(transactionContext, "owner", "count", "")
+			require.NoError(t, err)
+			require.Equal(t, []*AssetAggregate{{Group: "tom", Count: n, Value: float64(n)}}, aggregates, "%d assets", n)
+
+			aggregates, err = chaincode.AggregateAssets(transactionContext, "color", "max:size", "")
+			require.NoError(t, err)
+			require.Equal(t, []*AssetAggregate{{Group: "blue", Count: n, Value: float64(n - 1)}}, aggregates, "%d assets", n)
+		}
+	})
+}
+
+func TestAggregateAssetsErrors(t *testing.T) {
+	transactionContext, _ := prepLedger(t, true, aggregatedAssets...)
+	chaincode := SimpleChaincode{}
+
+	tests := []struct {
+		groupBy string
+		metric  string
+		filter  string
+		err     string
+	}{
+		{groupBy: "size", metric: "count", err: `assets can be grouped by owner or color, got "size"`},
+		{groupBy: "owner", metric: "sum", err: `the metric must be count, or sum, min, max or avg of size or appraisedValue such as sum:size, got "sum"`},
+		{groupBy: "owner", metric: "median:size", err: `got "median:size"`},
+		{groupBy: "owner", metric: "sum:owner", err: `got "sum:owner"`},
+		{groupBy: "owner", metric: "count", filter: `{"sort":[{"field":"size","direction":"desc"}]}`, err: "an aggregation filter cannot sort or limit the assets"},
+		{groupBy: "owner", metric: "count", filter: `{"limit":10}`, err: "an aggregation filter cannot sort or limit the assets"},
+		{groupBy: "owner", metric: "count", filter: `{"filters":[{"field":"docType","operator":"eq","value":"asset"}]}`, err: `docType`},
+	}
+
+	for _, test := range tests {
+		_, err := chaincode.AggregateAssets(transactionContext, test.groupBy, test.metric, test.filter)
+		coded, ok := errorcode.FromError(err)
+		require.True(t, ok, "%v", err)
+		require.Equal(t, errorcode.Validation, coded.Code, coded.Message)
+		require.Contains(t, coded.Message, test.err)
+	}
+}
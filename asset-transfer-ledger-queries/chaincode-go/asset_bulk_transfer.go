@@ -0,0 +1,127 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+// maxTransferBatchSize is the most assets TransferAssetByColorBatch transfers at once
+const maxTransferBatchSize = 100
+
+// BulkTransferResult is the progress of TransferAssetByColorBatch
+type BulkTransferResult struct {
+	// Scanned counts the assets read by the batch, including those of other colors
+	Scanned int `json:"scanned"`
+	// Transferred counts the assets the batch transferred, leaving out those
+	// that were already owned by the new owner
+	Transferred int `json:"transferred"`
+	// TotalTransferred counts the assets transferred by this and earlier batches
+	TotalTransferred int `json:"totalTransferred"`
+	// Bookmark is passed to the next batch, and is empty when every asset of
+	// the color has been transferred
+	Bookmark string `json:"bookmark"`
+	Done     bool   `json:"done"`
+}
+
+// transferBookmark is the position of a bulk transfer, which clients treat as opaque
+type transferBookmark struct {
+	Color string `json:"color"`
+	// AssetID is the key of the first asset the next batch reads
+	AssetID          string `json:"assetID"`
+	TotalTransferred int    `json:"totalTransferred"`
+}
+
+// TransferAssetByColorBatch transfers up to batchSize assets of a given color to a new owner,
+// starting from the bookmark returned by the previous batch, or from the first asset when the
+// bookmark is empty. Clients call it until the result is done, so that a color with many assets
+// is transferred in transactions that stay within size limits and are less likely to conflict.
+// Each batch reads batchSize assets with a range query that starts at the bookmarked asset key
+// and skips the assets of other colors. The color~name index cannot be used here: the shim only
+// accepts simple keys in range queries, a partial composite key always starts at the first
+// asset of the color, and paginated queries are only valid for read only transactions. In
+// exchange, each batch reads only its own slice of the assets, and the range the peer checks
+// for phantom reads ends at the last asset the batch reads.
+// Example: Resumable bulk update with a range query
+func (t *SimpleChaincode) TransferAssetByColorBatch(ctx contractapi.TransactionContextInterface, color, newOwner string, batchSize int, bookmark string) (*BulkTransferResult, error) {
+	if batchSize < 1 || batchSize > maxTransferBatchSize {
+		return nil, errorcode.New(errorcode.Validation, "the batch size must be between 1 and %d, got %d", maxTransferBatchSize, batchSize)
+	}
+	position, err := parseTransferBookmark(bookmark, color)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(position.AssetID, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &BulkTransferResult{TotalTransferred: position.TotalTransferred}
+	next := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if result.Scanned == batchSize {
+			next = queryResponse.Key
+			break
+		}
+		result.Scanned++
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		if asset.Color != color || asset.Owner == newOwner {
+			continue
+		}
+		transferred := asset
+		transferred.Owner = newOwner
+		err = putAsset(ctx, &asset, &transferred)
+		if err != nil {
+			return nil, fmt.Errorf("transfer failed for asset %s: %v", queryResponse.Key, err)
+		}
+		result.Transferred++
+	}
+	result.TotalTransferred += result.Transferred
+
+	if next == "" {
+		result.Done = true
+		return result, nil
+	}
+
+	bookmarkJSON, err := json.Marshal(transferBookmark{Color: color, AssetID: next, TotalTransferred: result.TotalTransferred})
+	if err != nil {
+		return nil, err
+	}
+	result.Bookmark = string(bookmarkJSON)
+	return result, nil
+}
+
+// parseTransferBookmark returns the position of a bulk transfer of a color, which is
+// the first asset when the bookmark is empty
+func parseTransferBookmark(bookmark, color string) (*transferBookmark, error) {
+	position := &transferBookmark{Color: color}
+	if bookmark == "" {
+		return position, nil
+	}
+
+	err := json.Unmarshal([]byte(bookmark), position)
+	if err != nil {
+		return nil, errorcode.New(errorcode.Validation, "invalid bookmark %q: %v", bookmark, err)
+	}
+	if position.Color != color {
+		return nil, errorcode.New(errorcode.Validation, "invalid bookmark %q: it continues a transfer of %s assets, not %s", bookmark, position.Color, color)
+	}
+	return position, nil
+}
@@ -0,0 +1,146 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+// requireOwners checks the owner of each asset
+func requireOwners(t *testing.T, ctx contractapi.TransactionContextInterface, owners map[string]string) {
+	chaincode := SimpleChaincode{}
+	for id, owner := range owners {
+		asset, err := chaincode.ReadAsset(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, owner, asset.Owner, id)
+	}
+}
+
+func TestTransferAssetByColorBatch(t *testing.T) {
+	assets := append(numberedAssets(5), &Asset{ID: "asset100", Color: "red", Size: 1, Owner: "tom", AppraisedValue: 100})
+	transactionContext, chaincodeStub := prepLedger(t, false, assets...)
+	chaincode := SimpleChaincode{}
+
+	result, err := chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 2, "")
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Scanned)
+	require.Equal(t, 2, result.Transferred)
+	require.Equal(t, 2, result.TotalTransferred)
+	require.False(t, result.Done)
+	require.NotEmpty(t, result.Bookmark)
+	chaincodeStub.Commit()
+	requireOwners(t, transactionContext, map[string]string{"asset000": "jerry", "asset001": "jerry", "asset002": "tom"})
+
+	// the next batch resumes after the assets already transferred
+	result, err = chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 2, result.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Scanned)
+	require.Equal(t, 2, result.Transferred)
+	require.Equal(t, 4, result.TotalTransferred)
+	require.False(t, result.Done)
+	chaincodeStub.Commit()
+	requireOwners(t, transactionContext, map[string]string{"asset002": "jerry", "asset003": "jerry", "asset004": "tom"})
+
+	// the last batch also reads the red asset, which it leaves alone
+	result, err = chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 2, result.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, &BulkTransferResult{Scanned: 2, Transferred: 1, TotalTransferred: 5, Done: true}, result)
+	chaincodeStub.Commit()
+	requireOwners(t, transactionContext, map[string]string{"asset004": "jerry", "asset100": "tom"})
+}
+
+func TestTransferAssetByColorBatchComplete(t *testing.T) {
+	transactionContext, _ := prepLedger(t, false, numberedAssets(3)...)
+	chaincode := SimpleChaincode{}
+
+	// a batch that ends on the last asset is done without another call
+	result, err := chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 3, "")
+	require.NoError(t, err)
+	require.Equal(t, &BulkTransferResult{Scanned: 3, Transferred: 3, TotalTransferred: 3, Done: true}, result)
+
+	result, err = chaincode.TransferAssetByColorBatch(transactionContext, "green", "jerry", 3, "")
+	require.NoError(t, err)
+	require.Equal(t, &BulkTransferResult{Scanned: 3, Done: true}, result)
+}
+
+func TestTransferAssetByColorBatchReadsItsSlice(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, false, numberedAssets(4)...)
+	chaincode := SimpleChaincode{}
+
+	result, err := chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 2, "")
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	result, err = chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 2, result.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, &BulkTransferResult{Scanned: 2, Transferred: 2, TotalTransferred: 4, Done: true}, result)
+	batch := chaincodeStub.Endorse()
+
+	require.NoError(t, chaincode.CreateAsset(transactionContext, "asset000a", "blue", 1, "tom", 100))
+	create := chaincodeStub.Endorse()
+
+	// the new asset sorts before the bookmark, outside the range the batch read
+	codes := chaincodeStub.CommitBlock(create, batch)
+	require.Equal(t, []peer.TxValidationCode{peer.TxValidationCode_VALID, peer.TxValidationCode_VALID}, codes)
+	requireOwners(t, transactionContext, map[string]string{"asset000a": "tom", "asset003": "jerry"})
+}
+
+func TestTransferAssetByColorBatchSkipsOwnedAssets(t *testing.T) {
+	assets := numberedAssets(4)
+	assets[1].Owner = "jerry"
+	assets[2].Owner = "jerry"
+	transactionContext, chaincodeStub := prepLedger(t, false, assets...)
+	chaincode := SimpleChaincode{}
+
+	result, err := chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 3, "")
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Scanned)
+	require.Equal(t, 1, result.Transferred)
+	require.Equal(t, 1, result.TotalTransferred)
+	require.False(t, result.Done)
+	chaincodeStub.Commit()
+
+	result, err = chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 3, result.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, &BulkTransferResult{Scanned: 1, Transferred: 1, TotalTransferred: 2, Done: true}, result)
+	chaincodeStub.Commit()
+	requireOwners(t, transactionContext, map[string]string{"asset000": "jerry", "asset001": "jerry", "asset002": "jerry", "asset003": "jerry"})
+}
+
+func TestTransferAssetByColorBatchErrors(t *testing.T) {
+	assets := append(numberedAssets(3), &Asset{ID: "asset100", Color: "red", Size: 1, Owner: "tom", AppraisedValue: 100})
+	transactionContext, _ := prepLedger(t, false, assets...)
+	chaincode := SimpleChaincode{}
+
+	result, err := chaincode.TransferAssetByColorBatch(transactionContext, "blue", "jerry", 1, "")
+	require.NoError(t, err)
+	blueBookmark := result.Bookmark
+
+	tests := []struct {
+		color     string
+		batchSize int
+		bookmark  string
+		err       string
+	}{
+		{color: "red", batchSize: 1, bookmark: blueBookmark, err: "it continues a transfer of blue assets, not red"},
+		{color: "blue", batchSize: 1, bookmark: "asset001", err: `invalid bookmark "asset001"`},
+		{color: "blue", batchSize: 0, err: "the batch size must be between 1 and 100, got 0"},
+		{color: "blue", batchSize: maxTransferBatchSize + 1, err: "the batch size must be between 1 and 100, got 101"},
+	}
+
+	for _, test := range tests {
+		_, err := chaincode.TransferAssetByColorBatch(transactionContext, test.color, "jerry", test.batchSize, test.bookmark)
+		coded, ok := errorcode.FromError(err)
+		require.True(t, ok, "%v", err)
+		require.Equal(t, errorcode.Validation, coded.Code, coded.Message)
+		require.Contains(t, coded.Message, test.err)
+	}
+	requireOwners(t, transactionContext, map[string]string{"asset100": "tom"})
+}
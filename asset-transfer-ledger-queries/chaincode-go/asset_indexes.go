@@ -0,0 +1,317 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/secondaryindex"
+)
+
+// sizeBucketWidth is the range of sizes that share an entry in the size index
+const sizeBucketWidth = 10
+
+// maxSizeBuckets is the most size buckets QueryAssetsBy reads before
+// scanning every asset instead
+const maxSizeBuckets = 100
+
+// maxIndexBatchSize is the most assets IndexAssets indexes at once
+const maxIndexBatchSize = 100
+
+// The indexes are normal key-value entries in the ledger, with composite keys based on
+// indexName~attribute~name, which enable efficient range queries on any state database.
+var (
+	colorIndex = &secondaryindex.Index{Name: index, Attributes: assetAttribute(func(asset *Asset) string { return asset.Color })}
+	ownerIndex = &secondaryindex.Index{Name: "owner~name", Attributes: assetAttribute(func(asset *Asset) string { return asset.Owner })}
+	sizeIndex  = &secondaryindex.Index{Name: "size~name", Attributes: assetAttribute(func(asset *Asset) string { return sizeBucket(float64(asset.Size)) })}
+
+	assetIndexes = secondaryindex.Indexes{colorIndex, ownerIndex, sizeIndex}
+)
+
+// assetAttribute returns the function that gets an indexed attribute from an asset's JSON
+func assetAttribute(attribute func(asset *Asset) string) func(value []byte) ([]string, error) {
+	return func(value []byte) ([]string, error) {
+		var asset Asset
+		err := json.Unmarshal(value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		return []string{attribute(&asset)}, nil
+	}
+}
+
+// sizeBucket returns the size index attribute of a size, which is the lowest size of its bucket
+func sizeBucket(size float64) string {
+	return strconv.Itoa(int(math.Floor(size/sizeBucketWidth)) * sizeBucketWidth)
+}
+
+// putAsset writes an asset and updates its index entries. previous is nil when the asset is created.
+func putAsset(ctx contractapi.TransactionContextInterface, previous *Asset, asset *Asset) error {
+	var previousBytes []byte
+	if previous != nil {
+		var err error
+		previousBytes, err = json.Marshal(previous)
+		if err != nil {
+			return err
+		}
+	}
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(asset.ID, assetBytes)
+	if err != nil {
+		return err
+	}
+
+	return assetIndexes.Update(ctx.GetStub(), asset.ID, previousBytes, assetBytes)
+}
+
+// IndexResult is the progress of IndexAssets
+type IndexResult struct {
+	// Indexed counts the assets the batch indexed
+	Indexed int `json:"indexed"`
+	// Bookmark is passed to the next batch, and is empty when every asset has been indexed
+	Bookmark string `json:"bookmark"`
+	Done     bool   `json:"done"`
+}
+
+// IndexAssets writes the index entries of up to batchSize assets, starting from the asset ID in
+// the bookmark, or from the first asset when the bookmark is empty. Assets written before the
+// owner~name and size~name indexes were added have no entries in them, and are not found by
+// QueryAssetsByOwner and QueryAssetsBy on LevelDB until clients have called IndexAssets until
+// the result is done. Indexing an asset again rewrites the same entries.
+func (t *SimpleChaincode) IndexAssets(ctx contractapi.TransactionContextInterface, batchSize int, bookmark string) (*IndexResult, error) {
+	if batchSize < 1 || batchSize > maxIndexBatchSize {
+		return nil, errorcode.New(errorcode.Validation, "the batch size must be between 1 and %d, got %d", maxIndexBatchSize, batchSize)
+	}
+
+	// the range of simple keys from the bookmark holds the assets not indexed yet
+	resultsIterator, err := ctx.GetStub().GetStateByRange(bookmark, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &IndexResult{}
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if result.Indexed == batchSize {
+			result.Bookmark = queryResult.Key
+			return result, nil
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResult.Value, &asset)
+		if err != nil || asset.DocType != "asset" {
+			continue
+		}
+		err = assetIndexes.Update(ctx.GetStub(), queryResult.Key, nil, queryResult.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to index asset %s: %v", queryResult.Key, err)
+		}
+		result.Indexed++
+	}
+
+	result.Done = true
+	return result, nil
+}
+
+// richQueryUnsupported reports whether a query failed because the state database is LevelDB
+func richQueryUnsupported(err error) bool {
+	return strings.Contains(err.Error(), "not supported for leveldb")
+}
+
+// readAssetsByKey reads the assets stored under the given keys, skipping those that no longer exist
+func readAssetsByKey(ctx contractapi.TransactionContextInterface, keys []string) ([][]byte, error) {
+	var values [][]byte
+	for _, key := range keys {
+		value, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset %s: %v", key, err)
+		}
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// queryAssetsByOwnerIndex finds the assets of an owner with the owner index
+func queryAssetsByOwnerIndex(ctx contractapi.TransactionContextInterface, owner string) ([]*Asset, error) {
+	keys, err := ownerIndex.Keys(ctx.GetStub(), owner)
+	if err != nil {
+		return nil, err
+	}
+	values, err := readAssetsByKey(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalAssets(values)
+}
+
+// queryAssetsByIndex evaluates a query against the assets found with the index that best
+// narrows down the matching assets, or against every asset if no index applies
+func queryAssetsByIndex(ctx contractapi.TransactionContextInterface, query *querybuilder.Query) ([]*Asset, error) {
+	keys, indexed, err := candidateKeys(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]byte
+	if indexed {
+		values, err = readAssetsByKey(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+		if err != nil {
+			return nil, err
+		}
+		defer resultsIterator.Close()
+
+		values, err = readValues(resultsIterator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := query.Evaluate(values)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalAssets(matches)
+}
+
+// candidateKeys returns the keys of the assets that may match a query, and false if no index applies.
+// An equality filter on the owner or color is preferred, then the size buckets of the size filters.
+func candidateKeys(ctx contractapi.TransactionContextInterface, query *querybuilder.Query) ([]string, bool, error) {
+	for _, filter := range query.Filters {
+		if filter.Operator != "eq" {
+			continue
+		}
+		var attributeIndex *secondaryindex.Index
+		switch filter.Field {
+		case "owner":
+			attributeIndex = ownerIndex
+		case "color":
+			attributeIndex = colorIndex
+		default:
+			continue
+		}
+
+		var value string
+		err := json.Unmarshal(filter.Value, &value)
+		if err != nil {
+			return nil, false, err
+		}
+		keys, err := attributeIndex.Keys(ctx.GetStub(), value)
+		return keys, true, err
+	}
+
+	buckets, ok := sizeBuckets(query.Filters)
+	if !ok {
+		return nil, false, nil
+	}
+	var keys []string
+	for _, bucket := range buckets {
+		bucketKeys, err := sizeIndex.Keys(ctx.GetStub(), bucket)
+		if err != nil {
+			return nil, false, err
+		}
+		keys = append(keys, bucketKeys...)
+	}
+	return keys, true, nil
+}
+
+// sizeBuckets returns the size buckets that can hold the sizes the filters allow,
+// and false if the sizes are not bounded above and below by a few buckets
+func sizeBuckets(filters []querybuilder.Filter) ([]string, bool) {
+	lowest, highest := math.Inf(-1), math.Inf(1)
+	for _, filter := range filters {
+		if filter.Field != "size" {
+			continue
+		}
+
+		if filter.Operator == "in" {
+			var sizes []float64
+			if json.Unmarshal(filter.Value, &sizes) != nil {
+				return nil, false
+			}
+			var buckets []string
+			seen := make(map[string]bool)
+			for _, size := range sizes {
+				bucket := sizeBucket(size)
+				if !seen[bucket] {
+					seen[bucket] = true
+					buckets = append(buckets, bucket)
+				}
+			}
+			return buckets, true
+		}
+
+		var size float64
+		if json.Unmarshal(filter.Value, &size) != nil {
+			return nil, false
+		}
+		switch filter.Operator {
+		case "eq":
+			lowest, highest = math.Max(lowest, size), math.Min(highest, size)
+		case "gt", "gte":
+			lowest = math.Max(lowest, size)
+		case "lt", "lte":
+			highest = math.Min(highest, size)
+		}
+	}
+	if math.IsInf(lowest, 0) || math.IsInf(highest, 0) || (highest-lowest)/sizeBucketWidth >= maxSizeBuckets {
+		return nil, false
+	}
+
+	var buckets []string
+	for size := math.Floor(lowest/sizeBucketWidth) * sizeBucketWidth; size <= highest; size += sizeBucketWidth {
+		buckets = append(buckets, sizeBucket(size))
+	}
+	return buckets, true
+}
+
+// readValues returns the values of the results of a query
+func readValues(resultsIterator shim.StateQueryIteratorInterface) ([][]byte, error) {
+	var values [][]byte
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, queryResult.Value)
+	}
+	return values, nil
+}
+
+func unmarshalAssets(values [][]byte) ([]*Asset, error) {
+	var assets []*Asset
+	for _, value := range values {
+		var asset Asset
+		err := json.Unmarshal(value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+	return assets, nil
+}
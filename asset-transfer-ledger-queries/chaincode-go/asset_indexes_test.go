@@ -0,0 +1,174 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutAsset(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, false)
+
+	asset := &Asset{DocType: "asset", ID: "asset1", Color: "blue", Size: 5, Owner: "tom", AppraisedValue: 100}
+	require.NoError(t, putAsset(transactionContext, nil, asset))
+	chaincodeStub.Commit()
+
+	assetBytes, err := chaincodeStub.GetState("asset1")
+	require.NoError(t, err)
+	stored := &Asset{}
+	require.NoError(t, json.Unmarshal(assetBytes, stored))
+	require.Equal(t, asset, stored)
+
+	requireIndexed := func(owner, color, size string) {
+		keys, err := ownerIndex.Keys(chaincodeStub, owner)
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset1"}, keys, owner)
+		keys, err = colorIndex.Keys(chaincodeStub, color)
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset1"}, keys, color)
+		keys, err = sizeIndex.Keys(chaincodeStub, size)
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset1"}, keys, size)
+	}
+	requireNotIndexed := func(owner, color, size string) {
+		keys, err := ownerIndex.Keys(chaincodeStub, owner)
+		require.NoError(t, err)
+		require.Empty(t, keys, owner)
+		keys, err = colorIndex.Keys(chaincodeStub, color)
+		require.NoError(t, err)
+		require.Empty(t, keys, color)
+		keys, err = sizeIndex.Keys(chaincodeStub, size)
+		require.NoError(t, err)
+		require.Empty(t, keys, size)
+	}
+	requireIndexed("tom", "blue", "0")
+
+	// the entries of the previous asset are replaced
+	updated := *asset
+	updated.Owner = "jerry"
+	updated.Color = "red"
+	updated.Size = 25
+	require.NoError(t, putAsset(transactionContext, asset, &updated))
+	chaincodeStub.Commit()
+	requireIndexed("jerry", "red", "20")
+	requireNotIndexed("tom", "blue", "0")
+}
+
+func TestSizeBuckets(t *testing.T) {
+	tests := []struct {
+		filters  string
+		buckets  []string
+		bucketed bool
+	}{
+		{filters: `[{"field":"size","operator":"eq","value":15}]`, buckets: []string{"10"}, bucketed: true},
+		{filters: `[{"field":"size","operator":"gte","value":5},{"field":"size","operator":"lt","value":30}]`, buckets: []string{"0", "10", "20", "30"}, bucketed: true},
+		{filters: `[{"field":"size","operator":"gt","value":-5},{"field":"size","operator":"lte","value":5}]`, buckets: []string{"-10", "0"}, bucketed: true},
+		{filters: `[{"field":"size","operator":"in","value":[1,5,12,40]}]`, buckets: []string{"0", "10", "40"}, bucketed: true},
+		{filters: `[{"field":"size","operator":"gt","value":5}]`},
+		{filters: `[{"field":"size","operator":"lt","value":5}]`},
+		{filters: `[{"field":"size","operator":"gt","value":0},{"field":"size","operator":"lt","value":1000}]`},
+		{filters: `[{"field":"owner","operator":"eq","value":"tom"}]`},
+		{filters: `[{"field":"size","operator":"eq","value":"large"}]`},
+	}
+
+	for _, test := range tests {
+		var filters []querybuilder.Filter
+		require.NoError(t, json.Unmarshal([]byte(test.filters), &filters))
+
+		buckets, bucketed := sizeBuckets(filters)
+		require.Equal(t, test.bucketed, bucketed, test.filters)
+		require.Equal(t, test.buckets, buckets, test.filters)
+	}
+}
+
+func TestCandidateKeys(t *testing.T) {
+	transactionContext, _ := prepLedger(t, false, aggregatedAssets...)
+
+	tests := []struct {
+		criteria string
+		keys     []string
+		indexed  bool
+	}{
+		{criteria: `{"filters":[{"field":"owner","operator":"eq","value":"jerry"}]}`, keys: []string{"asset3", "asset4"}, indexed: true},
+		{criteria: `{"filters":[{"field":"color","operator":"eq","value":"red"}]}`, keys: []string{"asset3", "asset5"}, indexed: true},
+		// an equality filter on the owner or color is preferred to the size buckets
+		{criteria: `{"filters":[{"field":"size","operator":"lt","value":12},{"field":"color","operator":"eq","value":"green"}]}`, keys: []string{"asset4"}, indexed: true},
+		// whole buckets are read, and the assets outside the range are left to the evaluation of the query
+		{criteria: `{"filters":[{"field":"size","operator":"gte","value":10},{"field":"size","operator":"lt","value":20}]}`, keys: []string{"asset2", "asset3", "asset4", "asset5"}, indexed: true},
+		{criteria: `{"filters":[{"field":"size","operator":"in","value":[5,7]}]}`, keys: []string{"asset1"}, indexed: true},
+		{criteria: `{"filters":[{"field":"owner","operator":"eq","value":"spike"}]}`, indexed: true},
+		{criteria: `{"filters":[{"field":"owner","operator":"ne","value":"tom"}]}`},
+		{criteria: `{"filters":[{"field":"appraisedValue","operator":"gt","value":100}]}`},
+		{criteria: `{}`},
+	}
+
+	for _, test := range tests {
+		query, err := assetQuerySchema.Compile(test.criteria)
+		require.NoError(t, err, test.criteria)
+
+		keys, indexed, err := candidateKeys(transactionContext, query)
+		require.NoError(t, err, test.criteria)
+		require.Equal(t, test.indexed, indexed, test.criteria)
+		require.ElementsMatch(t, test.keys, keys, test.criteria)
+	}
+}
+
+func TestIndexAssets(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, false, aggregatedAssets[0])
+	chaincode := SimpleChaincode{}
+
+	// assets written before the owner and size indexes were added
+	for _, asset := range aggregatedAssets[1:] {
+		legacy := *asset
+		legacy.DocType = "asset"
+		assetBytes, err := json.Marshal(&legacy)
+		require.NoError(t, err)
+		require.NoError(t, chaincodeStub.PutState(asset.ID, assetBytes))
+	}
+	require.NoError(t, chaincodeStub.PutState("config", []byte(`{"docType":"config"}`)))
+	chaincodeStub.Commit()
+
+	assets, err := chaincode.QueryAssetsByOwner(transactionContext, "jerry")
+	require.NoError(t, err)
+	require.Empty(t, assets)
+
+	var batches []*IndexResult
+	bookmark := ""
+	for {
+		result, err := chaincode.IndexAssets(transactionContext, 2, bookmark)
+		require.NoError(t, err)
+		chaincodeStub.Commit()
+		batches = append(batches, result)
+		if result.Done {
+			break
+		}
+		bookmark = result.Bookmark
+	}
+	require.Equal(t, []*IndexResult{
+		{Indexed: 2, Bookmark: "asset3"},
+		{Indexed: 2, Bookmark: "asset5"},
+		{Indexed: 1, Done: true},
+	}, batches)
+
+	assets, err = chaincode.QueryAssetsByOwner(transactionContext, "jerry")
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+	assets, err = chaincode.QueryAssetsBy(transactionContext, `{"filters":[{"field":"size","operator":"gte","value":20},{"field":"size","operator":"lt","value":30}]}`)
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+	keys, err := colorIndex.Keys(chaincodeStub, "blue")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset2"}, keys)
+
+	for _, batchSize := range []int{0, maxIndexBatchSize + 1} {
+		_, err = chaincode.IndexAssets(transactionContext, batchSize, "")
+		require.Equal(t, errorcode.Validation, errorcode.CodeOf(err), "%v", err)
+	}
+}
@@ -11,7 +11,9 @@ peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["CreateAsset","asse
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["CreateAsset","asset3","blue","6","tom","70"]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAsset","asset2","jerry"]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAssetByColor","blue","jerry"]}'
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAssetByColorBatch","blue","tom","2",""]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["DeleteAsset","asset1"]}'
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["IndexAssets","100",""]}'
 
 ==== Query assets ====
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["ReadAsset","asset1"]}'
@@ -19,12 +21,21 @@ peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetsByRange","
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetHistory","asset1"]}'
 
 Rich Query (Only supported if CouchDB is used as state database):
-peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsByOwner","tom"]}'
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssets","{\"selector\":{\"owner\":\"tom\"}}"]}'
 
+Rich Query that falls back to secondary indexes if CouchDB is not used as state database:
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsByOwner","tom"]}'
+
+Query with validated criteria instead of a query string, which also falls back to secondary indexes:
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsBy","{\"filters\":[{\"field\":\"owner\",\"operator\":\"eq\",\"value\":\"tom\"},{\"field\":\"size\",\"operator\":\"gt\",\"value\":4}],\"sort\":[{\"field\":\"size\",\"direction\":\"desc\"}],\"limit\":10}"]}'
+
 Rich Query with Pagination (Only supported if CouchDB is used as state database):
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsWithPagination","{\"selector\":{\"owner\":\"tom\"}}","3",""]}'
 
+Aggregation over paginated queries, which falls back to paginated range queries if CouchDB is not used as state database:
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["AggregateAssets","owner","sum:appraisedValue",""]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["AggregateAssets","color","count","{\"filters\":[{\"field\":\"size\",\"operator\":\"gt\",\"value\":4}]}"]}'
+
 INDEXES TO SUPPORT COUCHDB RICH QUERIES
 
 Indexes in CouchDB are required in order to make JSON queries efficient and are required for
@@ -78,10 +89,25 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
 )
 
 const index = "color~name"
 
+// assetQuerySchema lists the asset fields QueryAssetsBy can filter and sort on.
+// Sorting on a field needs one of the indexes in META-INF/statedb/couchdb/indexes.
+var assetQuerySchema = &querybuilder.Schema{
+	Constraint: map[string]interface{}{"docType": "asset"},
+	Fields: map[string]querybuilder.Field{
+		"ID":             {Type: querybuilder.String},
+		"color":          {Type: querybuilder.String},
+		"size":           {Type: querybuilder.Number, Sortable: true},
+		"owner":          {Type: querybuilder.String},
+		"appraisedValue": {Type: querybuilder.Number, Sortable: true},
+	},
+	MaxLimit: 100,
+}
+
 // SimpleChaincode implements the fabric-contract-api-go programming model
 type SimpleChaincode struct {
 	contractapi.Contract
@@ -129,29 +155,13 @@ func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterfac
 		Owner:          owner,
 		AppraisedValue: appraisedValue,
 	}
-	assetBytes, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	err = ctx.GetStub().PutState(assetID, assetBytes)
-	if err != nil {
-		return err
-	}
 
-	//  Create an index to enable color-based range queries, e.g. return all blue assets.
+	//  Also create index entries to enable color, owner and size based range queries, e.g. return all blue assets.
 	//  An 'index' is a normal key-value entry in the ledger.
 	//  The key is a composite key, with the elements that you want to range query on listed first.
-	//  In our case, the composite key is based on indexName~color~name.
+	//  In our case, the color composite key is based on indexName~color~name.
 	//  This will enable very efficient state range queries based on composite keys matching indexName~color~*
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
-	if err != nil {
-		return err
-	}
-	//  Save index entry to world state. Only the key name is needed, no need to store a duplicate copy of the asset.
-	//  Note - passing a 'nil' value will effectively delete the key from state, therefore we pass null character as value
-	value := []byte{0x00}
-	return ctx.GetStub().PutState(colorNameIndexKey, value)
+	return putAsset(ctx, nil, asset)
 }
 
 // ReadAsset retrieves an asset from the ledger
@@ -180,18 +190,18 @@ func (t *SimpleChaincode) DeleteAsset(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
-	err = ctx.GetStub().DelState(assetID)
+	assetBytes, err := json.Marshal(asset)
 	if err != nil {
-		return fmt.Errorf("failed to delete asset %s: %v", assetID, err)
+		return err
 	}
 
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
+	err = ctx.GetStub().DelState(assetID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to delete asset %s: %v", assetID, err)
 	}
 
-	// Delete index entry
-	return ctx.GetStub().DelState(colorNameIndexKey)
+	// Delete index entries
+	return assetIndexes.Update(ctx.GetStub(), assetID, assetBytes, nil)
 }
 
 // TransferAsset transfers an asset by setting a new owner name on the asset
@@ -201,13 +211,9 @@ func (t *SimpleChaincode) TransferAsset(ctx contractapi.TransactionContextInterf
 		return err
 	}
 
-	asset.Owner = newOwner
-	assetBytes, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	return ctx.GetStub().PutState(assetID, assetBytes)
+	transferred := *asset
+	transferred.Owner = newOwner
+	return putAsset(ctx, asset, &transferred)
 }
 
 // constructQueryResponseFromIterator constructs a slice of assets from the resultsIterator
@@ -253,6 +259,7 @@ func (t *SimpleChaincode) GetAssetsByRange(ctx contractapi.TransactionContextInt
 // between endorsement time and commit time. The transaction is invalidated by the
 // committing peers if the result set has changed between endorsement time and commit time.
 // Therefore, range queries are a safe option for performing update transactions based on query results.
+// All assets of the color are transferred in one transaction; TransferAssetByColorBatch transfers them in batches.
 // Example: GetStateByPartialCompositeKey/RangeQuery
 func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContextInterface, color, newOwner string) error {
 	// Execute a key range query on all keys starting with 'color'
@@ -279,12 +286,9 @@ func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContex
 			if err != nil {
 				return err
 			}
-			asset.Owner = newOwner
-			assetBytes, err := json.Marshal(asset)
-			if err != nil {
-				return err
-			}
-			err = ctx.GetStub().PutState(returnedAssetID, assetBytes)
+			transferred := *asset
+			transferred.Owner = newOwner
+			err = putAsset(ctx, asset, &transferred)
 			if err != nil {
 				return fmt.Errorf("transfer failed for asset %s: %v", returnedAssetID, err)
 			}
@@ -297,11 +301,15 @@ func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContex
 // QueryAssetsByOwner queries for assets based on the owners name.
 // This is an example of a parameterized query where the query logic is baked into the chaincode,
 // and accepting a single query parameter (owner).
-// Only available on state databases that support rich query (e.g. CouchDB)
+// On state databases that do not support rich query (e.g. LevelDB), the owner~name index is used instead.
 // Example: Parameterized rich query
 func (t *SimpleChaincode) QueryAssetsByOwner(ctx contractapi.TransactionContextInterface, owner string) ([]*Asset, error) {
 	queryString := fmt.Sprintf(`{"selector":{"docType":"asset","owner":"%s"}}`, owner)
-	return getQueryResultForQueryString(ctx, queryString)
+	assets, err := getQueryResultForQueryString(ctx, queryString)
+	if err != nil && richQueryUnsupported(err) {
+		return queryAssetsByOwnerIndex(ctx, owner)
+	}
+	return assets, err
 }
 
 // QueryAssets uses a query string to perform a query for assets.
@@ -314,6 +322,45 @@ func (t *SimpleChaincode) QueryAssets(ctx contractapi.TransactionContextInterfac
 	return getQueryResultForQueryString(ctx, queryString)
 }
 
+// QueryAssetsBy queries for assets matching criteria such as
+// {"filters":[{"field":"owner","operator":"eq","value":"tom"}],"sort":[{"field":"size","direction":"desc"}],"limit":10}.
+// Unlike QueryAssets, the criteria are validated, can only refer to asset fields,
+// and only ever match assets. At most 100 assets are returned.
+// On state databases that do not support rich query (e.g. LevelDB), the criteria are evaluated
+// against the assets found with the owner, color or size index, or against every asset.
+// Example: Validated ad hoc rich query
+func (t *SimpleChaincode) QueryAssetsBy(ctx contractapi.TransactionContextInterface, criteria string) ([]*Asset, error) {
+	query, err := assetQuerySchema.Compile(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(query.QueryString)
+	if err != nil && richQueryUnsupported(err) {
+		return queryAssetsByIndex(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for len(assets) < query.Limit && resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var asset Asset
+		err = json.Unmarshal(queryResult.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
 // getQueryResultForQueryString executes the passed in query string.
 // The result set is built and returned as a byte array containing the JSON results.
 func getQueryResultForQueryString(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
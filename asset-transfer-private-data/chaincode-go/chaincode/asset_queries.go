@@ -9,15 +9,29 @@ package chaincode
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
 )
 
+// assetQuerySchema lists the asset fields QueryAssetsBy can filter and sort on.
+// Queries only match assets created with the objectType "asset".
+var assetQuerySchema = &querybuilder.Schema{
+	Constraint: map[string]interface{}{"objectType": "asset"},
+	Fields: map[string]querybuilder.Field{
+		"assetID": {Type: querybuilder.String},
+		"color":   {Type: querybuilder.String},
+		"size":    {Type: querybuilder.Number, Sortable: true},
+		"owner":   {Type: querybuilder.String},
+	},
+	MaxLimit: 100,
+}
+
 // ReadAsset reads the information from collection
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	log := logger.ForTransaction(ctx)
 
-	log.Printf("ReadAsset: collection %v, ID %v", assetCollection, assetID)
+	log.Debugf("ReadAsset: collection %v, ID %v", assetCollection, assetID)
 	assetJSON, err := ctx.GetStub().GetPrivateData(assetCollection, assetID) //get the asset from chaincode state
 	if err != nil {
 		return nil, fmt.Errorf("failed to read asset: %v", err)
@@ -25,7 +39,7 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, a
 
 	//No Asset found, return empty response
 	if assetJSON == nil {
-		log.Printf("%v does not exist in collection %v", assetID, assetCollection)
+		log.Debugf("%v does not exist in collection %v", assetID, assetCollection)
 		return nil, nil
 	}
 
@@ -41,13 +55,15 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, a
 
 // ReadAssetPrivateDetails reads the asset private details in organization specific collection
 func (s *SmartContract) ReadAssetPrivateDetails(ctx contractapi.TransactionContextInterface, collection string, assetID string) (*AssetPrivateDetails, error) {
-	log.Printf("ReadAssetPrivateDetails: collection %v, ID %v", collection, assetID)
+	log := logger.ForTransaction(ctx)
+
+	log.Debugf("ReadAssetPrivateDetails: collection %v, ID %v", collection, assetID)
 	assetDetailsJSON, err := ctx.GetStub().GetPrivateData(collection, assetID) // Get the asset from chaincode state
 	if err != nil {
 		return nil, fmt.Errorf("failed to read asset details: %v", err)
 	}
 	if assetDetailsJSON == nil {
-		log.Printf("AssetPrivateDetails for %v does not exist in collection %v", assetID, collection)
+		log.Debugf("AssetPrivateDetails for %v does not exist in collection %v", assetID, collection)
 		return nil, nil
 	}
 
@@ -62,7 +78,9 @@ func (s *SmartContract) ReadAssetPrivateDetails(ctx contractapi.TransactionConte
 
 // ReadTransferAgreement gets the buyer's identity from the transfer agreement from collection
 func (s *SmartContract) ReadTransferAgreement(ctx contractapi.TransactionContextInterface, assetID string) (*TransferAgreement, error) {
-	log.Printf("ReadTransferAgreement: collection %v, ID %v", assetCollection, assetID)
+	log := logger.ForTransaction(ctx)
+
+	log.Debugf("ReadTransferAgreement: collection %v, ID %v", assetCollection, assetID)
 	// composite key for TransferAgreement of this asset
 	transferAgreeKey, err := ctx.GetStub().CreateCompositeKey(transferAgreementObjectType, []string{assetID})
 	if err != nil {
@@ -74,7 +92,7 @@ func (s *SmartContract) ReadTransferAgreement(ctx contractapi.TransactionContext
 		return nil, fmt.Errorf("failed to read TransferAgreement: %v", err)
 	}
 	if buyerIdentity == nil {
-		log.Printf("TransferAgreement for %v does not exist", assetID)
+		log.Debugf("TransferAgreement for %v does not exist", assetID)
 		return nil, nil
 	}
 	agreement := &TransferAgreement{
@@ -161,6 +179,42 @@ func (s *SmartContract) QueryAssets(ctx contractapi.TransactionContextInterface,
 	return queryResults, nil
 }
 
+// QueryAssetsBy queries for assets matching criteria such as
+// {"filters":[{"field":"owner","operator":"eq","value":"user1"}],"sort":[{"field":"size","direction":"desc"}],"limit":10}.
+// Unlike QueryAssets, the criteria are validated, can only refer to asset fields,
+// and only ever match documents with the objectType "asset". At most 100 assets are returned.
+// Only available on state databases that support rich query (e.g. CouchDB)
+func (s *SmartContract) QueryAssetsBy(ctx contractapi.TransactionContextInterface, criteria string) ([]*Asset, error) {
+	query, err := assetQuerySchema.Compile(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(assetCollection, query.QueryString)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	results := []*Asset{}
+
+	for len(results) < query.Limit && resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var asset *Asset
+
+		err = json.Unmarshal(response.Value, &asset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+
+		results = append(results, asset)
+	}
+	return results, nil
+}
+
 // getQueryResultForQueryString executes the passed in query string.
 func (s *SmartContract) getQueryResultForQueryString(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
 
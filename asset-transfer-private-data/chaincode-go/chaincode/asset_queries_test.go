@@ -14,6 +14,8 @@ import (
 
 	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
 	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode/mocks"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -148,6 +150,43 @@ func TestQueryAssets(t *testing.T) {
 	require.Equal(t, []*chaincode.Asset{asset}, assets)
 }
 
+func TestQueryAssetsBy(t *testing.T) {
+	chaincodeStub := stubtest.New()
+	chaincodeStub.EnableRichQueries()
+	transactionContext := prepLedgerAs(chaincodeStub, myOrg1Msp, myOrg1Clientid)
+	assetTransferCC := &chaincode.SmartContract{}
+
+	for _, asset := range []*assetTransientInput{
+		{Type: "asset", ID: "asset1", Color: "gray", Size: 7, AppraisedValue: 500},
+		{Type: "asset", ID: "asset2", Color: "blue", Size: 3, AppraisedValue: 100},
+		{Type: "asset", ID: "asset3", Color: "gray", Size: 12, AppraisedValue: 300},
+		{Type: "valuableasset", ID: "asset4", Color: "gray", Size: 9, AppraisedValue: 300},
+	} {
+		assetBytes, err := json.Marshal(asset)
+		require.NoError(t, err)
+		chaincodeStub.SetTransient(map[string][]byte{"asset_properties": assetBytes})
+		require.NoError(t, assetTransferCC.CreateAsset(transactionContext))
+		chaincodeStub.Commit()
+	}
+
+	assets, err := assetTransferCC.QueryAssetsBy(transactionContext, `{
+		"filters": [{"field": "color", "operator": "eq", "value": "gray"}],
+		"sort": [{"field": "size", "direction": "desc"}]
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{
+		{Type: "asset", ID: "asset3", Color: "gray", Size: 12, Owner: myOrg1Clientid},
+		{Type: "asset", ID: "asset1", Color: "gray", Size: 7, Owner: myOrg1Clientid},
+	}, assets, "documents of other object types are not matched")
+
+	assets, err = assetTransferCC.QueryAssetsBy(transactionContext, `{"filters": [{"field": "size", "operator": "lt", "value": 10}], "limit": 1}`)
+	require.NoError(t, err)
+	require.Len(t, assets, 1)
+
+	_, err = assetTransferCC.QueryAssetsBy(transactionContext, `{"filters": [{"field": "appraisedValue", "operator": "gt", "value": 200}]}`)
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, `the field "appraisedValue" cannot be queried`).Error())
+}
+
 func TestGetAssetByRange(t *testing.T) {
 	transactionContext, chaincodeStub := prepMocksAsOrg1()
 	//Iterator with no records
@@ -10,15 +10,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/logging"
 )
 
 const assetCollection = "assetCollection"
 const transferAgreementObjectType = "transferAgreement"
 
+var logger = logging.New("asset-transfer-private-data")
+
 // SmartContract of this fabric sample
 type SmartContract struct {
 	contractapi.Contract
@@ -48,6 +51,7 @@ type TransferAgreement struct {
 // CreateAsset creates a new asset by placing the main asset details in the assetCollection
 // that can be read by both organizations. The appraisal value is stored in the owners org specific collection.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface) error {
+	log := logger.ForTransaction(ctx)
 
 	// Get new asset from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
@@ -59,7 +63,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	transientAssetJSON, ok := transientMap["asset_properties"]
 	if !ok {
 		//log error to stdout
-		return fmt.Errorf("asset not found in the transient map input")
+		return errorcode.New(errorcode.Validation, "asset not found in the transient map input")
 	}
 
 	type assetTransientInput struct {
@@ -77,19 +81,19 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	}
 
 	if len(assetInput.Type) == 0 {
-		return fmt.Errorf("objectType field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "objectType field must be a non-empty string")
 	}
 	if len(assetInput.ID) == 0 {
-		return fmt.Errorf("assetID field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "assetID field must be a non-empty string")
 	}
 	if len(assetInput.Color) == 0 {
-		return fmt.Errorf("color field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "color field must be a non-empty string")
 	}
 	if assetInput.Size <= 0 {
-		return fmt.Errorf("size field must be a positive integer")
+		return errorcode.New(errorcode.Validation, "size field must be a positive integer")
 	}
 	if assetInput.AppraisedValue <= 0 {
-		return fmt.Errorf("appraisedValue field must be a positive integer")
+		return errorcode.New(errorcode.Validation, "appraisedValue field must be a positive integer")
 	}
 
 	// Check if asset already exists
@@ -97,8 +101,8 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	if err != nil {
 		return fmt.Errorf("failed to get asset: %v", err)
 	} else if assetAsBytes != nil {
-		fmt.Println("Asset already exists: " + assetInput.ID)
-		return fmt.Errorf("this asset already exists: " + assetInput.ID)
+		log.Infof("Asset already exists: %v", assetInput.ID)
+		return errorcode.New(errorcode.AlreadyExists, "this asset already exists: %v", assetInput.ID)
 	}
 
 	// Get ID of submitting client identity
@@ -131,7 +135,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	// Save asset to private data collection
 	// Typical logger, logs to stdout/file in the fabric managed docker container, running this chaincode
 	// Look for container name like dev-peer0.org1.example.com-{chaincodename_version}-xyz
-	log.Printf("CreateAsset Put: collection %v, ID %v", assetCollection, assetInput.ID)
+	log.Infof("CreateAsset Put: collection %v, ID %v", assetCollection, assetInput.ID)
 	err = ctx.GetStub().PutPrivateData(assetCollection, assetInput.ID, assetJSONasBytes)
 	if err != nil {
 		return fmt.Errorf("failed to put asset into private data collecton: %v", err)
@@ -155,7 +159,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	}
 
 	// Put asset appraised value into owners org specific private data collection
-	log.Printf("Put: collection %v, ID %v", orgCollection, assetInput.ID)
+	log.Infof("Put: collection %v, ID %v", orgCollection, assetInput.ID)
 	err = ctx.GetStub().PutPrivateData(orgCollection, assetInput.ID, assetPrivateDetailsAsBytes)
 	if err != nil {
 		return fmt.Errorf("failed to put asset private details: %v", err)
@@ -168,6 +172,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 // org specifc collection, while the the buyer client ID is stored in the asset collection
 // using a composite key
 func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterface) error {
+	log := logger.ForTransaction(ctx)
 
 	// Get ID of submitting client identity
 	clientID, err := ctx.GetClientIdentity().GetID()
@@ -184,7 +189,7 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 	// Persist the JSON bytes as-is so that there is no risk of nondeterministic marshaling.
 	valueJSONasBytes, ok := transientMap["asset_value"]
 	if !ok {
-		return fmt.Errorf("asset_value key not found in the transient map")
+		return errorcode.New(errorcode.Validation, "asset_value key not found in the transient map")
 	}
 
 	// Unmarshal the tranisent map to get the asset ID.
@@ -196,10 +201,10 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 
 	// Do some error checking since we get the chance
 	if len(valueJSON.ID) == 0 {
-		return fmt.Errorf("assetID field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "assetID field must be a non-empty string")
 	}
 	if valueJSON.AppraisedValue <= 0 {
-		return fmt.Errorf("appraisedValue field must be a positive integer")
+		return errorcode.New(errorcode.Validation, "appraisedValue field must be a positive integer")
 	}
 
 	// Read asset from the private data collection
@@ -208,7 +213,7 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("error reading asset: %v", err)
 	}
 	if asset == nil {
-		return fmt.Errorf("%v does not exist", valueJSON.ID)
+		return errorcode.New(errorcode.NotFound, "%v does not exist", valueJSON.ID)
 	}
 	// Verify that the client is submitting request to peer in their organization
 	err = verifyClientOrgMatchesPeerOrg(ctx)
@@ -222,7 +227,7 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to infer private collection name for the org: %v", err)
 	}
 
-	log.Printf("AgreeToTransfer Put: collection %v, ID %v", orgCollection, valueJSON.ID)
+	log.Infof("AgreeToTransfer Put: collection %v, ID %v", orgCollection, valueJSON.ID)
 	// Put agreed value in the org specifc private data collection
 	err = ctx.GetStub().PutPrivateData(orgCollection, valueJSON.ID, valueJSONasBytes)
 	if err != nil {
@@ -237,7 +242,7 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to create composite key: %v", err)
 	}
 
-	log.Printf("AgreeToTransfer Put: collection %v, ID %v, Key %v", assetCollection, valueJSON.ID, transferAgreeKey)
+	log.Infof("AgreeToTransfer Put: collection %v, ID %v, Key %v", assetCollection, valueJSON.ID, transferAgreeKey)
 	err = ctx.GetStub().PutPrivateData(assetCollection, transferAgreeKey, []byte(clientID))
 	if err != nil {
 		return fmt.Errorf("failed to put asset bid: %v", err)
@@ -248,6 +253,7 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 
 // TransferAsset transfers the asset to the new owner by setting a new owner ID
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface) error {
+	log := logger.ForTransaction(ctx)
 
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
@@ -257,7 +263,7 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 	// Asset properties are private, therefore they get passed in transient field
 	transientTransferJSON, ok := transientMap["asset_owner"]
 	if !ok {
-		return fmt.Errorf("asset owner not found in the transient map")
+		return errorcode.New(errorcode.Validation, "asset owner not found in the transient map")
 	}
 
 	type assetTransferTransientInput struct {
@@ -272,19 +278,19 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 	}
 
 	if len(assetTransferInput.ID) == 0 {
-		return fmt.Errorf("assetID field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "assetID field must be a non-empty string")
 	}
 	if len(assetTransferInput.BuyerMSP) == 0 {
-		return fmt.Errorf("buyerMSP field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "buyerMSP field must be a non-empty string")
 	}
-	log.Printf("TransferAsset: verify asset exists ID %v", assetTransferInput.ID)
+	log.Infof("TransferAsset: verify asset exists ID %v", assetTransferInput.ID)
 	// Read asset from the private data collection
 	asset, err := s.ReadAsset(ctx, assetTransferInput.ID)
 	if err != nil {
 		return fmt.Errorf("error reading asset: %v", err)
 	}
 	if asset == nil {
-		return fmt.Errorf("%v does not exist", assetTransferInput.ID)
+		return errorcode.New(errorcode.NotFound, "%v does not exist", assetTransferInput.ID)
 	}
 	// Verify that the client is submitting request to peer in their organization
 	err = verifyClientOrgMatchesPeerOrg(ctx)
@@ -303,7 +309,7 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed ReadTransferAgreement to find buyerID: %v", err)
 	}
 	if transferAgreement.BuyerID == "" {
-		return fmt.Errorf("BuyerID not found in TransferAgreement for %v", assetTransferInput.ID)
+		return errorcode.New(errorcode.NotFound, "BuyerID not found in TransferAgreement for %v", assetTransferInput.ID)
 	}
 
 	// Transfer asset in private data collection to new owner
@@ -314,7 +320,7 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed marshalling asset %v: %v", assetTransferInput.ID, err)
 	}
 
-	log.Printf("TransferAsset Put: collection %v, ID %v", assetCollection, assetTransferInput.ID)
+	log.Infof("TransferAsset Put: collection %v, ID %v", assetCollection, assetTransferInput.ID)
 	err = ctx.GetStub().PutPrivateData(assetCollection, assetTransferInput.ID, assetJSONasBytes) //rewrite the asset
 	if err != nil {
 		return err
@@ -361,7 +367,7 @@ func (s *SmartContract) verifyAgreement(ctx contractapi.TransactionContextInterf
 	}
 
 	if clientID != owner {
-		return fmt.Errorf("error: submitting client identity does not own asset")
+		return errorcode.New(errorcode.Unauthorized, "error: submitting client identity does not own asset")
 	}
 
 	// Check 2: verify that the buyer has agreed to the appraised value
@@ -380,7 +386,7 @@ func (s *SmartContract) verifyAgreement(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to get hash of appraised value from owners collection %v: %v", collectionOwner, err)
 	}
 	if ownerAppraisedValueHash == nil {
-		return fmt.Errorf("hash of appraised value for %v does not exist in collection %v", assetID, collectionOwner)
+		return errorcode.New(errorcode.NotFound, "hash of appraised value for %v does not exist in collection %v", assetID, collectionOwner)
 	}
 
 	// Get hash of buyers agreed to value
@@ -389,12 +395,12 @@ func (s *SmartContract) verifyAgreement(ctx contractapi.TransactionContextInterf
 		return fmt.Errorf("failed to get hash of appraised value from buyer collection %v: %v", collectionBuyer, err)
 	}
 	if buyerAppraisedValueHash == nil {
-		return fmt.Errorf("hash of appraised value for %v does not exist in collection %v. AgreeToTransfer must be called by the buyer first", assetID, collectionBuyer)
+		return errorcode.New(errorcode.NotFound, "hash of appraised value for %v does not exist in collection %v. AgreeToTransfer must be called by the buyer first", assetID, collectionBuyer)
 	}
 
 	// Verify that the two hashes match
 	if !bytes.Equal(ownerAppraisedValueHash, buyerAppraisedValueHash) {
-		return fmt.Errorf("hash for appraised value for owner %x does not value for seller %x", ownerAppraisedValueHash, buyerAppraisedValueHash)
+		return errorcode.New(errorcode.Conflict, "hash for appraised value for owner %x does not value for seller %x", ownerAppraisedValueHash, buyerAppraisedValueHash)
 	}
 
 	return nil
@@ -411,7 +417,7 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 	// Asset properties are private, therefore they get passed in transient field
 	transientDeleteJSON, ok := transientMap["asset_delete"]
 	if !ok {
-		return fmt.Errorf("asset to delete not found in the transient map")
+		return errorcode.New(errorcode.Validation, "asset to delete not found in the transient map")
 	}
 
 	type assetDelete struct {
@@ -425,7 +431,7 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 	}
 
 	if len(assetDeleteInput.ID) == 0 {
-		return fmt.Errorf("assetID field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "assetID field must be a non-empty string")
 	}
 
 	// Verify that the client is submitting request to peer in their organization
@@ -434,13 +440,13 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 		return fmt.Errorf("DeleteAsset cannot be performed: Error %v", err)
 	}
 
-	log.Printf("Deleting Asset: %v", assetDeleteInput.ID)
+	logger.ForTransaction(ctx).Infof("Deleting Asset: %v", assetDeleteInput.ID)
 	valAsbytes, err := ctx.GetStub().GetPrivateData(assetCollection, assetDeleteInput.ID) //get the asset from chaincode state
 	if err != nil {
 		return fmt.Errorf("failed to read asset: %v", err)
 	}
 	if valAsbytes == nil {
-		return fmt.Errorf("asset not found: %v", assetDeleteInput.ID)
+		return errorcode.New(errorcode.NotFound, "asset not found: %v", assetDeleteInput.ID)
 	}
 
 	ownerCollection, err := getCollectionName(ctx) // Get owners collection
@@ -454,7 +460,7 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 		return fmt.Errorf("failed to read asset from owner's Collection: %v", err)
 	}
 	if valAsbytes == nil {
-		return fmt.Errorf("asset not found in owner's private Collection %v: %v", ownerCollection, assetDeleteInput.ID)
+		return errorcode.New(errorcode.NotFound, "asset not found in owner's private Collection %v: %v", ownerCollection, assetDeleteInput.ID)
 	}
 
 	// delete the asset from state
@@ -485,7 +491,7 @@ func (s *SmartContract) DeleteTranferAgreement(ctx contractapi.TransactionContex
 	// Asset properties are private, therefore they get passed in transient field
 	transientDeleteJSON, ok := transientMap["agreement_delete"]
 	if !ok {
-		return fmt.Errorf("asset to delete not found in the transient map")
+		return errorcode.New(errorcode.Validation, "asset to delete not found in the transient map")
 	}
 
 	type assetDelete struct {
@@ -499,7 +505,7 @@ func (s *SmartContract) DeleteTranferAgreement(ctx contractapi.TransactionContex
 	}
 
 	if len(assetDeleteInput.ID) == 0 {
-		return fmt.Errorf("transient input ID field must be a non-empty string")
+		return errorcode.New(errorcode.Validation, "transient input ID field must be a non-empty string")
 	}
 
 	// Verify that the client is submitting request to peer in their organization
@@ -523,10 +529,10 @@ func (s *SmartContract) DeleteTranferAgreement(ctx contractapi.TransactionContex
 		return fmt.Errorf("failed to read transfer_agreement: %v", err)
 	}
 	if valAsbytes == nil {
-		return fmt.Errorf("asset's transfer_agreement does not exist: %v", assetDeleteInput.ID)
+		return errorcode.New(errorcode.NotFound, "asset's transfer_agreement does not exist: %v", assetDeleteInput.ID)
 	}
 
-	log.Printf("Deleting TranferAgreement: %v", assetDeleteInput.ID)
+	logger.ForTransaction(ctx).Infof("Deleting TranferAgreement: %v", assetDeleteInput.ID)
 	err = ctx.GetStub().DelPrivateData(orgCollection, assetDeleteInput.ID) // Delete the asset
 	if err != nil {
 		return err
@@ -569,7 +575,7 @@ func verifyClientOrgMatchesPeerOrg(ctx contractapi.TransactionContextInterface)
 	}
 
 	if clientMSPID != peerMSPID {
-		return fmt.Errorf("client from org %v is not authorized to read or write private data from an org %v peer", clientMSPID, peerMSPID)
+		return errorcode.New(errorcode.Unauthorized, "client from org %v is not authorized to read or write private data from an org %v peer", clientMSPID, peerMSPID)
 	}
 
 	return nil
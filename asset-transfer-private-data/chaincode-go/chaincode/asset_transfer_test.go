@@ -16,6 +16,8 @@ import (
 
 	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
 	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode/mocks"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,7 +76,7 @@ func TestCreateAssetBadInput(t *testing.T) {
 
 	// No transient map
 	err := assetTransferCC.CreateAsset(transactionContext)
-	require.EqualError(t, err, "asset not found in the transient map input")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "asset not found in the transient map input").Error())
 
 	// transient map with incomplete asset data
 	assetPropMap := map[string][]byte{
@@ -90,7 +92,7 @@ func TestCreateAssetBadInput(t *testing.T) {
 	}
 	setReturnAssetPropsInTransientMap(t, chaincodeStub, testAsset)
 	err = assetTransferCC.CreateAsset(transactionContext)
-	require.EqualError(t, err, "assetID field must be a non-empty string")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "assetID field must be a non-empty string").Error())
 
 	testAsset = &assetTransientInput{
 		ID:    "id1",
@@ -98,7 +100,7 @@ func TestCreateAssetBadInput(t *testing.T) {
 	}
 	setReturnAssetPropsInTransientMap(t, chaincodeStub, testAsset)
 	err = assetTransferCC.CreateAsset(transactionContext)
-	require.EqualError(t, err, "objectType field must be a non-empty string")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "objectType field must be a non-empty string").Error())
 
 	// case when asset exists, GetPrivateData returns a valid data from ledger
 	testAsset = &assetTransientInput{
@@ -111,7 +113,7 @@ func TestCreateAssetBadInput(t *testing.T) {
 	setReturnAssetPropsInTransientMap(t, chaincodeStub, testAsset)
 	chaincodeStub.GetPrivateDataReturns([]byte{}, nil)
 	err = assetTransferCC.CreateAsset(transactionContext)
-	require.EqualError(t, err, "this asset already exists: id1")
+	require.EqualError(t, err, errorcode.New(errorcode.AlreadyExists, "this asset already exists: id1").Error())
 }
 
 func TestCreateAssetSuccessful(t *testing.T) {
@@ -162,7 +164,7 @@ func TestAgreeToTransferBadInput(t *testing.T) {
 	setReturnPrivateDataInStub(t, chaincodeStub, &origAsset)
 
 	err := assetTransferCC.AgreeToTransfer(transactionContext)
-	require.EqualError(t, err, "appraisedValue field must be a positive integer")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "appraisedValue field must be a positive integer").Error())
 
 	assetPrivDetail = &chaincode.AssetPrivateDetails{
 		//no ID
@@ -170,7 +172,7 @@ func TestAgreeToTransferBadInput(t *testing.T) {
 	}
 	setReturnAssetPrivateDetailsInTransientMap(t, chaincodeStub, assetPrivDetail)
 	err = assetTransferCC.AgreeToTransfer(transactionContext)
-	require.EqualError(t, err, "assetID field must be a non-empty string")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "assetID field must be a non-empty string").Error())
 
 	assetPrivDetail = &chaincode.AssetPrivateDetails{
 		ID:             "id1",
@@ -180,7 +182,7 @@ func TestAgreeToTransferBadInput(t *testing.T) {
 	//asset does not exist
 	setReturnPrivateDataInStub(t, chaincodeStub, nil)
 	err = assetTransferCC.AgreeToTransfer(transactionContext)
-	require.EqualError(t, err, "id1 does not exist")
+	require.EqualError(t, err, errorcode.New(errorcode.NotFound, "id1 does not exist").Error())
 }
 
 func TestAgreeToTransferSuccessful(t *testing.T) {
@@ -225,7 +227,7 @@ func TestTransferAssetBadInput(t *testing.T) {
 	setReturnAssetOwnerInTransientMap(t, chaincodeStub, assetNewOwner)
 	setReturnPrivateDataInStub(t, chaincodeStub, &chaincode.Asset{})
 	err := assetTransferCC.TransferAsset(transactionContext)
-	require.EqualError(t, err, "buyerMSP field must be a non-empty string")
+	require.EqualError(t, err, errorcode.New(errorcode.Validation, "buyerMSP field must be a non-empty string").Error())
 
 	assetNewOwner = &assetTransferTransientInput{
 		ID:       "id1",
@@ -235,7 +237,7 @@ func TestTransferAssetBadInput(t *testing.T) {
 	//asset does not exist
 	setReturnPrivateDataInStub(t, chaincodeStub, nil)
 	err = assetTransferCC.TransferAsset(transactionContext)
-	require.EqualError(t, err, "id1 does not exist")
+	require.EqualError(t, err, errorcode.New(errorcode.NotFound, "id1 does not exist").Error())
 }
 
 func TestTransferAssetSuccessful(t *testing.T) {
@@ -299,7 +301,7 @@ func TestTransferAssetByNonOwner(t *testing.T) {
 	}
 	setReturnPrivateDataInStub(t, chaincodeStub, &org2Asset)
 	err := assetTransferCC.TransferAsset(transactionContext)
-	require.EqualError(t, err, "failed transfer verification: error: submitting client identity does not own asset")
+	require.EqualError(t, err, "failed transfer verification: "+errorcode.New(errorcode.Unauthorized, "error: submitting client identity does not own asset").Error())
 }
 
 func TestTransferAssetWithoutAnAgreement(t *testing.T) {
@@ -325,7 +327,7 @@ func TestTransferAssetWithoutAnAgreement(t *testing.T) {
 	chaincodeStub.GetPrivateDataReturnsOnCall(1, []byte{}, nil)
 
 	err := assetTransferCC.TransferAsset(transactionContext)
-	require.EqualError(t, err, "BuyerID not found in TransferAgreement for id1")
+	require.EqualError(t, err, errorcode.New(errorcode.NotFound, "BuyerID not found in TransferAgreement for id1").Error())
 }
 
 func TestTransferAssetNonMatchingAppraisalValue(t *testing.T) {
@@ -352,7 +354,88 @@ func TestTransferAssetNonMatchingAppraisalValue(t *testing.T) {
 
 	err := assetTransferCC.TransferAsset(transactionContext)
 	require.Error(t, err, "Expected failed hash verification")
-	require.Contains(t, err.Error(), "failed transfer verification: hash for appraised value")
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	require.Contains(t, err.Error(), "hash for appraised value")
+}
+
+func TestTransferAssetLifecycle(t *testing.T) {
+	chaincodeStub := stubtest.New()
+	assetTransferCC := chaincode.SmartContract{}
+
+	asset := &assetTransientInput{Type: "testfulasset", ID: "id1", Color: "gray", Size: 7, AppraisedValue: 500}
+	assetBytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_properties": assetBytes})
+	err = assetTransferCC.CreateAsset(prepLedgerAs(chaincodeStub, myOrg1Msp, myOrg1Clientid))
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	value := &chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 500}
+	valueBytes, err := json.Marshal(value)
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_value": valueBytes})
+	err = assetTransferCC.AgreeToTransfer(prepLedgerAs(chaincodeStub, myOrg2Msp, myOrg2Clientid))
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	owner := &assetTransferTransientInput{ID: "id1", BuyerMSP: myOrg2Msp}
+	ownerBytes, err := json.Marshal(owner)
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_owner": ownerBytes})
+	transactionContext := prepLedgerAs(chaincodeStub, myOrg1Msp, myOrg1Clientid)
+	err = assetTransferCC.TransferAsset(transactionContext)
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	transferred, err := assetTransferCC.ReadAsset(transactionContext, "id1")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Asset{Type: "testfulasset", ID: "id1", Color: "gray", Size: 7, Owner: myOrg2Clientid}, transferred)
+
+	details, err := assetTransferCC.ReadAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id1")
+	require.NoError(t, err)
+	require.Nil(t, details, "the seller's appraisal is deleted")
+	details, err = assetTransferCC.ReadAssetPrivateDetails(transactionContext, myOrg2PrivCollection, "id1")
+	require.NoError(t, err)
+	require.Equal(t, value, details)
+
+	agreement, err := assetTransferCC.ReadTransferAgreement(transactionContext, "id1")
+	require.NoError(t, err)
+	require.Nil(t, agreement)
+}
+
+func TestTransferAssetRequiresMatchingAppraisals(t *testing.T) {
+	chaincodeStub := stubtest.New()
+	assetTransferCC := chaincode.SmartContract{}
+
+	asset := &assetTransientInput{Type: "testfulasset", ID: "id1", Color: "gray", Size: 7, AppraisedValue: 500}
+	assetBytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_properties": assetBytes})
+	err = assetTransferCC.CreateAsset(prepLedgerAs(chaincodeStub, myOrg1Msp, myOrg1Clientid))
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	valueBytes, err := json.Marshal(&chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 400})
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_value": valueBytes})
+	err = assetTransferCC.AgreeToTransfer(prepLedgerAs(chaincodeStub, myOrg2Msp, myOrg2Clientid))
+	require.NoError(t, err)
+	chaincodeStub.Commit()
+
+	ownerBytes, err := json.Marshal(&assetTransferTransientInput{ID: "id1", BuyerMSP: myOrg2Msp})
+	require.NoError(t, err)
+	chaincodeStub.SetTransient(map[string][]byte{"asset_owner": ownerBytes})
+	err = assetTransferCC.TransferAsset(prepLedgerAs(chaincodeStub, myOrg1Msp, myOrg1Clientid))
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	require.Contains(t, err.Error(), "hash for appraised value")
+}
+
+// prepLedgerAs returns a transaction context for a client of orgMSP that uses
+// the in-memory world state of chaincodeStub
+func prepLedgerAs(chaincodeStub *stubtest.ChaincodeStub, orgMSP, clientId string) *mocks.TransactionContext {
+	transactionContext, _ := prepMocks(orgMSP, clientId)
+	transactionContext.GetStubReturns(chaincodeStub)
+	return transactionContext
 }
 
 func prepMocksAsOrg1() (*mocks.TransactionContext, *mocks.ChaincodeStub) {
@@ -12,6 +12,7 @@ require (
 	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200511190512-bcfeb58dd83a
 	github.com/hyperledger/fabric-contract-api-go v1.1.0
 	github.com/hyperledger/fabric-protos-go v0.0.0-20200707132912-fee30f3ccd23
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
 	github.com/mailru/easyjson v0.7.1 // indirect
 	github.com/rogpeppe/go-internal v1.6.0 // indirect
 	github.com/stretchr/testify v1.5.1
@@ -23,3 +24,5 @@ require (
 	google.golang.org/protobuf v1.25.0
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
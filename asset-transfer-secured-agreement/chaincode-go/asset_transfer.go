@@ -10,12 +10,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/logging"
 )
 
 const (
@@ -25,6 +27,8 @@ const (
 	typeAssetBuyReceipt  = "BR"
 )
 
+var logger = logging.New("asset-transfer-secured-agreement")
+
 type SmartContract struct {
 	contractapi.Contract
 }
@@ -91,6 +95,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("failed to put Asset private details: %v", err)
 	}
 
+	logger.ForTransaction(ctx).Infof("asset %s created, owned by %s", asset.ID, clientOrgID)
 	return nil
 }
 
@@ -118,7 +123,13 @@ func (s *SmartContract) ChangePublicDescription(ctx contractapi.TransactionConte
 		return fmt.Errorf("failed to marshal asset: %v", err)
 	}
 
-	return ctx.GetStub().PutState(assetID, updatedAssetJSON)
+	err = ctx.GetStub().PutState(assetID, updatedAssetJSON)
+	if err != nil {
+		return err
+	}
+
+	logger.ForTransaction(ctx).Infof("public description of asset %s updated", assetID)
+	return nil
 }
 
 // AgreeToSell adds seller's asking price to seller's implicit private data collection
@@ -181,6 +192,8 @@ func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, p
 		return fmt.Errorf("failed to put asset bid: %v", err)
 	}
 
+	// the price itself is private, so only the kind of agreement is logged
+	logger.ForTransaction(ctx).Infof("%s price agreement for asset %s stored in %s", priceType, assetID, collection)
 	return nil
 }
 
@@ -267,11 +280,21 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed transfer verification: %v", err)
 	}
 
+	// The payment and the transfer are written in the same transaction,
+	// so the asset does not change hands unless the buyer pays
+	if agreement.Settlement != nil {
+		err = settlePayment(ctx, agreement.Settlement, agreement.Price)
+		if err != nil {
+			return fmt.Errorf("failed payment: %v", err)
+		}
+	}
+
 	err = transferAssetState(ctx, asset, immutablePropertiesJSON, clientOrgID, buyerOrgID, agreement.Price)
 	if err != nil {
 		return fmt.Errorf("failed asset transfer: %v", err)
 	}
 
+	logger.ForTransaction(ctx).Infof("asset %s transferred from %s to %s", assetID, clientOrgID, buyerOrgID)
 	return nil
 
 }
@@ -368,6 +391,29 @@ func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 	return nil
 }
 
+// settlePayment transfers the price from the buyer's token account to the seller's token account.
+// The token chaincode debits the buyer's account on behalf of the client submitting the transfer,
+// so the buyer must have approved an allowance of at least the price for the seller's client.
+func settlePayment(ctx contractapi.TransactionContextInterface, settlement *Settlement, price int) error {
+	if settlement.Chaincode == "" || settlement.BuyerAccount == "" || settlement.SellerAccount == "" {
+		return fmt.Errorf("settlement must name the token chaincode and the buyer and seller accounts")
+	}
+
+	args := [][]byte{
+		[]byte("TransferFrom"),
+		[]byte(settlement.BuyerAccount),
+		[]byte(settlement.SellerAccount),
+		[]byte(strconv.Itoa(price)),
+	}
+	response := ctx.GetStub().InvokeChaincode(settlement.Chaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens with chaincode %s: %s", price, settlement.Chaincode, response.Message)
+	}
+
+	logger.ForTransaction(ctx).Infof("paid %d tokens with chaincode %s", price, settlement.Chaincode)
+	return nil
+}
+
 // transferAssetState performs the public and private state updates for the transferred asset
 func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asset, immutablePropertiesJSON []byte, clientOrgID string, buyerOrgID string, price int) error {
 	asset.OwnerOrg = buyerOrgID
@@ -21,9 +21,19 @@ type QueryResult struct {
 }
 
 type Agreement struct {
-	ID      string `json:"asset_id"`
-	Price   int    `json:"price"`
-	TradeID string `json:"trade_id"`
+	ID         string      `json:"asset_id"`
+	Price      int         `json:"price"`
+	TradeID    string      `json:"trade_id"`
+	Settlement *Settlement `json:"settlement,omitempty"`
+}
+
+// Settlement are the optional payment terms of an agreement. When both parties agree to them,
+// the price is paid in tokens of a token-account-based chaincode on the same channel
+// in the transaction that transfers the asset.
+type Settlement struct {
+	Chaincode     string `json:"chaincode"`
+	BuyerAccount  string `json:"buyer_account"`
+	SellerAccount string `json:"seller_account"`
 }
 
 // ReadAsset returns the public asset data
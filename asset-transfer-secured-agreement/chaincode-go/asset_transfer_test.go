@@ -0,0 +1,171 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sellerOrg = "Org1MSP"
+	buyerOrg  = "Org2MSP"
+)
+
+type clientIdentity struct {
+	cid.ClientIdentity
+	mspID string
+}
+
+func (c clientIdentity) GetMSPID() (string, error) { return c.mspID, nil }
+
+// tokenChaincode is a token chaincode whose TransferFrom moves tokens out of an
+// account that approved an allowance, keeping balances and allowances by account
+type tokenChaincode struct{}
+
+func (tokenChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return shim.Success(nil)
+}
+
+func (tokenChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if function != "TransferFrom" || len(args) != 3 {
+		return shim.Error(fmt.Sprintf("unexpected call %s%v", function, args))
+	}
+	sender, recipient := args[0], args[1]
+	amount, err := strconv.Atoi(args[2])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	allowance, ok := readInt(stub, "allowance~"+sender)
+	if !ok {
+		return shim.Error(fmt.Sprintf("spender has no allowance from %s", sender))
+	}
+	if allowance < amount {
+		return shim.Error(fmt.Sprintf("spender has an allowance of %d from %s", allowance, sender))
+	}
+	senderBalance, _ := readInt(stub, "balance~"+sender)
+	recipientBalance, _ := readInt(stub, "balance~"+recipient)
+
+	writeInt(stub, "allowance~"+sender, allowance-amount)
+	writeInt(stub, "balance~"+sender, senderBalance-amount)
+	writeInt(stub, "balance~"+recipient, recipientBalance+amount)
+	return shim.Success(nil)
+}
+
+func readInt(stub shim.ChaincodeStubInterface, key string) (int, bool) {
+	value, _ := stub.GetState(key)
+	if value == nil {
+		return 0, false
+	}
+	n, _ := strconv.Atoi(string(value))
+	return n, true
+}
+
+func writeInt(stub shim.ChaincodeStubInterface, key string, n int) {
+	_ = stub.PutState(key, []byte(strconv.Itoa(n)))
+}
+
+// prepAgreement returns a transaction context in which the seller submits the transfer of
+// asset1 at the given price, which both parties have agreed to, and the token chaincode
+// holding the buyer's balance and allowance
+func prepAgreement(t *testing.T, agreement Agreement, balance int, allowance int) (*contractapi.TransactionContext, *stubtest.ChaincodeStub, *stubtest.ChaincodeStub) {
+	chaincodeStub := stubtest.New()
+	tokenStub := chaincodeStub.RegisterChaincode("token", tokenChaincode{})
+	writeInt(tokenStub, "balance~buyer", balance)
+	if allowance >= 0 {
+		writeInt(tokenStub, "allowance~buyer", allowance)
+	}
+
+	properties := []byte(`{"color":"blue","size":5}`)
+	priceJSON, err := json.Marshal(agreement)
+	require.NoError(t, err)
+
+	assetJSON, err := json.Marshal(Asset{ObjectType: "asset", ID: "asset1", OwnerOrg: sellerOrg})
+	require.NoError(t, err)
+	require.NoError(t, chaincodeStub.PutState("asset1", assetJSON))
+	require.NoError(t, chaincodeStub.PutPrivateData(buildCollectionName(sellerOrg), "asset1", properties))
+	saleKey, err := chaincodeStub.CreateCompositeKey(typeAssetForSale, []string{"asset1"})
+	require.NoError(t, err)
+	require.NoError(t, chaincodeStub.PutPrivateData(buildCollectionName(sellerOrg), saleKey, priceJSON))
+	bidKey, err := chaincodeStub.CreateCompositeKey(typeAssetBid, []string{"asset1"})
+	require.NoError(t, err)
+	require.NoError(t, chaincodeStub.PutPrivateData(buildCollectionName(buyerOrg), bidKey, priceJSON))
+	chaincodeStub.Commit()
+
+	chaincodeStub.SetTransient(map[string][]byte{"asset_properties": properties, "asset_price": priceJSON})
+	transactionContext := &contractapi.TransactionContext{}
+	transactionContext.SetStub(chaincodeStub)
+	transactionContext.SetClientIdentity(clientIdentity{mspID: sellerOrg})
+
+	return transactionContext, chaincodeStub, tokenStub
+}
+
+func requireInt(t *testing.T, stub shim.ChaincodeStubInterface, key string, expected int) {
+	n, ok := readInt(stub, key)
+	require.True(t, ok, key)
+	require.Equal(t, expected, n, key)
+}
+
+var settledAgreement = Agreement{
+	ID:         "asset1",
+	Price:      100,
+	TradeID:    "trade1",
+	Settlement: &Settlement{Chaincode: "token", BuyerAccount: "buyer", SellerAccount: "seller"},
+}
+
+func TestTransferAssetSettlesPayment(t *testing.T) {
+	transactionContext, chaincodeStub, tokenStub := prepAgreement(t, settledAgreement, 150, 120)
+	assetTransfer := SmartContract{}
+
+	require.NoError(t, assetTransfer.TransferAsset(transactionContext, "asset1", buyerOrg))
+	chaincodeStub.Commit()
+
+	asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, buyerOrg, asset.OwnerOrg)
+	requireInt(t, tokenStub, "balance~buyer", 50)
+	requireInt(t, tokenStub, "balance~seller", 100)
+	requireInt(t, tokenStub, "allowance~buyer", 20)
+}
+
+func TestTransferAssetPaymentFailure(t *testing.T) {
+	for name, allowance := range map[string]int{"missing allowance": -1, "insufficient allowance": 50} {
+		t.Run(name, func(t *testing.T) {
+			transactionContext, chaincodeStub, tokenStub := prepAgreement(t, settledAgreement, 150, allowance)
+			assetTransfer := SmartContract{}
+
+			err := assetTransfer.TransferAsset(transactionContext, "asset1", buyerOrg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "failed payment: failed to transfer 100 tokens with chaincode token")
+			chaincodeStub.Rollback()
+
+			asset, err := assetTransfer.ReadAsset(transactionContext, "asset1")
+			require.NoError(t, err)
+			require.Equal(t, sellerOrg, asset.OwnerOrg, "the asset stays with the seller")
+			requireInt(t, tokenStub, "balance~buyer", 150)
+		})
+	}
+}
+
+func TestSettlePayment(t *testing.T) {
+	transactionContext, _, _ := prepAgreement(t, settledAgreement, 150, 120)
+
+	err := settlePayment(transactionContext, &Settlement{Chaincode: "token", BuyerAccount: "buyer"}, 100)
+	require.EqualError(t, err, "settlement must name the token chaincode and the buyer and seller accounts")
+
+	err = settlePayment(transactionContext, &Settlement{Chaincode: "unknown", BuyerAccount: "buyer", SellerAccount: "seller"}, 100)
+	require.EqualError(t, err, "failed to transfer 100 tokens with chaincode unknown: chaincode unknown is not registered")
+}
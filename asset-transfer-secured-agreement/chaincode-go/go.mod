@@ -4,6 +4,11 @@ go 1.14
 
 require (
 	github.com/golang/protobuf v1.3.2
-	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
 	github.com/hyperledger/fabric-contract-api-go v1.0.0
+	github.com/hyperledger/fabric-protos-go v0.0.0-20200124220212-e9cfc186ba7b
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
+	github.com/stretchr/testify v1.5.1
 )
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
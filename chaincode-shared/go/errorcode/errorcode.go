@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package errorcode provides chaincode errors with stable, machine readable
+// codes. An Error is serialized as JSON, which the peer returns as the message
+// of the failed transaction, so that clients can decode the code instead of
+// parsing English text.
+package errorcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code identifies the kind of failure
+type Code string
+
+// Codes returned by the sample contracts
+const (
+	// NotFound means that a requested object does not exist
+	NotFound Code = "NOT_FOUND"
+	// AlreadyExists means that an object to be created already exists
+	AlreadyExists Code = "ALREADY_EXISTS"
+	// Unauthorized means that the client may not perform the operation
+	Unauthorized Code = "UNAUTHORIZED"
+	// Validation means that the arguments or transient data are invalid
+	Validation Code = "VALIDATION"
+	// Conflict means that the current state does not allow the operation
+	Conflict Code = "CONFLICT"
+)
+
+// Error is an error with a code
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// New returns an error with the given code and formatted message
+func New(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the JSON serialization of the error
+func (e *Error) Error() string {
+	var serialized strings.Builder
+	encoder := json.NewEncoder(&serialized)
+	// keep messages such as "size must be >= 50" readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(e); err != nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return strings.TrimSuffix(serialized.String(), "\n")
+}
+
+const marker = `{"code":"`
+
+// Decode finds a serialized Error in a message, which may have been wrapped
+// by the chaincode or prefixed by the peer and client SDK
+func Decode(message string) (*Error, bool) {
+	for offset := 0; ; {
+		index := strings.Index(message[offset:], marker)
+		if index < 0 {
+			return nil, false
+		}
+		offset += index
+
+		var e Error
+		decoder := json.NewDecoder(strings.NewReader(message[offset:]))
+		if err := decoder.Decode(&e); err == nil && e.Code != "" {
+			return &e, true
+		}
+		offset += len(marker)
+	}
+}
+
+// FromError returns the coded Error in err, if there is one
+func FromError(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return Decode(err.Error())
+}
+
+// CodeOf returns the code of the Error in err, or an empty code if there is
+// none
+func CodeOf(err error) Code {
+	if e, ok := FromError(err); ok {
+		return e.Code
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package errorcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	err := errorcode.New(errorcode.NotFound, "the asset %s does not exist", "asset1")
+	require.EqualError(t, err, `{"code":"NOT_FOUND","message":"the asset asset1 does not exist"}`)
+	require.Equal(t, errorcode.NotFound, errorcode.CodeOf(err))
+}
+
+func TestDecode(t *testing.T) {
+	coded := errorcode.New(errorcode.Conflict, `the asset "asset1" is reserved`)
+
+	for _, message := range []string{
+		coded.Error(),
+		fmt.Sprintf("failed to transfer asset: %v", coded),
+		fmt.Sprintf("Transaction processing for endorser [localhost:7051]: Chaincode status Code: (500) UNKNOWN. Description: %v", coded),
+		`ignored {"code":"broken ` + coded.Error(),
+	} {
+		e, ok := errorcode.Decode(message)
+		require.True(t, ok, message)
+		require.Equal(t, &errorcode.Error{Code: errorcode.Conflict, Message: `the asset "asset1" is reserved`}, e)
+	}
+
+	_, ok := errorcode.Decode("the asset asset1 does not exist")
+	require.False(t, ok)
+	_, ok = errorcode.Decode(`{"code":""}`)
+	require.False(t, ok)
+}
+
+func TestFromError(t *testing.T) {
+	coded := errorcode.New(errorcode.Unauthorized, "client is not authorized to mint new tokens")
+
+	e, ok := errorcode.FromError(fmt.Errorf("wrapped: %w", coded))
+	require.True(t, ok)
+	require.Equal(t, errorcode.Unauthorized, e.Code)
+
+	e, ok = errorcode.FromError(errors.New(coded.Error()))
+	require.True(t, ok)
+	require.Equal(t, "client is not authorized to mint new tokens", e.Message)
+
+	_, ok = errorcode.FromError(nil)
+	require.False(t, ok)
+	require.Equal(t, errorcode.Code(""), errorcode.CodeOf(errors.New("plain")))
+}
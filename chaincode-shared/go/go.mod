@@ -0,0 +1,10 @@
+module github.com/hyperledger/fabric-samples/chaincode-shared/go
+
+go 1.14
+
+require (
+	github.com/golang/protobuf v1.3.2
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
+	github.com/hyperledger/fabric-protos-go v0.0.0-20190919234611-2a87503ac7c9
+	github.com/stretchr/testify v1.5.1
+)
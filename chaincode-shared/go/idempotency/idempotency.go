@@ -0,0 +1,257 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package idempotency lets clients retry a transaction without executing it
+// twice. A client puts a request ID of its choice in the transient data of the
+// transaction, and the first successful execution records the request and its
+// result in the world state. A retry with the same request ID returns the
+// recorded result instead of executing the transaction again. Request IDs are
+// scoped to the client that submits them, so that clients choosing the same ID
+// do not see each other's results or block each other's requests.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+// TransientKey is the transient data field holding the request ID
+const TransientKey = "requestID"
+
+// objectType is the composite key object type of the records
+const objectType = "idempotency"
+
+// TransactionContext is the part of contractapi.TransactionContextInterface
+// that identifies the transaction and the client submitting it
+type TransactionContext interface {
+	GetStub() shim.ChaincodeStubInterface
+	GetClientIdentity() cid.ClientIdentity
+}
+
+// Record is the outcome of a request, kept in the world state until it is
+// pruned
+type Record struct {
+	RequestID string `json:"requestID"`
+	// ClientID is the ID of the client that submitted the request
+	ClientID string `json:"clientID"`
+	Function string `json:"function"`
+	// RequestHash is the SHA-256 hash of the function name and arguments,
+	// which a retry must match
+	RequestHash string `json:"requestHash"`
+	// Result is the JSON result of the transaction, empty if it has none
+	Result     string    `json:"result,omitempty"`
+	ResultHash string    `json:"resultHash"`
+	TxID       string    `json:"txID"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RequestID returns the request ID in the transient data of the transaction,
+// or an empty string if there is none
+func RequestID(stub shim.ChaincodeStubInterface) (string, error) {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transient data: %v", err)
+	}
+	return string(transient[TransientKey]), nil
+}
+
+// Do runs fn once per client and request ID. If the transaction has no
+// request ID, fn is always run. Otherwise the first successful run records the
+// value result points to, and later transactions of the same client with the
+// same request ID unmarshal the recorded value into result without running fn.
+// result may be nil for transactions without a result.
+func Do(ctx TransactionContext, result interface{}, fn func() error) error {
+	stub := ctx.GetStub()
+	requestID, err := RequestID(stub)
+	if err != nil {
+		return err
+	}
+	if requestID == "" {
+		return fn()
+	}
+
+	clientID, key, err := recordKey(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	requestHash := hashRequest(stub.GetArgs())
+
+	record, err := read(stub, key)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		if record.RequestHash != requestHash {
+			return errorcode.New(errorcode.Conflict, "the request ID %s was already used for a different %s request", requestID, record.Function)
+		}
+		if result != nil && record.Result != "" {
+			err = json.Unmarshal([]byte(record.Result), result)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal the recorded result of request %s: %v", requestID, err)
+			}
+		}
+		return nil
+	}
+
+	err = fn()
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	txTime, err := ptypes.Timestamp(timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to convert transaction timestamp: %v", err)
+	}
+
+	record = &Record{
+		RequestID:   requestID,
+		ClientID:    clientID,
+		RequestHash: requestHash,
+		TxID:        stub.GetTxID(),
+		Timestamp:   txTime,
+	}
+	if args := stub.GetArgs(); len(args) > 0 {
+		record.Function = string(args[0])
+	}
+	if result != nil {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the result of request %s: %v", requestID, err)
+		}
+		record.Result = string(resultJSON)
+	}
+	record.ResultHash = hash([]byte(record.Result))
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, recordJSON)
+}
+
+// Get returns the record of a request submitted by the client of the
+// transaction, or nil if the request has not been executed or its record was
+// pruned
+func Get(ctx TransactionContext, requestID string) (*Record, error) {
+	_, key, err := recordKey(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return read(ctx.GetStub(), key)
+}
+
+// recordKey returns the ID of the client of the transaction and the key of
+// its record of a request
+func recordKey(ctx TransactionContext, requestID string) (string, string, error) {
+	identity := ctx.GetClientIdentity()
+	if identity == nil {
+		return "", "", fmt.Errorf("failed to get client identity for request %s", requestID)
+	}
+	clientID, err := identity.GetID()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get client identity for request %s: %v", requestID, err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{clientID, requestID})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create the composite key for request %s: %v", requestID, err)
+	}
+	return clientID, key, nil
+}
+
+// Prune deletes up to limit records that are older than the retention window
+// at the transaction timestamp, and returns how many it deleted. A request
+// retried after its record is pruned executes again, so the retention window
+// must be longer than clients keep retrying.
+func Prune(stub shim.ChaincodeStubInterface, retention time.Duration, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, errorcode.New(errorcode.Validation, "the prune limit must be greater than zero, got %d", limit)
+	}
+
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	txTime, err := ptypes.Timestamp(timestamp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert transaction timestamp: %v", err)
+	}
+	cutoff := txTime.Add(-retention)
+
+	iterator, err := stub.GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get request records: %v", err)
+	}
+	defer iterator.Close()
+
+	pruned := 0
+	for pruned < limit && iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return pruned, err
+		}
+
+		var record Record
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to unmarshal request record %s: %v", response.Key, err)
+		}
+		if !record.Timestamp.Before(cutoff) {
+			continue
+		}
+
+		err = stub.DelState(response.Key)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to delete the record of request %s: %v", record.RequestID, err)
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
+
+func read(stub shim.ChaincodeStubInterface, key string) (*Record, error) {
+	recordJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record Record
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// hashRequest hashes the function name and arguments, each prefixed with its
+// length so that different argument lists cannot hash the same
+func hashRequest(args [][]byte) string {
+	digest := sha256.New()
+	for _, arg := range args {
+		fmt.Fprintf(digest, "%d:", len(arg))
+		digest.Write(arg)
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func hash(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
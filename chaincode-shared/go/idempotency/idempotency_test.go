@@ -0,0 +1,206 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idempotency_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIdentity struct {
+	cid.ClientIdentity
+	id string
+}
+
+func (i fakeIdentity) GetID() (string, error) { return i.id, nil }
+
+type fakeContext struct {
+	stub     *stubtest.ChaincodeStub
+	clientID string
+}
+
+func (c fakeContext) GetStub() shim.ChaincodeStubInterface { return c.stub }
+func (c fakeContext) GetClientIdentity() cid.ClientIdentity {
+	return fakeIdentity{id: c.clientID}
+}
+
+// request starts a transaction calling function with a request ID
+func request(stub *stubtest.ChaincodeStub, requestID string, args ...string) {
+	stub.SetArgs(args...)
+	if requestID != "" {
+		stub.SetTransient(map[string][]byte{idempotency.TransientKey: []byte(requestID)})
+	}
+}
+
+func TestDo(t *testing.T) {
+	stub := stubtest.New()
+	ctx := fakeContext{stub: stub, clientID: "alice"}
+	runs := 0
+	increment := func(result *int) func() error {
+		return func() error {
+			runs++
+			*result = runs
+			return nil
+		}
+	}
+
+	var result int
+	request(stub, "req1", "Increment", "counter")
+	require.NoError(t, idempotency.Do(ctx, &result, increment(&result)))
+	require.Equal(t, 1, result)
+	require.Equal(t, pb.TxValidationCode_VALID, stub.Commit())
+
+	result = 0
+	request(stub, "req1", "Increment", "counter")
+	require.NoError(t, idempotency.Do(ctx, &result, increment(&result)))
+	require.Equal(t, 1, result, "the retry returns the recorded result")
+	require.Equal(t, 1, runs)
+	require.Empty(t, stub.Endorse().Writes(), "the retry does not write")
+
+	request(stub, "req1", "Increment", "other")
+	err := idempotency.Do(ctx, &result, increment(&result))
+	require.EqualError(t, err, `{"code":"CONFLICT","message":"the request ID req1 was already used for a different Increment request"}`)
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	stub.Rollback()
+
+	request(stub, "", "Increment", "counter")
+	require.NoError(t, idempotency.Do(ctx, &result, increment(&result)))
+	require.Equal(t, 2, result, "requests without an ID always run")
+	stub.Commit()
+
+	record, err := idempotency.Get(ctx, "req1")
+	require.NoError(t, err)
+	require.Equal(t, &idempotency.Record{
+		RequestID:   "req1",
+		ClientID:    "alice",
+		Function:    "Increment",
+		RequestHash: record.RequestHash,
+		Result:      "1",
+		ResultHash:  "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+		TxID:        "tx1",
+		Timestamp:   stubtest.StartTime,
+	}, record)
+
+	record, err = idempotency.Get(ctx, "req2")
+	require.NoError(t, err)
+	require.Nil(t, record)
+}
+
+func TestDoPerClient(t *testing.T) {
+	stub := stubtest.New()
+	alice := fakeContext{stub: stub, clientID: "alice"}
+	bob := fakeContext{stub: stub, clientID: "bob"}
+	runs := 0
+	transfer := func() error {
+		runs++
+		return nil
+	}
+
+	request(stub, "1", "Transfer", "carol", "10")
+	require.NoError(t, idempotency.Do(alice, nil, transfer))
+	stub.Commit()
+
+	request(stub, "1", "Transfer", "carol", "10")
+	require.NoError(t, idempotency.Do(bob, nil, transfer))
+	stub.Commit()
+	require.Equal(t, 2, runs, "another client's request with the same ID and arguments is not a retry")
+
+	request(stub, "1", "Transfer", "carol", "10")
+	require.NoError(t, idempotency.Do(alice, nil, transfer))
+	stub.Commit()
+	require.Equal(t, 2, runs)
+
+	request(stub, "2", "Transfer", "dave", "5")
+	require.NoError(t, idempotency.Do(alice, nil, transfer))
+	stub.Commit()
+	request(stub, "2", "Mint", "5")
+	require.NoError(t, idempotency.Do(bob, nil, transfer), "an ID claimed by another client does not block the request")
+	stub.Commit()
+
+	record, err := idempotency.Get(bob, "2")
+	require.NoError(t, err)
+	require.Equal(t, "bob", record.ClientID)
+	require.Equal(t, "Mint", record.Function)
+}
+
+func TestDoFailure(t *testing.T) {
+	stub := stubtest.New()
+	ctx := fakeContext{stub: stub, clientID: "alice"}
+
+	request(stub, "req1", "Transfer", "bob", "10")
+	err := idempotency.Do(ctx, nil, func() error {
+		return errors.New("insufficient funds")
+	})
+	require.EqualError(t, err, "insufficient funds")
+	stub.Rollback()
+
+	request(stub, "req1", "Transfer", "bob", "10")
+	runs := 0
+	require.NoError(t, idempotency.Do(ctx, nil, func() error {
+		runs++
+		return nil
+	}))
+	require.Equal(t, 1, runs, "a failed request is not recorded")
+}
+
+func TestConcurrentRetries(t *testing.T) {
+	stub := stubtest.New()
+	ctx := fakeContext{stub: stub, clientID: "alice"}
+	transfer := func() error {
+		return stub.PutState("balance", []byte("90"))
+	}
+
+	request(stub, "req1", "Transfer", "bob", "10")
+	require.NoError(t, idempotency.Do(ctx, nil, transfer))
+	first := stub.Endorse()
+	request(stub, "req1", "Transfer", "bob", "10")
+	require.NoError(t, idempotency.Do(ctx, nil, transfer))
+	retry := stub.Endorse()
+
+	require.Equal(t, []pb.TxValidationCode{
+		pb.TxValidationCode_VALID,
+		pb.TxValidationCode_MVCC_READ_CONFLICT,
+	}, stub.CommitBlock(first, retry), "a retry endorsed before the request commits is invalidated")
+}
+
+func TestPrune(t *testing.T) {
+	stub := stubtest.New()
+	ctx := fakeContext{stub: stub, clientID: "alice"}
+	for _, requestID := range []string{"req1", "req2", "req3"} {
+		request(stub, requestID, "Create", requestID)
+		require.NoError(t, idempotency.Do(ctx, nil, func() error { return nil }))
+		stub.Commit()
+	}
+
+	stub.SetTxTimestamp(stubtest.StartTime.Add(time.Hour + time.Second))
+	pruned, err := idempotency.Prune(stub, time.Hour, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, pruned, "only req1 is older than an hour")
+	stub.Commit()
+
+	stub.SetTxTimestamp(stubtest.StartTime.Add(2 * time.Hour))
+	pruned, err = idempotency.Prune(stub, time.Hour, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, pruned, "the limit is respected")
+	stub.Commit()
+
+	for requestID, exists := range map[string]bool{"req1": false, "req2": false, "req3": true} {
+		record, err := idempotency.Get(ctx, requestID)
+		require.NoError(t, err)
+		require.Equal(t, exists, record != nil, requestID)
+	}
+
+	_, err = idempotency.Prune(stub, time.Hour, 0)
+	require.EqualError(t, err, `{"code":"VALIDATION","message":"the prune limit must be greater than zero, got 0"}`)
+}
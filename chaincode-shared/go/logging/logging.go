@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package logging writes structured, leveled JSON logs from chaincode. Entries
+// logged for a transaction carry its ID, channel, function and client MSP, so
+// that they can be correlated with the peer logs.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels, from the most to the least verbose
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarningLevel
+	ErrorLevel
+)
+
+// LevelEnv is the environment variable that sets the log level. The level the
+// peer passes to chaincode in CORE_CHAINCODE_LOGGING_LEVEL is used if it is
+// not set, and info if neither is.
+const LevelEnv = "CHAINCODE_LOG_LEVEL"
+
+var levelNames = []string{"DEBUG", "INFO", "WARNING", "ERROR"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the given name, ignoring case
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "WARN", "WARNING":
+		return WarningLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q, expected one of debug, info, warning or error", name)
+}
+
+// TransactionContext is the part of contractapi.TransactionContextInterface
+// that the logger reads transaction details from
+type TransactionContext interface {
+	GetStub() shim.ChaincodeStubInterface
+	GetClientIdentity() cid.ClientIdentity
+}
+
+// Logger writes JSON log entries at or above its level. Loggers derived with
+// With or ForTransaction share their parent's output.
+type Logger struct {
+	out    *output
+	level  Level
+	fields map[string]interface{}
+}
+
+type output struct {
+	mutex sync.Mutex
+	w     io.Writer
+	now   func() time.Time
+}
+
+// New returns a logger for the named chaincode that writes to stderr at the
+// level set in the environment
+func New(name string) *Logger {
+	level := InfoLevel
+	for _, env := range []string{LevelEnv, "CORE_CHAINCODE_LOGGING_LEVEL"} {
+		if value := os.Getenv(env); value != "" {
+			if parsed, err := ParseLevel(value); err == nil {
+				level = parsed
+			}
+			break
+		}
+	}
+
+	return NewWithWriter(os.Stderr, level).With("logger", name)
+}
+
+// NewWithWriter returns a logger that writes to w at the given level
+func NewWithWriter(w io.Writer, level Level) *Logger {
+	return &Logger{
+		out:    &output{w: w, now: time.Now},
+		level:  level,
+		fields: map[string]interface{}{},
+	}
+}
+
+// With returns a logger that adds a field to every entry
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make(map[string]interface{}, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+
+	return &Logger{out: l.out, level: l.level, fields: fields}
+}
+
+// ForTransaction returns a logger that adds the transaction ID, channel,
+// function name and client MSP of the current transaction to every entry
+func (l *Logger) ForTransaction(ctx TransactionContext) *Logger {
+	logger := l
+	if stub := ctx.GetStub(); stub != nil {
+		function, _ := stub.GetFunctionAndParameters()
+		logger = logger.
+			With("txId", stub.GetTxID()).
+			With("channel", stub.GetChannelID()).
+			With("function", function)
+	}
+
+	if identity := ctx.GetClientIdentity(); identity != nil {
+		if mspID, err := identity.GetMSPID(); err == nil {
+			logger = logger.With("clientMsp", mspID)
+		}
+	}
+
+	return logger
+}
+
+// Enabled reports whether entries at the given level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debugf logs at debug level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(DebugLevel, format, args...)
+}
+
+// Infof logs at info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(InfoLevel, format, args...)
+}
+
+// Warningf logs at warning level
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.log(WarningLevel, format, args...)
+}
+
+// Errorf logs at error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(ErrorLevel, format, args...)
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := make(map[string]interface{}, len(l.fields)+3)
+	for k, v := range l.fields {
+		entry[k] = v
+	}
+	entry["level"] = level.String()
+	entry["message"] = fmt.Sprintf(format, args...)
+
+	l.out.mutex.Lock()
+	defer l.out.mutex.Unlock()
+
+	entry["time"] = l.out.now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(map[string]string{
+			"level":   ErrorLevel.String(),
+			"message": fmt.Sprintf("failed to encode log entry: %v", err),
+		})
+	}
+	_, _ = l.out.w.Write(append(line, '\n'))
+}
@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+}
+
+func (fakeStub) GetTxID() string      { return "tx1" }
+func (fakeStub) GetChannelID() string { return "mychannel" }
+func (fakeStub) GetFunctionAndParameters() (string, []string) {
+	return "TransferAsset", []string{"asset1"}
+}
+
+type fakeIdentity struct {
+	cid.ClientIdentity
+}
+
+func (fakeIdentity) GetMSPID() (string, error) { return "Org1MSP", nil }
+
+type fakeContext struct {
+	identity cid.ClientIdentity
+}
+
+func (fakeContext) GetStub() shim.ChaincodeStubInterface { return fakeStub{} }
+func (c fakeContext) GetClientIdentity() cid.ClientIdentity {
+	return c.identity
+}
+
+func entries(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		require.NotEmpty(t, entry["time"])
+		delete(entry, "time")
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := logging.NewWithWriter(&buffer, logging.InfoLevel).With("logger", "basic")
+
+	logger.Debugf("not written")
+	logger.Infof("asset %s created", "asset1")
+	logger.ForTransaction(fakeContext{identity: fakeIdentity{}}).Warningf("balance of %d is low", 5)
+	logger.ForTransaction(fakeContext{}).Errorf("failed")
+
+	require.Equal(t, []map[string]interface{}{
+		{"level": "INFO", "logger": "basic", "message": "asset asset1 created"},
+		{
+			"level":     "WARNING",
+			"logger":    "basic",
+			"message":   "balance of 5 is low",
+			"txId":      "tx1",
+			"channel":   "mychannel",
+			"function":  "TransferAsset",
+			"clientMsp": "Org1MSP",
+		},
+		{
+			"level":    "ERROR",
+			"logger":   "basic",
+			"message":  "failed",
+			"txId":     "tx1",
+			"channel":  "mychannel",
+			"function": "TransferAsset",
+		},
+	}, entries(t, &buffer))
+}
+
+func setenv(t *testing.T, name string, value string) {
+	previous, ok := os.LookupEnv(name)
+	require.NoError(t, os.Setenv(name, value))
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func TestNewReadsLevelFromEnvironment(t *testing.T) {
+	setenv(t, logging.LevelEnv, "")
+	setenv(t, "CORE_CHAINCODE_LOGGING_LEVEL", "")
+	require.False(t, logging.New("basic").Enabled(logging.DebugLevel))
+	require.True(t, logging.New("basic").Enabled(logging.InfoLevel))
+
+	setenv(t, "CORE_CHAINCODE_LOGGING_LEVEL", "debug")
+	require.True(t, logging.New("basic").Enabled(logging.DebugLevel))
+
+	setenv(t, logging.LevelEnv, "error")
+	require.False(t, logging.New("basic").Enabled(logging.WarningLevel))
+	require.True(t, logging.New("basic").Enabled(logging.ErrorLevel))
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]logging.Level{
+		"debug":   logging.DebugLevel,
+		"INFO":    logging.InfoLevel,
+		"warn":    logging.WarningLevel,
+		"Warning": logging.WarningLevel,
+		" error ": logging.ErrorLevel,
+	} {
+		level, err := logging.ParseLevel(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, level)
+	}
+
+	_, err := logging.ParseLevel("verbose")
+	require.EqualError(t, err, `unknown log level "verbose", expected one of debug, info, warning or error`)
+}
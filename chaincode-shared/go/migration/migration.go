@@ -0,0 +1,313 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package migration upgrades the JSON objects a chaincode keeps in the world
+// state when their format changes. Each object carries the version of its
+// format, and a Type lists the steps that upgrade an object from one version
+// to the next. Objects are upgraded in memory when they are read, and written
+// back in their current format by MigrateBatch.
+package migration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// VersionField is the JSON field holding the format version of an object.
+// Objects without it, including values that are not JSON objects, are at
+// version zero.
+const VersionField = "dataVersion"
+
+// Step upgrades the JSON value of an object by one version. It does not need
+// to set VersionField, which is set after the step returns.
+type Step func(value []byte) ([]byte, error)
+
+// Type is a kind of object in the world state and the steps that upgrade it
+type Type struct {
+	// Name identifies the type in bookmarks, errors and status reports
+	Name string
+	// ObjectType is the composite key object type of the objects, or empty if
+	// they are stored under simple keys
+	ObjectType string
+	// Match optionally excludes keys that do not hold objects of the type,
+	// such as other simple keys in the namespace
+	Match func(key string) bool
+	// Steps upgrade the objects, where Steps[i] upgrades version i to i+1
+	Steps []Step
+}
+
+// Version returns the current format version of the type
+func (t *Type) Version() int {
+	return len(t.Steps)
+}
+
+// VersionOf returns the format version of a stored value
+func VersionOf(value []byte) (int, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+		return 0, nil
+	}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(value, &fields)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal object: %v", err)
+	}
+	versionJSON, ok := fields[VersionField]
+	if !ok {
+		return 0, nil
+	}
+
+	var version int
+	err = json.Unmarshal(versionJSON, &version)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s: %v", VersionField, versionJSON, err)
+	}
+	return version, nil
+}
+
+// Upgrade returns a value in the current format, and whether it had to be
+// upgraded
+func (t *Type) Upgrade(value []byte) ([]byte, bool, error) {
+	version, err := VersionOf(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the version of the %s: %v", t.Name, err)
+	}
+	if version > t.Version() {
+		return nil, false, fmt.Errorf("the %s has version %d, which is newer than version %d of this chaincode", t.Name, version, t.Version())
+	}
+	if version == t.Version() {
+		return value, false, nil
+	}
+
+	for ; version < t.Version(); version++ {
+		value, err = t.Steps[version](value)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to migrate the %s from version %d to %d: %v", t.Name, version, version+1, err)
+		}
+		value, err = setVersion(value, version+1)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to migrate the %s from version %d to %d: %v", t.Name, version, version+1, err)
+		}
+	}
+	return value, true, nil
+}
+
+// Get reads an object and returns it in the current format without writing it
+// back, or nil if it does not exist
+func (t *Type) Get(stub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+	value, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	value, _, err = t.Upgrade(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key, err)
+	}
+	return value, nil
+}
+
+// setVersion sets VersionField of a JSON object
+func setVersion(value []byte, version int) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(value, &fields)
+	if err != nil || fields == nil {
+		return nil, fmt.Errorf("the upgraded value must be a JSON object: %s", value)
+	}
+	fields[VersionField] = json.RawMessage(strconv.Itoa(version))
+	return json.Marshal(fields)
+}
+
+// iterate calls fn for each object of the type with a key from startKey on,
+// until fn returns false
+func (t *Type) iterate(stub shim.ChaincodeStubInterface, startKey string, fn func(key string, value []byte) (bool, error)) error {
+	var iterator shim.StateQueryIteratorInterface
+	var err error
+	if t.ObjectType == "" {
+		iterator, err = stub.GetStateByRange(startKey, "")
+	} else {
+		// composite keys cannot start a range query, so earlier keys are skipped
+		iterator, err = stub.GetStateByPartialCompositeKey(t.ObjectType, []string{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s objects: %v", t.Name, err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		response, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if response.Key < startKey || (t.Match != nil && !t.Match(response.Key)) {
+			continue
+		}
+
+		more, err := fn(response.Key, response.Value)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registry holds the types of the objects a chaincode migrates
+type Registry struct {
+	types []*Type
+}
+
+// NewRegistry returns a registry that migrates types in the given order
+func NewRegistry(types ...*Type) *Registry {
+	return &Registry{types: types}
+}
+
+// BatchResult is the progress of MigrateBatch
+type BatchResult struct {
+	// Scanned counts the objects read by the batch
+	Scanned int `json:"scanned"`
+	// Migrated counts the objects that were upgraded and written back
+	Migrated int `json:"migrated"`
+	// Bookmark is passed to the next batch, and is empty when every type has
+	// been migrated
+	Bookmark string `json:"bookmark"`
+	Done     bool   `json:"done"`
+}
+
+// bookmark is the position of a migration, which clients treat as opaque
+type bookmark struct {
+	Type string `json:"type"`
+	Key  string `json:"key"`
+}
+
+// MigrateBatch reads up to limit objects from the bookmark on, and writes back
+// those that are not in the current format. It starts with the first type
+// when the bookmark is empty.
+func (r *Registry) MigrateBatch(stub shim.ChaincodeStubInterface, bookmarkJSON string, limit int) (*BatchResult, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("the batch size must be greater than zero, got %d", limit)
+	}
+
+	index, startKey, err := r.parseBookmark(bookmarkJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{}
+	for ; index < len(r.types); index++ {
+		t := r.types[index]
+		next := ""
+		err := t.iterate(stub, startKey, func(key string, value []byte) (bool, error) {
+			if result.Scanned == limit {
+				next = key
+				return false, nil
+			}
+			result.Scanned++
+
+			upgraded, changed, err := t.Upgrade(value)
+			if err != nil {
+				return false, fmt.Errorf("failed to migrate %s: %v", key, err)
+			}
+			if !changed {
+				return true, nil
+			}
+			err = stub.PutState(key, upgraded)
+			if err != nil {
+				return false, fmt.Errorf("failed to put to world state: %v", err)
+			}
+			result.Migrated++
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if next != "" {
+			position, err := json.Marshal(bookmark{Type: t.Name, Key: next})
+			if err != nil {
+				return nil, err
+			}
+			result.Bookmark = string(position)
+			return result, nil
+		}
+		startKey = ""
+	}
+
+	result.Done = true
+	return result, nil
+}
+
+func (r *Registry) parseBookmark(bookmarkJSON string) (int, string, error) {
+	if bookmarkJSON == "" {
+		return 0, "", nil
+	}
+
+	var position bookmark
+	err := json.Unmarshal([]byte(bookmarkJSON), &position)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid bookmark %q: %v", bookmarkJSON, err)
+	}
+	for i, t := range r.types {
+		if t.Name == position.Type {
+			return i, position.Key, nil
+		}
+	}
+	return 0, "", fmt.Errorf("invalid bookmark %q: unknown type %s", bookmarkJSON, position.Type)
+}
+
+// Status is the migration progress of a type
+type Status struct {
+	Type           string `json:"type"`
+	CurrentVersion int    `json:"currentVersion"`
+	Total          int    `json:"total"`
+	// Pending counts the objects that are not in the current format
+	Pending  int            `json:"pending"`
+	Versions []VersionCount `json:"versions"`
+}
+
+// VersionCount is the number of objects stored with a version
+type VersionCount struct {
+	Version int `json:"version"`
+	Count   int `json:"count"`
+}
+
+// Status counts the objects of each type by version
+func (r *Registry) Status(stub shim.ChaincodeStubInterface) ([]*Status, error) {
+	var statuses []*Status
+	for _, t := range r.types {
+		counts := make(map[int]int)
+		status := &Status{Type: t.Name, CurrentVersion: t.Version(), Versions: []VersionCount{}}
+		err := t.iterate(stub, "", func(key string, value []byte) (bool, error) {
+			version, err := VersionOf(value)
+			if err != nil {
+				return false, fmt.Errorf("failed to read the version of %s: %v", key, err)
+			}
+			counts[version]++
+			status.Total++
+			if version < t.Version() {
+				status.Pending++
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for version, count := range counts {
+			status.Versions = append(status.Versions, VersionCount{Version: version, Count: count})
+		}
+		sort.Slice(status.Versions, func(i, j int) bool {
+			return status.Versions[i].Version < status.Versions[j].Version
+		})
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
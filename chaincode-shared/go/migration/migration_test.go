@@ -0,0 +1,183 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/migration"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+// balances were stored as plain integers, then as objects, then with a
+// currency
+var balanceType = &migration.Type{
+	Name:  "balance",
+	Match: func(key string) bool { return key != "totalSupply" },
+	Steps: []migration.Step{
+		func(value []byte) ([]byte, error) {
+			balance, err := strconv.Atoi(string(value))
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(map[string]int{"balance": balance})
+		},
+		func(value []byte) ([]byte, error) {
+			var fields map[string]interface{}
+			if err := json.Unmarshal(value, &fields); err != nil {
+				return nil, err
+			}
+			fields["currency"] = "EUR"
+			return json.Marshal(fields)
+		},
+	},
+}
+
+var noteType = &migration.Type{
+	Name:       "note",
+	ObjectType: "note",
+	Steps: []migration.Step{
+		func(value []byte) ([]byte, error) {
+			return value, nil
+		},
+	},
+}
+
+func TestUpgrade(t *testing.T) {
+	upgraded, changed, err := balanceType.Upgrade([]byte("100"))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.JSONEq(t, `{"balance": 100, "currency": "EUR", "dataVersion": 2}`, string(upgraded))
+
+	upgraded, changed, err = balanceType.Upgrade([]byte(`{"balance": 5, "dataVersion": 1}`))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.JSONEq(t, `{"balance": 5, "currency": "EUR", "dataVersion": 2}`, string(upgraded))
+
+	current := []byte(`{"balance": 5, "currency": "USD", "dataVersion": 2}`)
+	upgraded, changed, err = balanceType.Upgrade(current)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, current, upgraded)
+
+	_, _, err = balanceType.Upgrade([]byte(`{"dataVersion": 3}`))
+	require.EqualError(t, err, "the balance has version 3, which is newer than version 2 of this chaincode")
+
+	_, _, err = balanceType.Upgrade([]byte("many"))
+	require.EqualError(t, err, `failed to migrate the balance from version 0 to 1: strconv.Atoi: parsing "many": invalid syntax`)
+
+	broken := &migration.Type{Name: "broken", Steps: []migration.Step{
+		func(value []byte) ([]byte, error) { return []byte("[]"), nil },
+	}}
+	_, _, err = broken.Upgrade([]byte("{}"))
+	require.EqualError(t, err, "failed to migrate the broken from version 0 to 1: the upgraded value must be a JSON object: []")
+}
+
+func TestVersionOf(t *testing.T) {
+	for value, expected := range map[string]int{
+		`100`:                 0,
+		`"text"`:              0,
+		`{"balance": 1}`:      0,
+		`{"dataVersion": 4}`:  4,
+		` {"dataVersion": 1}`: 1,
+	} {
+		version, err := migration.VersionOf([]byte(value))
+		require.NoError(t, err, value)
+		require.Equal(t, expected, version, value)
+	}
+
+	_, err := migration.VersionOf([]byte(`{"dataVersion": "one"}`))
+	require.Error(t, err)
+}
+
+func TestGet(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.PutState("alice", []byte("10")))
+	stub.Commit()
+
+	value, err := balanceType.Get(stub, "alice")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"balance": 10, "currency": "EUR", "dataVersion": 2}`, string(value))
+	require.Empty(t, stub.Endorse().Writes(), "reads do not write the upgraded value back")
+
+	value, err = balanceType.Get(stub, "bob")
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestMigrateBatch(t *testing.T) {
+	stub := stubtest.New()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, stub.PutState(fmt.Sprintf("account%d", i), []byte(strconv.Itoa(i))))
+	}
+	require.NoError(t, stub.PutState("account5", []byte(`{"balance": 5, "currency": "USD", "dataVersion": 2}`)))
+	require.NoError(t, stub.PutState("totalSupply", []byte("15")))
+	for _, id := range []string{"n1", "n2"} {
+		key, err := stub.CreateCompositeKey("note", []string{id})
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState(key, []byte(`{"text": "hello"}`)))
+	}
+	stub.Commit()
+
+	registry := migration.NewRegistry(balanceType, noteType)
+
+	statuses, err := registry.Status(stub)
+	require.NoError(t, err)
+	require.Equal(t, []*migration.Status{
+		{Type: "balance", CurrentVersion: 2, Total: 6, Pending: 5, Versions: []migration.VersionCount{{Version: 0, Count: 5}, {Version: 2, Count: 1}}},
+		{Type: "note", CurrentVersion: 1, Total: 2, Pending: 2, Versions: []migration.VersionCount{{Version: 0, Count: 2}}},
+	}, statuses)
+	stub.Rollback()
+
+	var results []*migration.BatchResult
+	bookmark := ""
+	for {
+		result, err := registry.MigrateBatch(stub, bookmark, 4)
+		require.NoError(t, err)
+		stub.Commit()
+		results = append(results, result)
+		if result.Done {
+			break
+		}
+		bookmark = result.Bookmark
+	}
+	require.Equal(t, []*migration.BatchResult{
+		{Scanned: 4, Migrated: 4, Bookmark: `{"type":"balance","key":"account4"}`},
+		{Scanned: 4, Migrated: 3, Done: true},
+	}, results)
+
+	statuses, err = registry.Status(stub)
+	require.NoError(t, err)
+	require.Zero(t, statuses[0].Pending)
+	require.Zero(t, statuses[1].Pending)
+
+	value, err := stub.GetState("totalSupply")
+	require.NoError(t, err)
+	require.Equal(t, []byte("15"), value, "keys that do not match are not migrated")
+	value, err = stub.GetState("account5")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"balance": 5, "currency": "USD", "dataVersion": 2}`, string(value))
+}
+
+func TestMigrateBatchErrors(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.PutState("alice", []byte("lots")))
+	stub.Commit()
+	registry := migration.NewRegistry(balanceType)
+
+	_, err := registry.MigrateBatch(stub, "", 0)
+	require.EqualError(t, err, "the batch size must be greater than zero, got 0")
+	_, err = registry.MigrateBatch(stub, "account1", 10)
+	require.Error(t, err)
+	_, err = registry.MigrateBatch(stub, `{"type":"asset","key":"a"}`, 10)
+	require.EqualError(t, err, `invalid bookmark "{\"type\":\"asset\",\"key\":\"a\"}": unknown type asset`)
+
+	_, err = registry.MigrateBatch(stub, "", 10)
+	require.EqualError(t, err, `failed to migrate alice: failed to migrate the balance from version 0 to 1: strconv.Atoi: parsing "lots": invalid syntax`)
+}
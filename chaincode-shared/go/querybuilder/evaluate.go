@@ -0,0 +1,162 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package querybuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Evaluate runs the query against documents read without a rich query, such
+// as through a secondary index on a state database that does not support
+// rich queries. It returns the documents that match, sorted and limited as
+// the state database would. Documents that are not JSON objects never match.
+func (q *Query) Evaluate(documents [][]byte) ([][]byte, error) {
+	type match struct {
+		document []byte
+		fields   map[string]interface{}
+	}
+
+	var matches []match
+	for _, document := range documents {
+		fields := make(map[string]interface{})
+		decoder := json.NewDecoder(bytes.NewReader(document))
+		decoder.UseNumber()
+		if err := decoder.Decode(&fields); err != nil {
+			continue
+		}
+
+		ok, err := q.matches(fields)
+		if err != nil {
+			return nil, err
+		}
+		// like CouchDB, documents without the sort fields are left out
+		for _, sortField := range q.sort {
+			for name := range sortField {
+				_, exists := fields[name]
+				ok = ok && exists
+			}
+		}
+		if ok {
+			matches = append(matches, match{document: document, fields: fields})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, sortField := range q.sort {
+			for name, direction := range sortField {
+				c, _ := compare(matches[i].fields[name], matches[j].fields[name])
+				if c != 0 {
+					return (c < 0) != (direction == "desc")
+				}
+			}
+		}
+		return false
+	})
+
+	var results [][]byte
+	for _, m := range matches {
+		if len(results) == q.Limit {
+			break
+		}
+		results = append(results, m.document)
+	}
+	return results, nil
+}
+
+// matches checks a document against every condition of the selector
+func (q *Query) matches(fields map[string]interface{}) (bool, error) {
+	for _, condition := range q.conditions {
+		for name, test := range condition {
+			value, exists := fields[name]
+			operators, ok := test.(map[string]interface{})
+			if !ok {
+				operators = map[string]interface{}{"$eq": test}
+			}
+			for operator, argument := range operators {
+				ok, err := matchOperator(value, exists, operator, argument)
+				if err != nil || !ok {
+					return false, err
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(value interface{}, exists bool, operator string, argument interface{}) (bool, error) {
+	if operator == "$exists" {
+		return exists == (argument == true), nil
+	}
+	if !exists {
+		return false, nil
+	}
+
+	switch operator {
+	case "$in", "$nin":
+		found := false
+		for _, element := range argument.([]interface{}) {
+			if c, ok := compare(value, element); ok && c == 0 {
+				found = true
+			}
+		}
+		return found == (operator == "$in"), nil
+	case "$ne":
+		c, ok := compare(value, argument)
+		return !ok || c != 0, nil
+	}
+
+	c, ok := compare(value, argument)
+	if !ok {
+		return false, nil
+	}
+	switch operator {
+	case "$eq":
+		return c == 0, nil
+	case "$gt":
+		return c > 0, nil
+	case "$gte":
+		return c >= 0, nil
+	case "$lt":
+		return c < 0, nil
+	case "$lte":
+		return c <= 0, nil
+	default:
+		return false, fmt.Errorf("unsupported operator %s", operator)
+	}
+}
+
+// compare orders two strings or two numbers, and reports whether they could
+// be compared
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return strings.Compare(a, b), ok
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		if errA != nil || errB != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	default:
+		return 0, false
+	}
+}
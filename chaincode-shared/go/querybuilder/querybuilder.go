@@ -0,0 +1,235 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package querybuilder compiles query criteria sent by clients into CouchDB
+// queries. Unlike a query string passed to the state database as is, the
+// criteria can only filter and sort on the fields a contract allows, and the
+// compiled query always includes the contract's constraint, such as the
+// document type of its assets.
+package querybuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+)
+
+// FieldType is the JSON type of a field
+type FieldType string
+
+// Types of the fields that can be queried
+const (
+	String FieldType = "string"
+	Number FieldType = "number"
+)
+
+// Field is a field that criteria may filter on
+type Field struct {
+	Type FieldType
+	// Sortable fields may be sorted on, which CouchDB only allows if an index
+	// includes the field
+	Sortable bool
+}
+
+// Schema is what criteria may query
+type Schema struct {
+	// Constraint is a selector every query is restricted to
+	Constraint map[string]interface{}
+	// Fields are the fields that can be queried, by JSON name
+	Fields map[string]Field
+	// MaxLimit is the most results a query may return, and the limit of
+	// criteria that do not set one
+	MaxLimit int
+}
+
+// Filter compares a field with a value. Operator is one of eq, ne, gt, gte,
+// lt, lte, in and nin, where in and nin take a list of values.
+type Filter struct {
+	Field    string          `json:"field"`
+	Operator string          `json:"operator"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Sort orders results by a field. Direction is asc, the default, or desc.
+type Sort struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction"`
+}
+
+// Criteria select the documents that match every filter
+type Criteria struct {
+	Filters []Filter `json:"filters"`
+	Sort    []Sort   `json:"sort"`
+	Limit   int      `json:"limit"`
+}
+
+// Query is compiled criteria
+type Query struct {
+	// QueryString is the CouchDB query
+	QueryString string
+	// Limit is the most results to read, which the peer may not enforce for
+	// queries without pagination
+	Limit int
+	// Filters are the validated filters, which a contract can use to choose a
+	// secondary index for Evaluate
+	Filters []Filter
+
+	conditions []map[string]interface{}
+	sort       []map[string]string
+}
+
+var operators = map[string]string{
+	"eq":  "$eq",
+	"ne":  "$ne",
+	"gt":  "$gt",
+	"gte": "$gte",
+	"lt":  "$lt",
+	"lte": "$lte",
+	"in":  "$in",
+	"nin": "$nin",
+}
+
+// Compile validates criteria JSON against the schema and returns the query.
+// An empty string selects every document up to the maximum limit.
+func (s *Schema) Compile(criteriaJSON string) (*Query, error) {
+	criteria := &Criteria{}
+	if strings.TrimSpace(criteriaJSON) != "" {
+		decoder := json.NewDecoder(strings.NewReader(criteriaJSON))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(criteria); err != nil {
+			return nil, errorcode.New(errorcode.Validation, "invalid criteria: %v", err)
+		}
+	}
+
+	conditions := []interface{}{s.Constraint}
+	for _, filter := range criteria.Filters {
+		condition, err := s.compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	sort, err := s.compileSort(criteria.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := criteria.Limit
+	if limit == 0 {
+		limit = s.MaxLimit
+	}
+	if limit < 0 || limit > s.MaxLimit {
+		return nil, errorcode.New(errorcode.Validation, "the limit must be between 1 and %d, got %d", s.MaxLimit, criteria.Limit)
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{"$and": conditions},
+		"limit":    limit,
+	}
+	if len(sort) > 0 {
+		query["sort"] = sort
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	// the conditions are decoded from the query, so that Evaluate compares the
+	// same JSON values the state database would
+	var compiled struct {
+		Selector struct {
+			And []map[string]interface{} `json:"$and"`
+		} `json:"selector"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(queryJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&compiled); err != nil {
+		return nil, fmt.Errorf("failed to decode query: %v", err)
+	}
+
+	return &Query{QueryString: string(queryJSON), Limit: limit, Filters: criteria.Filters, conditions: compiled.Selector.And, sort: sort}, nil
+}
+
+func (s *Schema) compileFilter(filter Filter) (map[string]interface{}, error) {
+	field, ok := s.Fields[filter.Field]
+	if !ok {
+		return nil, errorcode.New(errorcode.Validation, "the field %q cannot be queried", filter.Field)
+	}
+	operator, ok := operators[filter.Operator]
+	if !ok {
+		return nil, errorcode.New(errorcode.Validation, "the field %s cannot be compared with the operator %q", filter.Field, filter.Operator)
+	}
+
+	var value interface{}
+	if operator == "$in" || operator == "$nin" {
+		var values []json.RawMessage
+		if err := json.Unmarshal(filter.Value, &values); err != nil || len(values) == 0 {
+			return nil, errorcode.New(errorcode.Validation, "the field %s must be compared with a non-empty list of %ss, got %s", filter.Field, field.Type, filter.Value)
+		}
+		list := make([]interface{}, 0, len(values))
+		for _, element := range values {
+			decoded, ok := decodeValue(element, field.Type)
+			if !ok {
+				return nil, errorcode.New(errorcode.Validation, "the field %s must be compared with a list of %ss, got %s", filter.Field, field.Type, filter.Value)
+			}
+			list = append(list, decoded)
+		}
+		value = list
+	} else {
+		decoded, ok := decodeValue(filter.Value, field.Type)
+		if !ok {
+			return nil, errorcode.New(errorcode.Validation, "the field %s must be compared with a %s, got %s", filter.Field, field.Type, filter.Value)
+		}
+		value = decoded
+	}
+
+	return map[string]interface{}{filter.Field: map[string]interface{}{operator: value}}, nil
+}
+
+// decodeValue decodes a JSON value of the given type
+func decodeValue(value json.RawMessage, fieldType FieldType) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	switch decoded.(type) {
+	case string:
+		return decoded, fieldType == String
+	case json.Number:
+		return decoded, fieldType == Number
+	default:
+		return nil, false
+	}
+}
+
+func (s *Schema) compileSort(sorts []Sort) ([]map[string]string, error) {
+	var compiled []map[string]string
+	for i, sort := range sorts {
+		if field, ok := s.Fields[sort.Field]; !ok || !field.Sortable {
+			return nil, errorcode.New(errorcode.Validation, "the field %q cannot be sorted on", sort.Field)
+		}
+
+		direction := sort.Direction
+		if direction == "" {
+			direction = "asc"
+		}
+		if direction != "asc" && direction != "desc" {
+			return nil, errorcode.New(errorcode.Validation, "the field %s must be sorted asc or desc, got %q", sort.Field, sort.Direction)
+		}
+		// CouchDB can only sort on several fields in the same direction
+		if i > 0 && direction != compiled[0][sorts[0].Field] {
+			return nil, errorcode.New(errorcode.Validation, "the field %s must be sorted in the same direction as %s", sort.Field, sorts[0].Field)
+		}
+
+		compiled = append(compiled, map[string]string{sort.Field: direction})
+	}
+	return compiled, nil
+}
@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package querybuilder_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/querybuilder"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+var schema = &querybuilder.Schema{
+	Constraint: map[string]interface{}{"docType": "asset"},
+	Fields: map[string]querybuilder.Field{
+		"owner": {Type: querybuilder.String},
+		"color": {Type: querybuilder.String},
+		"size":  {Type: querybuilder.Number, Sortable: true},
+	},
+	MaxLimit: 10,
+}
+
+func TestCompile(t *testing.T) {
+	query, err := schema.Compile(`{
+		"filters": [
+			{"field": "owner", "operator": "eq", "value": "tom"},
+			{"field": "size", "operator": "gte", "value": 5},
+			{"field": "color", "operator": "in", "value": ["blue", "red"]}
+		],
+		"sort": [{"field": "size", "direction": "desc"}],
+		"limit": 3
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, 3, query.Limit)
+	require.JSONEq(t, `{
+		"selector": {"$and": [
+			{"docType": "asset"},
+			{"owner": {"$eq": "tom"}},
+			{"size": {"$gte": 5}},
+			{"color": {"$in": ["blue", "red"]}}
+		]},
+		"sort": [{"size": "desc"}],
+		"limit": 3
+	}`, query.QueryString)
+
+	query, err = schema.Compile("")
+	require.NoError(t, err)
+	require.Equal(t, 10, query.Limit)
+	require.JSONEq(t, `{"selector": {"$and": [{"docType": "asset"}]}, "limit": 10}`, query.QueryString)
+}
+
+func TestCompileErrors(t *testing.T) {
+	for criteria, message := range map[string]string{
+		`{"selector": {"docType": "note"}}`:                                         `invalid criteria: json: unknown field "selector"`,
+		`{"filters": [{"field": "docType", "operator": "eq", "value": "note"}]}`:    `the field "docType" cannot be queried`,
+		`{"filters": [{"field": "owner", "operator": "regex", "value": "t.*"}]}`:    `the field owner cannot be compared with the operator "regex"`,
+		`{"filters": [{"field": "size", "operator": "gt", "value": "5"}]}`:          `the field size must be compared with a number, got "5"`,
+		`{"filters": [{"field": "owner", "operator": "eq"}]}`:                       `the field owner must be compared with a string, got null`,
+		`{"filters": [{"field": "owner", "operator": "eq", "value": {"$gt": ""}}]}`: `the field owner must be compared with a string, got {"$gt": ""}`,
+		`{"filters": [{"field": "color", "operator": "nin", "value": []}]}`:         `the field color must be compared with a non-empty list of strings, got []`,
+		`{"filters": [{"field": "color", "operator": "in", "value": ["red", 1]}]}`:  `the field color must be compared with a list of strings, got ["red", 1]`,
+		`{"sort": [{"field": "owner"}]}`:                                            `the field "owner" cannot be sorted on`,
+		`{"sort": [{"field": "size", "direction": "up"}]}`:                          `the field size must be sorted asc or desc, got "up"`,
+		`{"limit": 11}`: `the limit must be between 1 and 10, got 11`,
+	} {
+		_, err := schema.Compile(criteria)
+		coded, ok := errorcode.FromError(err)
+		require.True(t, ok, criteria)
+		require.Equal(t, errorcode.Validation, coded.Code, criteria)
+		require.Equal(t, message, coded.Message, criteria)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	stub := stubtest.New()
+	stub.EnableRichQueries()
+	require.NoError(t, stub.PutState("asset1", []byte(`{"docType": "asset", "owner": "tom", "size": 3}`)))
+	require.NoError(t, stub.PutState("asset2", []byte(`{"docType": "asset", "owner": "tom", "size": 8}`)))
+	require.NoError(t, stub.PutState("asset3", []byte(`{"docType": "asset", "owner": "jerry", "size": 5}`)))
+	require.NoError(t, stub.PutState("note1", []byte(`{"docType": "note", "owner": "tom", "size": 9}`)))
+	stub.Commit()
+
+	query, err := schema.Compile(`{"filters": [{"field": "owner", "operator": "eq", "value": "tom"}], "sort": [{"field": "size", "direction": "desc"}]}`)
+	require.NoError(t, err)
+	iterator, err := stub.GetQueryResult(query.QueryString)
+	require.NoError(t, err)
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		require.NoError(t, err)
+		keys = append(keys, result.Key)
+	}
+	require.Equal(t, []string{"asset2", "asset1"}, keys, "documents of other types are not matched")
+}
+
+func TestEvaluate(t *testing.T) {
+	documents := [][]byte{
+		[]byte(`{"docType": "asset", "owner": "tom", "color": "blue", "size": 3}`),
+		[]byte(`{"docType": "asset", "owner": "tom", "color": "red", "size": 8}`),
+		[]byte(`{"docType": "asset", "owner": "tom", "color": "blue"}`),
+		[]byte(`{"docType": "asset", "owner": "jerry", "color": "blue", "size": 5}`),
+		[]byte(`{"docType": "note", "owner": "tom", "size": 9}`),
+		[]byte(`not json`),
+	}
+
+	query, err := schema.Compile(`{"filters": [{"field": "owner", "operator": "eq", "value": "tom"}], "sort": [{"field": "size", "direction": "desc"}]}`)
+	require.NoError(t, err)
+	results, err := query.Evaluate(documents)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{documents[1], documents[0]}, results, "documents without the sort field are left out")
+
+	query, err = schema.Compile(`{"filters": [{"field": "color", "operator": "nin", "value": ["red"]}, {"field": "size", "operator": "lte", "value": 5}], "limit": 1}`)
+	require.NoError(t, err)
+	results, err = query.Evaluate(documents)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{documents[0]}, results)
+}
@@ -0,0 +1,114 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package secondaryindex keeps secondary indexes of world state objects as
+// composite keys, which every state database supports. An index entry is a
+// composite key made of the index name, the indexed attributes of an object
+// and the object's key, so that a partial composite key query on the
+// attributes finds the keys of the matching objects.
+package secondaryindex
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// entryValue is the value of index entries. Only the key is needed, and an
+// empty value would delete the key.
+var entryValue = []byte{0x00}
+
+// Index is a secondary index of the objects of a type
+type Index struct {
+	// Name is the object type of the index entries, such as "color~name"
+	Name string
+	// Attributes returns the indexed attributes of an object, or nil to leave
+	// the object out of the index
+	Attributes func(value []byte) ([]string, error)
+}
+
+// Indexes are the secondary indexes of a type of object
+type Indexes []*Index
+
+// Update changes the index entries of the object stored under key from those
+// of its old value to those of its new value. The old value is nil when the
+// object is created, and the new value is nil when it is deleted. Entries
+// that do not change are not written.
+func (indexes Indexes) Update(stub shim.ChaincodeStubInterface, key string, oldValue, newValue []byte) error {
+	for _, index := range indexes {
+		oldKey, err := index.entryKey(stub, key, oldValue)
+		if err != nil {
+			return err
+		}
+		newKey, err := index.entryKey(stub, key, newValue)
+		if err != nil {
+			return err
+		}
+		if oldKey == newKey {
+			continue
+		}
+
+		if oldKey != "" {
+			err = stub.DelState(oldKey)
+			if err != nil {
+				return fmt.Errorf("failed to delete %s index entry of %s: %v", index.Name, key, err)
+			}
+		}
+		if newKey != "" {
+			err = stub.PutState(newKey, entryValue)
+			if err != nil {
+				return fmt.Errorf("failed to put %s index entry of %s: %v", index.Name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// entryKey returns the index entry of an object, or an empty string if it
+// has none
+func (i *Index) entryKey(stub shim.ChaincodeStubInterface, key string, value []byte) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	attributes, err := i.Attributes(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s index attributes of %s: %v", i.Name, key, err)
+	}
+	if attributes == nil {
+		return "", nil
+	}
+
+	entryKey, err := stub.CreateCompositeKey(i.Name, append(attributes, key))
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s index entry of %s: %v", i.Name, key, err)
+	}
+	return entryKey, nil
+}
+
+// Keys returns the keys of the objects whose leading indexed attributes are
+// the given attributes, in index order
+func (i *Index) Keys(stub shim.ChaincodeStubInterface, attributes ...string) ([]string, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(i.Name, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s index: %v", i.Name, err)
+	}
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := stub.SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split %s index entry: %v", i.Name, err)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		keys = append(keys, parts[len(parts)-1])
+	}
+	return keys, nil
+}
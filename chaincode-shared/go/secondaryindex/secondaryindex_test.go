@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package secondaryindex_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/secondaryindex"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+type asset struct {
+	Owner string `json:"owner"`
+	Color string `json:"color,omitempty"`
+}
+
+func attribute(name func(asset) string) func([]byte) ([]string, error) {
+	return func(value []byte) ([]string, error) {
+		var a asset
+		if err := json.Unmarshal(value, &a); err != nil {
+			return nil, err
+		}
+		if name(a) == "" {
+			return nil, nil
+		}
+		return []string{name(a)}, nil
+	}
+}
+
+var (
+	ownerIndex = &secondaryindex.Index{Name: "owner~name", Attributes: attribute(func(a asset) string { return a.Owner })}
+	colorIndex = &secondaryindex.Index{Name: "color~name", Attributes: attribute(func(a asset) string { return a.Color })}
+	indexes    = secondaryindex.Indexes{ownerIndex, colorIndex}
+)
+
+func TestUpdate(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, indexes.Update(stub, "asset1", nil, []byte(`{"owner": "tom", "color": "blue"}`)))
+	require.NoError(t, indexes.Update(stub, "asset2", nil, []byte(`{"owner": "tom"}`)))
+	stub.Commit()
+
+	keys, err := ownerIndex.Keys(stub, "tom")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset2"}, keys)
+	keys, err = colorIndex.Keys(stub)
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1"}, keys, "objects without attributes are not indexed")
+
+	require.NoError(t, indexes.Update(stub, "asset1", []byte(`{"owner": "tom", "color": "blue"}`), []byte(`{"owner": "jerry", "color": "blue"}`)))
+	transaction := stub.Endorse()
+	require.Len(t, transaction.Writes(), 2, "unchanged entries are not written")
+	stub.CommitBlock(transaction)
+
+	keys, err = ownerIndex.Keys(stub, "tom")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset2"}, keys)
+	keys, err = ownerIndex.Keys(stub, "jerry")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1"}, keys)
+
+	require.NoError(t, indexes.Update(stub, "asset1", []byte(`{"owner": "jerry", "color": "blue"}`), nil))
+	stub.Commit()
+	keys, err = colorIndex.Keys(stub, "blue")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	err = indexes.Update(stub, "asset3", nil, []byte(`[]`))
+	require.EqualError(t, err, "failed to get owner~name index attributes of asset3: json: cannot unmarshal array into Go value of type secondaryindex_test.asset")
+}
@@ -0,0 +1,269 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package serverconfig loads the configuration of a chaincode that runs as an
+// external service, from the variables of chaincode.env. The variables can be
+// set in the environment or read from a file in the same KEY=VALUE format.
+package serverconfig
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Config is the address, package ID and TLS material of a chaincode server
+type Config struct {
+	CCID    string
+	Address string
+
+	// TLSDisabled defaults to true so that the samples run unchanged on the
+	// test network's Docker network
+	TLSDisabled bool
+	TLSKey      []byte
+	TLSCert     []byte
+	// TLSClientCACerts enables mutual TLS: the peer must present a client
+	// certificate issued by one of these CAs
+	TLSClientCACerts []byte
+	// TLSRequireClientCert refuses to start without client CA certificates
+	TLSRequireClientCert bool
+}
+
+// Load reads the configuration using getenv. The TLS key and certificates are
+// either given inline as PEM, or as the path of a PEM file in the variable of
+// the same name with a _FILE suffix. Load only fails on values it cannot read;
+// Validate checks that the configuration is usable.
+func Load(getenv func(string) string) (Config, error) {
+	config := Config{
+		CCID:        getenv("CHAINCODE_ID"),
+		Address:     getenv("CHAINCODE_SERVER_ADDRESS"),
+		TLSDisabled: true,
+	}
+
+	var err error
+	if config.TLSDisabled, err = envBool(getenv, "CHAINCODE_TLS_DISABLED", config.TLSDisabled); err != nil {
+		return config, err
+	}
+	if config.TLSRequireClientCert, err = envBool(getenv, "CHAINCODE_TLS_REQUIRE_CLIENT_CERT", false); err != nil {
+		return config, err
+	}
+	if config.TLSKey, err = envPEM(getenv, "CHAINCODE_TLS_KEY"); err != nil {
+		return config, err
+	}
+	if config.TLSCert, err = envPEM(getenv, "CHAINCODE_TLS_CERT"); err != nil {
+		return config, err
+	}
+	if config.TLSClientCACerts, err = envPEM(getenv, "CHAINCODE_TLS_CLIENT_CA_CERT"); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// Validate reports every problem with the configuration at once, so that the
+// server fails at startup rather than when the peer first connects
+func (c Config) Validate() error {
+	problems := c.Problems()
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Problems returns a description of each problem with the configuration, for
+// servers that check settings of their own as well
+func (c Config) Problems() []string {
+	var problems []string
+
+	if c.CCID == "" {
+		problems = append(problems, "CHAINCODE_ID must be set to the package ID of the installed chaincode")
+	}
+	if err := ValidateAddress(c.Address); err != nil {
+		problems = append(problems, fmt.Sprintf("CHAINCODE_SERVER_ADDRESS %v", err))
+	}
+	if err := c.validateTLS(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// ValidateAddress checks that address is a host:port a server can listen on
+func ValidateAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("must be set to the host:port the server listens on")
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%q is not a host:port address: %v", address, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q does not have a port between 1 and 65535", address)
+	}
+
+	return nil
+}
+
+// validateTLS checks the TLS material up front, so that a bad certificate is
+// reported when the server starts rather than when the peer first connects
+func (c Config) validateTLS() error {
+	if c.TLSDisabled {
+		return nil
+	}
+
+	if len(c.TLSKey) == 0 || len(c.TLSCert) == 0 {
+		return fmt.Errorf("CHAINCODE_TLS_KEY and CHAINCODE_TLS_CERT must be set when TLS is enabled")
+	}
+	if _, err := tls.X509KeyPair(c.TLSCert, c.TLSKey); err != nil {
+		return fmt.Errorf("failed to load the TLS key pair: %v", err)
+	}
+
+	if len(c.TLSClientCACerts) == 0 {
+		if c.TLSRequireClientCert {
+			return fmt.Errorf("CHAINCODE_TLS_REQUIRE_CLIENT_CERT is set but CHAINCODE_TLS_CLIENT_CA_CERT is not")
+		}
+		return nil
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(c.TLSClientCACerts) {
+		return fmt.Errorf("CHAINCODE_TLS_CLIENT_CA_CERT does not contain a PEM encoded certificate")
+	}
+
+	return nil
+}
+
+// TLSProperties returns the TLS properties of a shim.ChaincodeServer. The shim
+// requires and verifies a client certificate whenever client CA certificates
+// are set.
+func (c Config) TLSProperties() shim.TLSProperties {
+	if c.TLSDisabled {
+		return shim.TLSProperties{Disabled: true}
+	}
+
+	return shim.TLSProperties{
+		Key:           c.TLSKey,
+		Cert:          c.TLSCert,
+		ClientCACerts: c.TLSClientCACerts,
+	}
+}
+
+// TLSConfig returns the TLS configuration of a gRPC chaincode server, or nil
+// if TLS is disabled. It only offers forward secret cipher suites and, like
+// the shim, requires and verifies a client certificate whenever client CA
+// certificates are set.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	if c.TLSDisabled {
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair(c.TLSCert, c.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS key pair: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:             tls.VersionTLS12,
+		Certificates:           []tls.Certificate{cert},
+		SessionTicketsDisabled: true,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		},
+	}
+
+	if len(c.TLSClientCACerts) > 0 {
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(c.TLSClientCACerts) {
+			return nil, fmt.Errorf("CHAINCODE_TLS_CLIENT_CA_CERT does not contain a PEM encoded certificate")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// ReadFile reads variables from a file in the same KEY=VALUE format as
+// chaincode.env. Blank lines and lines starting with # are ignored.
+func ReadFile(path string) (map[string]string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %v", err)
+	}
+	defer file.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE, got %q", path, lineNumber, line)
+		}
+		vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %v", err)
+	}
+
+	return vars, nil
+}
+
+// WithFallback returns a getenv function that prefers the environment and
+// falls back to the variables read from a configuration file
+func WithFallback(lookupEnv func(string) (string, bool), vars map[string]string) func(string) string {
+	return func(name string) string {
+		if value, ok := lookupEnv(name); ok {
+			return value
+		}
+		return vars[name]
+	}
+}
+
+func envBool(getenv func(string) string, name string, defaultValue bool) (bool, error) {
+	value := getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", name, value)
+	}
+	return b, nil
+}
+
+func envPEM(getenv func(string) string, name string) ([]byte, error) {
+	value := getenv(name)
+	path := getenv(name + "_FILE")
+	if value != "" && path != "" {
+		return nil, fmt.Errorf("only one of %s and %s_FILE may be set", name, name)
+	}
+	if value != "" {
+		return []byte(value), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s_FILE: %v", name, err)
+	}
+	return contents, nil
+}
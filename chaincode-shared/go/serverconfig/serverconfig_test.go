@@ -0,0 +1,224 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package serverconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyPair(t *testing.T) (keyPEM []byte, certPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "chaincode.org1.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "serverconfig")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// env returns a getenv function for vars, defaulting the variables that must
+// always be set
+func env(vars map[string]string) func(string) string {
+	defaults := map[string]string{
+		"CHAINCODE_ID":             "basic_1.0:abc",
+		"CHAINCODE_SERVER_ADDRESS": "0.0.0.0:9999",
+	}
+	return func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return defaults[name]
+	}
+}
+
+// load loads and validates a configuration
+func load(getenv func(string) string) (serverconfig.Config, error) {
+	config, err := serverconfig.Load(getenv)
+	if err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+func TestLoad(t *testing.T) {
+	key, cert := newKeyPair(t)
+	_, clientCA := newKeyPair(t)
+
+	config, err := load(env(nil))
+	require.NoError(t, err)
+	require.Equal(t, "basic_1.0:abc", config.CCID)
+	require.Equal(t, "0.0.0.0:9999", config.Address)
+	require.True(t, config.TLSDisabled)
+	require.True(t, config.TLSProperties().Disabled)
+	tlsConfig, err := config.TLSConfig()
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+
+	config, err = load(env(map[string]string{
+		"CHAINCODE_TLS_DISABLED": "false",
+		"CHAINCODE_TLS_KEY":      string(key),
+		"CHAINCODE_TLS_CERT":     string(cert),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, key, config.TLSKey)
+	require.Equal(t, cert, config.TLSCert)
+	tlsConfig, err = config.TLSConfig()
+	require.NoError(t, err)
+	require.Len(t, tlsConfig.Certificates, 1)
+	require.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+	for _, suite := range tlsConfig.CipherSuites {
+		require.Contains(t, tls.CipherSuiteName(suite), "_ECDHE_", "cipher suites must be forward secret")
+	}
+
+	dir := tempDir(t)
+	keyFile := filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "client-ca.crt")
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+	require.NoError(t, ioutil.WriteFile(caFile, clientCA, 0600))
+
+	config, err = load(env(map[string]string{
+		"CHAINCODE_TLS_DISABLED":            "false",
+		"CHAINCODE_TLS_KEY_FILE":            keyFile,
+		"CHAINCODE_TLS_CERT":                string(cert),
+		"CHAINCODE_TLS_CLIENT_CA_CERT_FILE": caFile,
+		"CHAINCODE_TLS_REQUIRE_CLIENT_CERT": "true",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, key, config.TLSKey)
+	require.Equal(t, clientCA, config.TLSClientCACerts)
+	tlsConfig, err = config.TLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+	require.NotNil(t, tlsConfig.ClientCAs)
+
+	properties := config.TLSProperties()
+	require.False(t, properties.Disabled)
+	require.Equal(t, key, properties.Key)
+	require.Equal(t, cert, properties.Cert)
+	require.Equal(t, clientCA, properties.ClientCACerts)
+}
+
+func TestLoadErrors(t *testing.T) {
+	key, cert := newKeyPair(t)
+	otherKey, _ := newKeyPair(t)
+
+	tests := []struct {
+		vars map[string]string
+		err  string
+	}{
+		{
+			vars: map[string]string{"CHAINCODE_ID": "", "CHAINCODE_SERVER_ADDRESS": ""},
+			err:  "invalid configuration: CHAINCODE_ID must be set to the package ID of the installed chaincode; CHAINCODE_SERVER_ADDRESS must be set to the host:port the server listens on",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_SERVER_ADDRESS": "localhost"},
+			err:  `CHAINCODE_SERVER_ADDRESS "localhost" is not a host:port address`,
+		},
+		{
+			vars: map[string]string{"CHAINCODE_SERVER_ADDRESS": "localhost:99999"},
+			err:  `CHAINCODE_SERVER_ADDRESS "localhost:99999" does not have a port between 1 and 65535`,
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "no"},
+			err:  `CHAINCODE_TLS_DISABLED must be true or false, got "no"`,
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "false", "CHAINCODE_TLS_CERT": string(cert)},
+			err:  "CHAINCODE_TLS_KEY and CHAINCODE_TLS_CERT must be set when TLS is enabled",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_KEY": "inline", "CHAINCODE_TLS_KEY_FILE": "server.key"},
+			err:  "only one of CHAINCODE_TLS_KEY and CHAINCODE_TLS_KEY_FILE may be set",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_CERT_FILE": filepath.Join(tempDir(t), "missing.crt")},
+			err:  "failed to read CHAINCODE_TLS_CERT_FILE",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "false", "CHAINCODE_TLS_KEY": string(otherKey), "CHAINCODE_TLS_CERT": string(cert)},
+			err:  "failed to load the TLS key pair",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "false", "CHAINCODE_TLS_KEY": string(key), "CHAINCODE_TLS_CERT": string(cert), "CHAINCODE_TLS_REQUIRE_CLIENT_CERT": "true"},
+			err:  "CHAINCODE_TLS_REQUIRE_CLIENT_CERT is set but CHAINCODE_TLS_CLIENT_CA_CERT is not",
+		},
+		{
+			vars: map[string]string{"CHAINCODE_TLS_DISABLED": "false", "CHAINCODE_TLS_KEY": string(key), "CHAINCODE_TLS_CERT": string(cert), "CHAINCODE_TLS_CLIENT_CA_CERT": "not a certificate"},
+			err:  "CHAINCODE_TLS_CLIENT_CA_CERT does not contain a PEM encoded certificate",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := load(env(test.vars))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(tempDir(t), "chaincode.env")
+	contents := `
+# comments and blank lines are ignored
+CHAINCODE_SERVER_ADDRESS=asset-transfer-basic.org1.example.com:9999
+CHAINCODE_ID = basic_1.0:abc
+`
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+	vars, err := serverconfig.ReadFile(path)
+	require.NoError(t, err)
+
+	environment := map[string]string{"CHAINCODE_SERVER_ADDRESS": "0.0.0.0:7052"}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := environment[name]
+		return value, ok
+	}
+
+	config, err := load(serverconfig.WithFallback(lookupEnv, vars))
+	require.NoError(t, err)
+	require.Equal(t, "basic_1.0:abc", config.CCID)
+	require.Equal(t, "0.0.0.0:7052", config.Address)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("CHAINCODE_ID\n"), 0600))
+	_, err = serverconfig.ReadFile(path)
+	require.EqualError(t, err, path+`:1: expected KEY=VALUE, got "CHAINCODE_ID"`)
+
+	_, err = serverconfig.ReadFile(filepath.Join(tempDir(t), "missing.env"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read configuration file")
+}
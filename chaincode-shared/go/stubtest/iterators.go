@@ -0,0 +1,71 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+var errNoMoreResults = errors.New("no more results")
+
+// stateIterator returns the results of a query, which are read when the query
+// is made like the peer's snapshot of committed state
+type stateIterator struct {
+	results []*queryresult.KV
+	closed  bool
+}
+
+func newStateIterator(results []*queryresult.KV) *stateIterator {
+	return &stateIterator{results: results}
+}
+
+// HasNext implements shim.StateQueryIteratorInterface
+func (i *stateIterator) HasNext() bool {
+	return !i.closed && len(i.results) > 0
+}
+
+// Next implements shim.StateQueryIteratorInterface
+func (i *stateIterator) Next() (*queryresult.KV, error) {
+	if !i.HasNext() {
+		return nil, errNoMoreResults
+	}
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+// Close implements shim.StateQueryIteratorInterface
+func (i *stateIterator) Close() error {
+	i.closed = true
+	return nil
+}
+
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	closed  bool
+}
+
+// HasNext implements shim.HistoryQueryIteratorInterface
+func (i *historyIterator) HasNext() bool {
+	return !i.closed && len(i.results) > 0
+}
+
+// Next implements shim.HistoryQueryIteratorInterface
+func (i *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !i.HasNext() {
+		return nil, errNoMoreResults
+	}
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+// Close implements shim.HistoryQueryIteratorInterface
+func (i *historyIterator) Close() error {
+	i.closed = true
+	return nil
+}
@@ -0,0 +1,439 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Version identifies the transaction that last wrote a key by its block number
+// and its position in the block
+type Version struct {
+	BlockNum uint64
+	TxNum    uint64
+}
+
+// KeyRead is a key read by a transaction with the version it had, which is nil
+// if the key did not exist. Collection is empty for the public state.
+type KeyRead struct {
+	Namespace  string
+	Collection string
+	Key        string
+	Version    *Version
+}
+
+// KeyWrite is a key written or deleted by a transaction. Collection is empty
+// for the public state.
+type KeyWrite struct {
+	Namespace  string
+	Collection string
+	Key        string
+	Value      []byte
+	IsDelete   bool
+}
+
+// Transaction is a simulated transaction with its read and write sets
+type Transaction struct {
+	id             string
+	timestamp      time.Time
+	transient      map[string][]byte
+	reads          map[keyID]*Version
+	ranges         []*rangeRead
+	writes         map[string]*writeSet
+	paginated      bool
+	written        bool
+	validationCode pb.TxValidationCode
+}
+
+type keyID struct {
+	namespace  string
+	collection string
+	key        string
+}
+
+// rangeRead is a range of public keys read by a transaction, which the
+// validator checks for phantom reads
+type rangeRead struct {
+	namespace string
+	startKey  string
+	endKey    string
+	results   []keyVersion
+}
+
+type keyVersion struct {
+	key     string
+	version Version
+}
+
+type writeSet struct {
+	state       *pendingWrites
+	collections map[string]*pendingWrites
+	event       *pb.ChaincodeEvent
+}
+
+type pendingWrites struct {
+	values     map[string][]byte
+	parameters map[string][]byte
+}
+
+type ledger struct {
+	channelID   string
+	richQueries bool
+	namespaces  map[string]*namespace
+	txIDs       map[string]bool
+	blockNumber uint64
+	txNumber    int
+	clock       time.Time
+	creator     []byte
+	tx          *Transaction
+}
+
+type namespace struct {
+	chaincode   shim.Chaincode
+	state       *keySpace
+	collections map[string]*keySpace
+	history     map[string][]*queryresult.KeyModification
+	events      []*pb.ChaincodeEvent
+}
+
+// keySpace holds the committed values and validation parameters of the public
+// state of a namespace or one of its private data collections
+type keySpace struct {
+	values     map[string]*versionedValue
+	parameters map[string][]byte
+}
+
+type versionedValue struct {
+	value   []byte
+	version Version
+}
+
+// ID returns the transaction ID
+func (t *Transaction) ID() string {
+	return t.id
+}
+
+// ValidationCode returns the result of validating the transaction, which is
+// NOT_VALIDATED until it has been committed
+func (t *Transaction) ValidationCode() pb.TxValidationCode {
+	return t.validationCode
+}
+
+// Reads returns the keys read by the transaction and their versions when they
+// were first read
+func (t *Transaction) Reads() []KeyRead {
+	reads := make([]KeyRead, 0, len(t.reads))
+	for id, version := range t.reads {
+		reads = append(reads, KeyRead{Namespace: id.namespace, Collection: id.collection, Key: id.key, Version: version})
+	}
+	sort.Slice(reads, func(i, j int) bool {
+		return keyID{reads[i].Namespace, reads[i].Collection, reads[i].Key}.less(keyID{reads[j].Namespace, reads[j].Collection, reads[j].Key})
+	})
+	return reads
+}
+
+// Writes returns the keys written or deleted by the transaction
+func (t *Transaction) Writes() []KeyWrite {
+	var writes []KeyWrite
+	add := func(namespace, collection string, pending *pendingWrites) {
+		for key, value := range pending.values {
+			writes = append(writes, KeyWrite{Namespace: namespace, Collection: collection, Key: key, Value: copyBytes(value), IsDelete: value == nil})
+		}
+	}
+	for name, writeSet := range t.writes {
+		add(name, "", writeSet.state)
+		for collection, pending := range writeSet.collections {
+			add(name, collection, pending)
+		}
+	}
+	sort.Slice(writes, func(i, j int) bool {
+		return keyID{writes[i].Namespace, writes[i].Collection, writes[i].Key}.less(keyID{writes[j].Namespace, writes[j].Collection, writes[j].Key})
+	})
+	return writes
+}
+
+func (k keyID) less(other keyID) bool {
+	if k.namespace != other.namespace {
+		return k.namespace < other.namespace
+	}
+	if k.collection != other.collection {
+		return k.collection < other.collection
+	}
+	return k.key < other.key
+}
+
+func (l *ledger) startTransaction() {
+	if l.tx != nil {
+		l.clock = l.clock.Add(time.Second)
+	}
+	l.txNumber++
+	l.tx = &Transaction{
+		id:             "tx" + strconv.Itoa(l.txNumber),
+		timestamp:      l.clock,
+		reads:          make(map[keyID]*Version),
+		writes:         make(map[string]*writeSet),
+		validationCode: pb.TxValidationCode_NOT_VALIDATED,
+	}
+}
+
+// checkWrite enforces the peer's rule that a transaction cannot both write and
+// run paginated queries
+func (l *ledger) checkWrite() error {
+	if l.tx.paginated {
+		return fmt.Errorf("transaction %s has performed a paginated query, so it cannot write", l.tx.id)
+	}
+	l.tx.written = true
+	return nil
+}
+
+func (l *ledger) checkPaginatedQuery() error {
+	if l.tx.written {
+		return fmt.Errorf("transaction %s has performed a write, so it cannot run paginated queries", l.tx.id)
+	}
+	l.tx.paginated = true
+	return nil
+}
+
+// read returns the committed value of a key and adds it to the read set
+func (l *ledger) read(namespace, collection, key string) []byte {
+	v := l.space(namespace, collection).values[key]
+
+	id := keyID{namespace: namespace, collection: collection, key: key}
+	if _, ok := l.tx.reads[id]; !ok {
+		var version *Version
+		if v != nil {
+			version = &Version{BlockNum: v.version.BlockNum, TxNum: v.version.TxNum}
+		}
+		l.tx.reads[id] = version
+	}
+
+	if v == nil {
+		return nil
+	}
+	return copyBytes(v.value)
+}
+
+// scan returns up to limit public values with keys from startKey up to but
+// excluding endKey, where a limit of zero returns them all. It adds the range
+// that was read to the transaction, and returns the key to continue from if
+// there are more values.
+func (l *ledger) scan(namespace, startKey, endKey string, limit int) ([]*queryresult.KV, string) {
+	space := l.space(namespace, "")
+	results := space.rangeOf(startKey, endKey)
+
+	next := ""
+	if limit > 0 && len(results) > limit {
+		next = results[limit].Key
+		results = results[:limit]
+	}
+
+	read := &rangeRead{namespace: namespace, startKey: startKey, endKey: endKey}
+	if next != "" {
+		read.endKey = next
+	}
+	for _, kv := range results {
+		read.results = append(read.results, keyVersion{key: kv.Key, version: space.values[kv.Key].version})
+	}
+	l.tx.ranges = append(l.tx.ranges, read)
+
+	return results, next
+}
+
+// commitBlock validates the transactions in order like the peer's validator
+// and applies the writes of the valid ones
+func (l *ledger) commitBlock(txs []*Transaction) []pb.TxValidationCode {
+	l.blockNumber++
+
+	codes := make([]pb.TxValidationCode, len(txs))
+	for i, tx := range txs {
+		codes[i] = l.validate(tx)
+		tx.validationCode = codes[i]
+		l.txIDs[tx.id] = true
+
+		if codes[i] == pb.TxValidationCode_VALID {
+			l.apply(tx, Version{BlockNum: l.blockNumber, TxNum: uint64(i)})
+		}
+	}
+	return codes
+}
+
+// validate checks that nothing a transaction read has changed since it was
+// simulated, including earlier transactions in the same block
+func (l *ledger) validate(tx *Transaction) pb.TxValidationCode {
+	if l.txIDs[tx.id] {
+		return pb.TxValidationCode_DUPLICATE_TXID
+	}
+
+	for id, version := range tx.reads {
+		current := l.space(id.namespace, id.collection).values[id.key]
+		switch {
+		case current == nil && version == nil:
+		case current == nil || version == nil || current.version != *version:
+			return pb.TxValidationCode_MVCC_READ_CONFLICT
+		}
+	}
+
+	for _, read := range tx.ranges {
+		space := l.space(read.namespace, "")
+		current := space.rangeOf(read.startKey, read.endKey)
+		if len(current) != len(read.results) {
+			return pb.TxValidationCode_PHANTOM_READ_CONFLICT
+		}
+		for i, kv := range current {
+			if kv.Key != read.results[i].key || space.values[kv.Key].version != read.results[i].version {
+				return pb.TxValidationCode_PHANTOM_READ_CONFLICT
+			}
+		}
+	}
+
+	return pb.TxValidationCode_VALID
+}
+
+func (l *ledger) apply(tx *Transaction, version Version) {
+	names := make([]string, 0, len(tx.writes))
+	for name := range tx.writes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writes := tx.writes[name]
+		ns := l.namespaceFor(name)
+
+		for key, value := range writes.state.values {
+			ns.state.apply(key, value, version)
+			ns.history[key] = append(ns.history[key], &queryresult.KeyModification{
+				TxId:      tx.id,
+				Value:     value,
+				Timestamp: mustTimestamp(tx.timestamp),
+				IsDelete:  value == nil,
+			})
+		}
+		ns.state.applyParameters(writes.state.parameters)
+
+		for collection, pending := range writes.collections {
+			space := ns.collection(collection)
+			for key, value := range pending.values {
+				space.apply(key, value, version)
+			}
+			space.applyParameters(pending.parameters)
+		}
+
+		if writes.event != nil {
+			ns.events = append(ns.events, writes.event)
+		}
+	}
+}
+
+func (l *ledger) namespaceFor(name string) *namespace {
+	ns, ok := l.namespaces[name]
+	if !ok {
+		ns = &namespace{
+			state:       newKeySpace(),
+			collections: make(map[string]*keySpace),
+			history:     make(map[string][]*queryresult.KeyModification),
+		}
+		l.namespaces[name] = ns
+	}
+	return ns
+}
+
+// space returns the public state of a namespace, or one of its collections
+func (l *ledger) space(namespace, collection string) *keySpace {
+	ns := l.namespaceFor(namespace)
+	if collection == "" {
+		return ns.state
+	}
+	return ns.collection(collection)
+}
+
+func (ns *namespace) collection(name string) *keySpace {
+	space, ok := ns.collections[name]
+	if !ok {
+		space = newKeySpace()
+		ns.collections[name] = space
+	}
+	return space
+}
+
+func newKeySpace() *keySpace {
+	return &keySpace{
+		values:     make(map[string]*versionedValue),
+		parameters: make(map[string][]byte),
+	}
+}
+
+func (k *keySpace) apply(key string, value []byte, version Version) {
+	if value == nil {
+		delete(k.values, key)
+		return
+	}
+	k.values[key] = &versionedValue{value: value, version: version}
+}
+
+func (k *keySpace) applyParameters(parameters map[string][]byte) {
+	for key, ep := range parameters {
+		if ep == nil {
+			delete(k.parameters, key)
+			continue
+		}
+		k.parameters[key] = ep
+	}
+}
+
+// sortedKeys returns every key in the space in the order of the state database
+func (k *keySpace) sortedKeys() []string {
+	keys := make([]string, 0, len(k.values))
+	for key := range k.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rangeOf returns the values with keys from startKey up to but excluding
+// endKey, where an empty endKey has no upper bound
+func (k *keySpace) rangeOf(startKey, endKey string) []*queryresult.KV {
+	var results []*queryresult.KV
+	for _, key := range k.sortedKeys() {
+		if key < startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		results = append(results, &queryresult.KV{Key: key, Value: copyBytes(k.values[key].value)})
+	}
+	return results
+}
+
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{
+		values:     make(map[string][]byte),
+		parameters: make(map[string][]byte),
+	}
+}
+
+func (w *writeSet) collection(name string) *pendingWrites {
+	pending, ok := w.collections[name]
+	if !ok {
+		pending = newPendingWrites()
+		w.collections[name] = pending
+	}
+	return pending
+}
+
+// put records a write, where a nil or empty value is a delete
+func (p *pendingWrites) put(key string, value []byte) {
+	if len(value) == 0 {
+		p.values[key] = nil
+		return
+	}
+	p.values[key] = copyBytes(value)
+}
@@ -0,0 +1,390 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// richQuery is the subset of a CouchDB Mango query supported by the stub
+type richQuery struct {
+	Selector map[string]interface{} `json:"selector"`
+	Sort     []interface{}          `json:"sort"`
+	Limit    int                    `json:"limit"`
+	Skip     int                    `json:"skip"`
+}
+
+type sortField struct {
+	path       []string
+	descending bool
+}
+
+type document struct {
+	kv    *queryresult.KV
+	value map[string]interface{}
+}
+
+// query runs a rich query against the JSON values of space. Values that are
+// not JSON objects are never matched, as CouchDB stores them as attachments. A
+// pageSize of zero returns every result and the query's own limit applies.
+//
+// Like CouchDB, documents are returned in key order unless the query sorts
+// them, documents without every sort field are left out, and a paginated query
+// always returns a bookmark, which yields no results once they are exhausted.
+func (s *ChaincodeStub) query(space *keySpace, query string, pageSize int32, bookmark string) ([]*queryresult.KV, string, error) {
+	if !s.ledger.richQueries {
+		return nil, "", errors.New("ExecuteQuery not supported for leveldb")
+	}
+
+	parsed := &richQuery{}
+	decoder := json.NewDecoder(strings.NewReader(query))
+	decoder.UseNumber()
+	if err := decoder.Decode(parsed); err != nil {
+		return nil, "", fmt.Errorf("failed to parse query %s: %v", query, err)
+	}
+	if parsed.Selector == nil {
+		return nil, "", fmt.Errorf("query %s does not have a selector", query)
+	}
+
+	sortFields, err := parseSort(parsed.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var matches []*document
+	for _, key := range space.sortedKeys() {
+		kv := &queryresult.KV{Key: key, Value: copyBytes(space.values[key].value)}
+
+		value := make(map[string]interface{})
+		decoder := json.NewDecoder(bytes.NewReader(kv.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			continue
+		}
+
+		ok, err := matchSelector(value, parsed.Selector)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok && hasFields(value, sortFields) {
+			matches = append(matches, &document{kv: kv, value: value})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, field := range sortFields {
+			a, _ := lookup(matches[i].value, field.path)
+			b, _ := lookup(matches[j].value, field.path)
+			if c := collate(a, b); c != 0 {
+				return (c < 0) != field.descending
+			}
+		}
+		return false
+	})
+
+	start := parsed.Skip
+	limit := parsed.Limit
+	if pageSize > 0 {
+		limit = int(pageSize)
+		if bookmark != "" {
+			offset, err := strconv.Atoi(bookmark)
+			if err != nil || offset < 0 {
+				return nil, "", fmt.Errorf("invalid bookmark %q", bookmark)
+			}
+			start = offset
+		}
+	}
+
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := len(matches)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	results := make([]*queryresult.KV, 0, end-start)
+	for _, match := range matches[start:end] {
+		results = append(results, match.kv)
+	}
+
+	next := ""
+	if pageSize > 0 {
+		next = strconv.Itoa(end)
+	}
+	return results, next, nil
+}
+
+func parseSort(fields []interface{}) ([]sortField, error) {
+	var parsed []sortField
+	for _, field := range fields {
+		switch f := field.(type) {
+		case string:
+			parsed = append(parsed, sortField{path: strings.Split(f, ".")})
+		case map[string]interface{}:
+			if len(f) != 1 {
+				return nil, fmt.Errorf("sort field %v must have exactly one field name", f)
+			}
+			for name, direction := range f {
+				if direction != "asc" && direction != "desc" {
+					return nil, fmt.Errorf("sort direction of %s must be asc or desc, got %v", name, direction)
+				}
+				parsed = append(parsed, sortField{path: strings.Split(name, "."), descending: direction == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("invalid sort field %v", field)
+		}
+	}
+	return parsed, nil
+}
+
+func hasFields(value map[string]interface{}, fields []sortField) bool {
+	for _, field := range fields {
+		if _, ok := lookup(value, field.path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value at a dotted field path
+func lookup(value interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func matchSelector(value map[string]interface{}, selector map[string]interface{}) (bool, error) {
+	for name, condition := range selector {
+		var ok bool
+		var err error
+
+		switch name {
+		case "$and", "$or", "$nor":
+			ok, err = matchCombination(value, name, condition)
+		case "$not":
+			sub, isSelector := condition.(map[string]interface{})
+			if !isSelector {
+				return false, fmt.Errorf("$not must be given a selector, got %v", condition)
+			}
+			ok, err = matchSelector(value, sub)
+			ok = !ok
+		default:
+			if strings.HasPrefix(name, "$") {
+				return false, fmt.Errorf("unsupported operator %s", name)
+			}
+			field, exists := lookup(value, strings.Split(name, "."))
+			ok, err = matchCondition(field, exists, condition)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCombination(value map[string]interface{}, operator string, condition interface{}) (bool, error) {
+	selectors, ok := condition.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("%s must be given an array of selectors, got %v", operator, condition)
+	}
+
+	matched := 0
+	for _, selector := range selectors {
+		sub, ok := selector.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s must be given an array of selectors, got %v", operator, condition)
+		}
+		ok, err := matchSelector(value, sub)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+
+	switch operator {
+	case "$and":
+		return matched == len(selectors), nil
+	case "$or":
+		return matched > 0, nil
+	default:
+		return matched == 0, nil
+	}
+}
+
+func matchCondition(field interface{}, exists bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return exists && equal(field, condition), nil
+	}
+
+	operatorCount := 0
+	for name := range operators {
+		if strings.HasPrefix(name, "$") {
+			operatorCount++
+		}
+	}
+	if operatorCount > 0 && operatorCount < len(operators) {
+		return false, fmt.Errorf("condition %v mixes operators and field names", condition)
+	}
+	if operatorCount == 0 {
+		object, ok := field.(map[string]interface{})
+		if !exists || !ok {
+			return false, nil
+		}
+		return matchSelector(object, operators)
+	}
+
+	for name, argument := range operators {
+		ok, err := matchOperator(field, exists, name, argument)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(field interface{}, exists bool, operator string, argument interface{}) (bool, error) {
+	if operator == "$exists" {
+		want, ok := argument.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists must be given true or false, got %v", argument)
+		}
+		return exists == want, nil
+	}
+	if !exists {
+		return false, nil
+	}
+
+	switch operator {
+	case "$eq":
+		return equal(field, argument), nil
+	case "$ne":
+		return !equal(field, argument), nil
+	case "$gt":
+		return collate(field, argument) > 0, nil
+	case "$gte":
+		return collate(field, argument) >= 0, nil
+	case "$lt":
+		return collate(field, argument) < 0, nil
+	case "$lte":
+		return collate(field, argument) <= 0, nil
+	case "$in", "$nin":
+		values, ok := argument.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s must be given an array, got %v", operator, argument)
+		}
+		found := false
+		for _, value := range values {
+			if equal(field, value) {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "$in"), nil
+	case "$regex":
+		pattern, ok := argument.(string)
+		if !ok {
+			return false, fmt.Errorf("$regex must be given a string, got %v", argument)
+		}
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid $regex %q: %v", pattern, err)
+		}
+		text, ok := field.(string)
+		return ok && expression.MatchString(text), nil
+	default:
+		return false, fmt.Errorf("unsupported operator %s", operator)
+	}
+}
+
+func equal(a, b interface{}) bool {
+	if collate(a, b) != 0 {
+		return false
+	}
+	switch a.(type) {
+	case []interface{}, map[string]interface{}:
+		return reflect.DeepEqual(a, b)
+	}
+	return true
+}
+
+// collate compares two JSON values in CouchDB's order: null, false, true,
+// numbers, strings, arrays and then objects
+func collate(a, b interface{}) int {
+	rankA, rankB := rank(a), rank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch x := a.(type) {
+	case json.Number:
+		fa, _ := x.Float64()
+		fb, _ := b.(json.Number).Float64()
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case []interface{}:
+		y := b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := collate(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case map[string]interface{}:
+		ja, _ := json.Marshal(x)
+		jb, _ := json.Marshal(b)
+		return bytes.Compare(ja, jb)
+	}
+	return 0
+}
+
+func rank(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case json.Number:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,98 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueryStub(t *testing.T) *stubtest.ChaincodeStub {
+	stub := stubtest.New()
+	stub.EnableRichQueries()
+
+	assets := map[string]string{
+		"asset1": `{"docType":"asset","color":"blue","size":5,"owner":"Tomoko","tags":["new"]}`,
+		"asset2": `{"docType":"asset","color":"red","size":15,"owner":"Brad"}`,
+		"asset3": `{"docType":"asset","color":"green","size":10,"owner":"Tomoko","details":{"grade":"A"}}`,
+		"asset4": `{"docType":"asset","color":"blue","size":20,"owner":"Max","details":{"grade":"B"}}`,
+		"asset5": `{"docType":"asset","color":"blue","owner":"Max"}`,
+		"note1":  `not json`,
+	}
+	for key, value := range assets {
+		require.NoError(t, stub.PutState(key, []byte(value)))
+	}
+	stub.Commit()
+	return stub
+}
+
+func TestRichQueries(t *testing.T) {
+	stub := newQueryStub(t)
+
+	tests := []struct {
+		query string
+		keys  []string
+	}{
+		{query: `{"selector":{"docType":"asset","owner":"Tomoko"}}`, keys: []string{"asset1", "asset3"}},
+		{query: `{"selector":{"size":{"$gte":10,"$lt":20}}}`, keys: []string{"asset2", "asset3"}},
+		{query: `{"selector":{"color":{"$in":["red","green"]}}}`, keys: []string{"asset2", "asset3"}},
+		{query: `{"selector":{"color":{"$nin":["red","green"]},"size":{"$exists":false}}}`, keys: []string{"asset5"}},
+		{query: `{"selector":{"$or":[{"owner":"Brad"},{"details.grade":"B"}]}}`, keys: []string{"asset2", "asset4"}},
+		{query: `{"selector":{"details":{"grade":"A"}}}`, keys: []string{"asset3"}},
+		{query: `{"selector":{"$not":{"color":"blue"}}}`, keys: []string{"asset2", "asset3"}},
+		{query: `{"selector":{"owner":{"$regex":"^M"},"color":{"$ne":"red"}}}`, keys: []string{"asset4", "asset5"}},
+		{query: `{"selector":{"tags":["new"]}}`, keys: []string{"asset1"}},
+		{query: `{"selector":{"docType":"asset"},"sort":[{"size":"desc"}]}`, keys: []string{"asset4", "asset2", "asset3", "asset1"}},
+		{query: `{"selector":{"docType":"asset"},"sort":["owner","size"],"skip":1,"limit":2}`, keys: []string{"asset4", "asset1"}},
+	}
+
+	for _, test := range tests {
+		iterator, err := stub.GetQueryResult(test.query)
+		require.NoError(t, err, test.query)
+		require.Equal(t, test.keys, keys(t, iterator), test.query)
+	}
+
+	_, err := stub.GetQueryResult(`{"selector":{"size":{"$mod":[2,0]}}}`)
+	require.EqualError(t, err, "unsupported operator $mod")
+	_, err = stub.GetQueryResult(`{"sort":["size"]}`)
+	require.EqualError(t, err, `query {"sort":["size"]} does not have a selector`)
+}
+
+func TestRichQueryPagination(t *testing.T) {
+	stub := newQueryStub(t)
+
+	var pages [][]string
+	bookmark := ""
+	for {
+		iterator, metadata, err := stub.GetQueryResultWithPagination(`{"selector":{"docType":"asset"}}`, 2, bookmark)
+		require.NoError(t, err)
+		page := keys(t, iterator)
+		if len(page) == 0 {
+			break
+		}
+		require.NotEmpty(t, metadata.Bookmark)
+		pages = append(pages, page)
+		bookmark = metadata.Bookmark
+	}
+	require.Equal(t, [][]string{{"asset1", "asset2"}, {"asset3", "asset4"}, {"asset5"}}, pages)
+
+	_, _, err := stub.GetQueryResultWithPagination(`{"selector":{}}`, 2, "not a bookmark")
+	require.EqualError(t, err, `invalid bookmark "not a bookmark"`)
+}
+
+func TestRichQueriesAreDisabledByDefault(t *testing.T) {
+	stub := stubtest.New()
+	_, err := stub.GetQueryResult(`{"selector":{}}`)
+	require.EqualError(t, err, "ExecuteQuery not supported for leveldb")
+
+	require.NoError(t, stub.PutPrivateData("collection", "asset1", []byte(`{"size":1}`)))
+	stub.Commit()
+	stub.EnableRichQueries()
+	iterator, err := stub.GetPrivateDataQueryResult("collection", `{"selector":{"size":1}}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1"}, keys(t, iterator))
+}
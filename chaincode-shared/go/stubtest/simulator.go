@@ -0,0 +1,105 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// DefaultBlockSize is the number of transactions in a block when a Simulator
+// does not set one, matching the orderer's default batch size
+const DefaultBlockSize = 10
+
+// Invocation runs one transaction against stub, and returns an error if the
+// transaction fails endorsement
+type Invocation func(stub *ChaincodeStub) error
+
+// Invoke returns an Invocation calling the chaincode with args, which fails if
+// the chaincode returns an error response
+func Invoke(cc shim.Chaincode, args ...string) Invocation {
+	return func(stub *ChaincodeStub) error {
+		stub.SetArgs(args...)
+		response := cc.Invoke(stub)
+		if response.Status >= shim.ERRORTHRESHOLD {
+			return fmt.Errorf("invocation %v failed with status %d: %s", args, response.Status, response.Message)
+		}
+		return nil
+	}
+}
+
+// Simulator submits transactions faster than blocks are committed, which is
+// when MVCC conflicts happen. Every transaction in a block is endorsed against
+// the world state committed before the block, then the block is validated and
+// committed before the next one is endorsed.
+type Simulator struct {
+	Stub      *ChaincodeStub
+	BlockSize int
+}
+
+// SimulationResult is the outcome of running invocations with a Simulator
+type SimulationResult struct {
+	// Transactions are the endorsed transactions in the order they were
+	// committed
+	Transactions []*Transaction
+	// EndorsementFailures counts the invocations that returned an error, which
+	// are never submitted for ordering
+	EndorsementFailures int
+}
+
+// Run endorses and commits the invocations in blocks
+func (s *Simulator) Run(invocations ...Invocation) *SimulationResult {
+	blockSize := s.BlockSize
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	result := &SimulationResult{}
+	for start := 0; start < len(invocations); start += blockSize {
+		end := start + blockSize
+		if end > len(invocations) {
+			end = len(invocations)
+		}
+
+		var block []*Transaction
+		for _, invocation := range invocations[start:end] {
+			if err := invocation(s.Stub); err != nil {
+				s.Stub.Rollback()
+				result.EndorsementFailures++
+				continue
+			}
+			block = append(block, s.Stub.Endorse())
+		}
+
+		if len(block) > 0 {
+			s.Stub.CommitBlock(block...)
+		}
+		result.Transactions = append(result.Transactions, block...)
+	}
+	return result
+}
+
+// Count returns the number of committed transactions with a validation code
+func (r *SimulationResult) Count(code pb.TxValidationCode) int {
+	count := 0
+	for _, tx := range r.Transactions {
+		if tx.ValidationCode() == code {
+			count++
+		}
+	}
+	return count
+}
+
+// ConflictRate returns the fraction of committed transactions that were
+// invalidated by MVCC or phantom read conflicts
+func (r *SimulationResult) ConflictRate() float64 {
+	if len(r.Transactions) == 0 {
+		return 0
+	}
+	conflicts := r.Count(pb.TxValidationCode_MVCC_READ_CONFLICT) + r.Count(pb.TxValidationCode_PHANTOM_READ_CONFLICT)
+	return float64(conflicts) / float64(len(r.Transactions))
+}
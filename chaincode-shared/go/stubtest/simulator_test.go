@@ -0,0 +1,139 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+// counterChaincode increments a counter with a read and a write, or counts the
+// keys in a range
+type counterChaincode struct{}
+
+func (counterChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (counterChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	switch function {
+	case "increment":
+		value, err := stub.GetState(args[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		count, _ := strconv.Atoi(string(value))
+		if err := stub.PutState(args[0], []byte(strconv.Itoa(count+1))); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "snapshot":
+		iterator, err := stub.GetStateByRange("", "")
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		defer iterator.Close()
+		count := 0
+		for iterator.HasNext() {
+			if _, err := iterator.Next(); err != nil {
+				return shim.Error(err.Error())
+			}
+			count++
+		}
+		if err := stub.PutState("snapshot", []byte(strconv.Itoa(count))); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	}
+	return shim.Error("unknown function " + function)
+}
+
+func TestCommitBlock(t *testing.T) {
+	stub := stubtest.New()
+	cc := counterChaincode{}
+
+	require.NoError(t, stubtest.Invoke(cc, "increment", "counter")(stub))
+	first := stub.Endorse()
+	require.NoError(t, stubtest.Invoke(cc, "increment", "counter")(stub))
+	second := stub.Endorse()
+	require.NoError(t, stubtest.Invoke(cc, "increment", "other")(stub))
+	third := stub.Endorse()
+
+	require.Equal(t, []stubtest.KeyRead{{Namespace: stubtest.DefaultChaincodeName, Key: "counter"}}, first.Reads())
+	require.Equal(t, []stubtest.KeyWrite{{Namespace: stubtest.DefaultChaincodeName, Key: "counter", Value: []byte("1")}}, first.Writes())
+	require.Equal(t, pb.TxValidationCode_NOT_VALIDATED, first.ValidationCode())
+
+	codes := stub.CommitBlock(first, second, third)
+	require.Equal(t, []pb.TxValidationCode{
+		pb.TxValidationCode_VALID,
+		pb.TxValidationCode_MVCC_READ_CONFLICT,
+		pb.TxValidationCode_VALID,
+	}, codes)
+	require.Equal(t, pb.TxValidationCode_MVCC_READ_CONFLICT, second.ValidationCode())
+
+	value, err := stub.GetState("counter")
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), value)
+
+	require.NoError(t, stubtest.Invoke(cc, "increment", "counter")(stub))
+	next := stub.Endorse()
+	require.Equal(t, []stubtest.KeyRead{
+		{Namespace: stubtest.DefaultChaincodeName, Key: "counter", Version: &stubtest.Version{BlockNum: 1, TxNum: 0}},
+	}, next.Reads())
+
+	require.Equal(t, []pb.TxValidationCode{pb.TxValidationCode_DUPLICATE_TXID}, stub.CommitBlock(first))
+}
+
+func TestPhantomReads(t *testing.T) {
+	stub := stubtest.New()
+	cc := counterChaincode{}
+	require.NoError(t, stub.PutState("asset1", []byte("1")))
+	stub.Commit()
+
+	require.NoError(t, stubtest.Invoke(cc, "increment", "asset2")(stub))
+	insert := stub.Endorse()
+	require.NoError(t, stubtest.Invoke(cc, "snapshot")(stub))
+	snapshot := stub.Endorse()
+	require.Equal(t, []pb.TxValidationCode{
+		pb.TxValidationCode_VALID,
+		pb.TxValidationCode_PHANTOM_READ_CONFLICT,
+	}, stub.CommitBlock(insert, snapshot))
+
+	require.NoError(t, stubtest.Invoke(cc, "snapshot")(stub))
+	require.Equal(t, pb.TxValidationCode_VALID, stub.Commit())
+	value, err := stub.GetState("snapshot")
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), value)
+}
+
+func TestSimulator(t *testing.T) {
+	cc := counterChaincode{}
+	var invocations []stubtest.Invocation
+	for i := 0; i < 25; i++ {
+		invocations = append(invocations, stubtest.Invoke(cc, "increment", "counter"))
+	}
+	invocations = append(invocations, stubtest.Invoke(cc, "unknown"))
+
+	simulator := &stubtest.Simulator{Stub: stubtest.New()}
+	result := simulator.Run(invocations...)
+	require.Len(t, result.Transactions, 25)
+	require.Equal(t, 1, result.EndorsementFailures)
+	require.Equal(t, 3, result.Count(pb.TxValidationCode_VALID), "one transaction in each block is valid")
+	require.InDelta(t, 22.0/25.0, result.ConflictRate(), 0.0001)
+
+	value, err := simulator.Stub.GetState("counter")
+	require.NoError(t, err)
+	require.Equal(t, []byte("3"), value)
+
+	simulator = &stubtest.Simulator{Stub: stubtest.New(), BlockSize: 1}
+	result = simulator.Run(invocations[:25]...)
+	require.Zero(t, result.ConflictRate(), "transactions are serialized with one per block")
+}
@@ -0,0 +1,604 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package stubtest provides an in-memory implementation of
+// shim.ChaincodeStubInterface for unit testing chaincode against a world state
+// that behaves like the peer's, instead of scripting every return value.
+package stubtest
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	// DefaultChannelID is the channel of a new stub
+	DefaultChannelID = "mychannel"
+	// DefaultChaincodeName is the namespace of the chaincode using a new stub
+	DefaultChaincodeName = "chaincode"
+)
+
+// StartTime is the timestamp of the first transaction. Each later transaction
+// is one second after the one before it unless SetTxTimestamp is used.
+var StartTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	compositeKeyNamespace = "\x00"
+	minUnicodeRuneValue   = 0
+	maxUnicodeRuneValue   = utf8.MaxRune
+	emptyKeySubstitute    = "\x01"
+)
+
+// ChaincodeStub is an in-memory shim.ChaincodeStubInterface. Like the peer, it
+// simulates one transaction at a time: reads return the committed world state,
+// and writes, private data, validation parameters and the event are only
+// applied when Commit is called. Rollback discards them instead.
+//
+// The stub records the versions of the keys and ranges each transaction reads.
+// Endorse and CommitBlock use them to commit several transactions in one block
+// and find the ones the peer would reject with MVCC or phantom read conflicts.
+//
+// Rich queries are rejected, as with LevelDB, until EnableRichQueries is called.
+type ChaincodeStub struct {
+	ledger    *ledger
+	namespace string
+	args      [][]byte
+}
+
+// New returns a stub for a chaincode with an empty world state
+func New() *ChaincodeStub {
+	l := &ledger{
+		channelID:  DefaultChannelID,
+		namespaces: make(map[string]*namespace),
+		txIDs:      make(map[string]bool),
+		clock:      StartTime,
+	}
+	l.startTransaction()
+
+	return &ChaincodeStub{ledger: l, namespace: DefaultChaincodeName}
+}
+
+// RegisterChaincode makes cc available to InvokeChaincode as name and returns a
+// stub for its namespace, which shares the ledger and current transaction
+func (s *ChaincodeStub) RegisterChaincode(name string, cc shim.Chaincode) *ChaincodeStub {
+	s.ledger.namespaceFor(name).chaincode = cc
+	return &ChaincodeStub{ledger: s.ledger, namespace: name}
+}
+
+// EnableRichQueries makes the stub accept CouchDB queries in GetQueryResult and
+// the other query functions
+func (s *ChaincodeStub) EnableRichQueries() {
+	s.ledger.richQueries = true
+}
+
+// SetChannelID sets the channel of the ledger
+func (s *ChaincodeStub) SetChannelID(channelID string) {
+	s.ledger.channelID = channelID
+}
+
+// SetArgs sets the function name and parameters of the invocation
+func (s *ChaincodeStub) SetArgs(args ...string) {
+	s.args = make([][]byte, len(args))
+	for i, arg := range args {
+		s.args[i] = []byte(arg)
+	}
+}
+
+// SetCreator sets the identity submitting this and later transactions from an
+// MSP ID and a PEM encoded certificate
+func (s *ChaincodeStub) SetCreator(mspID string, certPEM []byte) error {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certPEM})
+	if err != nil {
+		return fmt.Errorf("failed to marshal the creator: %v", err)
+	}
+	s.ledger.creator = creator
+	return nil
+}
+
+// SetTransient sets the transient data of the current transaction
+func (s *ChaincodeStub) SetTransient(transient map[string][]byte) {
+	s.ledger.tx.transient = transient
+}
+
+// SetTxID sets the ID of the current transaction
+func (s *ChaincodeStub) SetTxID(txID string) {
+	s.ledger.tx.id = txID
+}
+
+// SetTxTimestamp sets the timestamp of the current transaction. Later
+// transactions continue one second apart from it.
+func (s *ChaincodeStub) SetTxTimestamp(t time.Time) {
+	s.ledger.clock = t
+	s.ledger.tx.timestamp = t
+}
+
+// Commit commits the current transaction in a block of its own and starts the
+// next transaction. Transactions committed this way are always valid unless
+// their ID has been committed before.
+func (s *ChaincodeStub) Commit() pb.TxValidationCode {
+	return s.CommitBlock(s.Endorse())[0]
+}
+
+// Endorse ends the current transaction without committing it and starts the
+// next one. Transactions endorsed one after another all read the same
+// committed state, as when clients submit them concurrently, until they are
+// ordered into a block with CommitBlock.
+func (s *ChaincodeStub) Endorse() *Transaction {
+	tx := s.ledger.tx
+	s.ledger.startTransaction()
+	return tx
+}
+
+// CommitBlock validates endorsed transactions in order like the peer's
+// validator and applies the writes of the valid ones in a new block. A
+// transaction is invalid if a key it read, or the keys in a range it read,
+// changed after it was endorsed, including changes made by earlier
+// transactions in the block.
+func (s *ChaincodeStub) CommitBlock(txs ...*Transaction) []pb.TxValidationCode {
+	return s.ledger.commitBlock(txs)
+}
+
+// Rollback discards the writes of the current transaction and starts the next
+// one, as when a transaction fails endorsement
+func (s *ChaincodeStub) Rollback() {
+	s.ledger.startTransaction()
+}
+
+// Events returns the events committed by the chaincode, oldest first
+func (s *ChaincodeStub) Events() []*pb.ChaincodeEvent {
+	return s.ledger.namespaceFor(s.namespace).events
+}
+
+// GetArgs implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetArgs() [][]byte {
+	return s.args
+}
+
+// GetStringArgs implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStringArgs() []string {
+	args := make([]string, len(s.args))
+	for i, arg := range s.args {
+		args[i] = string(arg)
+	}
+	return args
+}
+
+// GetFunctionAndParameters implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+// GetArgsSlice implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetArgsSlice() ([]byte, error) {
+	var slice []byte
+	for _, arg := range s.args {
+		slice = append(slice, arg...)
+	}
+	return slice, nil
+}
+
+// GetTxID implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetTxID() string {
+	return s.ledger.tx.id
+}
+
+// GetChannelID implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetChannelID() string {
+	return s.ledger.channelID
+}
+
+// InvokeChaincode implements shim.ChaincodeStubInterface. The called chaincode
+// must have been registered with RegisterChaincode, and its writes become part
+// of the current transaction.
+func (s *ChaincodeStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	if channel != "" && channel != s.ledger.channelID {
+		return shim.Error(fmt.Sprintf("invoking chaincode on another channel (%s) is not supported", channel))
+	}
+
+	ns, ok := s.ledger.namespaces[chaincodeName]
+	if !ok || ns.chaincode == nil {
+		return shim.Error(fmt.Sprintf("chaincode %s is not registered", chaincodeName))
+	}
+
+	called := &ChaincodeStub{ledger: s.ledger, namespace: chaincodeName, args: args}
+	return ns.chaincode.Invoke(called)
+}
+
+// GetState implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetState(key string) ([]byte, error) {
+	return s.ledger.read(s.namespace, "", key), nil
+}
+
+// PutState implements shim.ChaincodeStubInterface. As on the peer, writing an
+// empty value deletes the key.
+func (s *ChaincodeStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().state.put(key, value)
+	return nil
+}
+
+// DelState implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) DelState(key string) error {
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().state.put(key, nil)
+	return nil
+}
+
+// SetStateValidationParameter implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) SetStateValidationParameter(key string, ep []byte) error {
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().state.parameters[key] = copyBytes(ep)
+	return nil
+}
+
+// GetStateValidationParameter implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStateValidationParameter(key string) ([]byte, error) {
+	s.ledger.read(s.namespace, "", key)
+	return copyBytes(s.ledger.namespaceFor(s.namespace).state.parameters[key]), nil
+}
+
+// GetStateByRange implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	results, _ := s.ledger.scan(s.namespace, startKey, endKey, 0)
+	return newStateIterator(results), nil
+}
+
+// GetStateByRangeWithPagination implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return s.rangePage(startKey, endKey, pageSize, bookmark)
+}
+
+// GetStateByPartialCompositeKey implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	results, _ := s.ledger.scan(s.namespace, startKey, endKey, 0)
+	return newStateIterator(results), nil
+}
+
+// GetStateByPartialCompositeKeyWithPagination implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.rangePage(startKey, endKey, pageSize, bookmark)
+}
+
+// CreateCompositeKey implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if len(compositeKey) < 2 || compositeKey[:1] != compositeKeyNamespace || compositeKey[len(compositeKey)-1] != minUnicodeRuneValue {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+
+	var components []string
+	start := 1
+	for i := 1; i < len(compositeKey); i++ {
+		if compositeKey[i] == minUnicodeRuneValue {
+			components = append(components, compositeKey[start:i])
+			start = i + 1
+		}
+	}
+	return components[0], components[1:], nil
+}
+
+// GetQueryResult implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	results, _, err := s.query(s.state(), query, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(results), nil
+}
+
+// GetQueryResultWithPagination implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.ledger.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	if pageSize <= 0 {
+		return nil, nil, fmt.Errorf("page size must be greater than zero, got %d", pageSize)
+	}
+
+	results, next, err := s.query(s.state(), query, pageSize, bookmark)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newStateIterator(results), &pb.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            next,
+	}, nil
+}
+
+// GetHistoryForKey implements shim.ChaincodeStubInterface. The modifications
+// are returned newest first.
+func (s *ChaincodeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	history := s.ledger.namespaceFor(s.namespace).history[key]
+
+	results := make([]*queryresult.KeyModification, len(history))
+	for i, modification := range history {
+		results[len(history)-1-i] = &queryresult.KeyModification{
+			TxId:      modification.TxId,
+			Value:     copyBytes(modification.Value),
+			Timestamp: modification.Timestamp,
+			IsDelete:  modification.IsDelete,
+		}
+	}
+	return &historyIterator{results: results}, nil
+}
+
+// GetPrivateData implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	return s.ledger.read(s.namespace, collection, key), nil
+}
+
+// GetPrivateDataHash implements shim.ChaincodeStubInterface. It returns the
+// SHA-256 hash of the committed value, or nil if the key does not exist.
+func (s *ChaincodeStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	value := s.ledger.read(s.namespace, collection, key)
+	if value == nil {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// PutPrivateData implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().collection(collection).put(key, value)
+	return nil
+}
+
+// DelPrivateData implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) DelPrivateData(collection, key string) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().collection(collection).put(key, nil)
+	return nil
+}
+
+// SetPrivateDataValidationParameter implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if err := s.ledger.checkWrite(); err != nil {
+		return err
+	}
+	s.writes().collection(collection).parameters[key] = copyBytes(ep)
+	return nil
+}
+
+// GetPrivateDataValidationParameter implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	space, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	s.ledger.read(s.namespace, collection, key)
+	return copyBytes(space.parameters[key]), nil
+}
+
+// GetPrivateDataByRange implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	space, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return newStateIterator(space.rangeOf(startKey, endKey)), nil
+}
+
+// GetPrivateDataByPartialCompositeKey implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	space, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(space.rangeOf(startKey, endKey)), nil
+}
+
+// GetPrivateDataQueryResult implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	space, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	results, _, err := s.query(space, query, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(results), nil
+}
+
+// GetCreator implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetCreator() ([]byte, error) {
+	return s.ledger.creator, nil
+}
+
+// GetTransient implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetTransient() (map[string][]byte, error) {
+	return s.ledger.tx.transient, nil
+}
+
+// GetBinding implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+// GetDecorations implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+// GetSignedProposal implements shim.ChaincodeStubInterface. There is no
+// proposal behind the stub, so it always fails.
+func (s *ChaincodeStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return nil, errors.New("the in-memory stub does not have a signed proposal")
+}
+
+// GetTxTimestamp implements shim.ChaincodeStubInterface
+func (s *ChaincodeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return ptypes.TimestampProto(s.ledger.tx.timestamp)
+}
+
+// SetEvent implements shim.ChaincodeStubInterface. As on the peer, only the
+// last event set by a transaction is kept.
+func (s *ChaincodeStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.writes().event = &pb.ChaincodeEvent{
+		ChaincodeId: s.namespace,
+		TxId:        s.ledger.tx.id,
+		EventName:   name,
+		Payload:     copyBytes(payload),
+	}
+	return nil
+}
+
+func (s *ChaincodeStub) state() *keySpace {
+	return s.ledger.namespaceFor(s.namespace).state
+}
+
+func (s *ChaincodeStub) collection(collection string) (*keySpace, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	return s.ledger.namespaceFor(s.namespace).collection(collection), nil
+}
+
+func (s *ChaincodeStub) writes() *writeSet {
+	writes, ok := s.ledger.tx.writes[s.namespace]
+	if !ok {
+		writes = &writeSet{
+			state:       newPendingWrites(),
+			collections: make(map[string]*pendingWrites),
+		}
+		s.ledger.tx.writes[s.namespace] = writes
+	}
+	return writes
+}
+
+func (s *ChaincodeStub) rangePage(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.ledger.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	if pageSize <= 0 {
+		return nil, nil, fmt.Errorf("page size must be greater than zero, got %d", pageSize)
+	}
+	if bookmark != "" {
+		startKey = bookmark
+	}
+
+	results, next := s.ledger.scan(s.namespace, startKey, endKey, int(pageSize))
+
+	return newStateIterator(results), &pb.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            next,
+	}, nil
+}
+
+func partialCompositeKeyRange(objectType string, keys []string) (string, string, error) {
+	startKey, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return "", "", err
+	}
+	return startKey, startKey + string(maxUnicodeRuneValue), nil
+}
+
+func validateSimpleKeys(keys ...string) error {
+	for _, key := range keys {
+		if len(key) > 0 && key[:1] == compositeKeyNamespace {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", key)
+		}
+	}
+	return nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func mustTimestamp(t time.Time) *timestamp.Timestamp {
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		panic(fmt.Sprintf("invalid transaction timestamp %v: %v", t, err))
+	}
+	return ts
+}
@@ -0,0 +1,352 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stubtest_test
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+var _ shim.ChaincodeStubInterface = &stubtest.ChaincodeStub{}
+
+func keys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	t.Helper()
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		require.NoError(t, err)
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestWritesAreAppliedOnCommit(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.PutState("asset1", []byte("blue")))
+
+	value, err := stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "a transaction does not read its own writes")
+
+	stub.Commit()
+	value, err = stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("blue"), value)
+
+	require.NoError(t, stub.PutState("asset1", []byte("red")))
+	stub.Rollback()
+	value, err = stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("blue"), value)
+
+	require.NoError(t, stub.PutState("asset1", []byte{}))
+	stub.Commit()
+	value, err = stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "an empty value deletes the key")
+
+	require.EqualError(t, stub.PutState("", []byte("blue")), "key must not be an empty string")
+}
+
+func TestTransactions(t *testing.T) {
+	stub := stubtest.New()
+	require.Equal(t, "tx1", stub.GetTxID())
+	require.Equal(t, stubtest.DefaultChannelID, stub.GetChannelID())
+
+	timestamp, err := stub.GetTxTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, stubtest.StartTime.Unix(), timestamp.Seconds)
+
+	stub.SetTransient(map[string][]byte{"asset_properties": []byte("{}")})
+	stub.Commit()
+	require.Equal(t, "tx2", stub.GetTxID())
+	timestamp, err = stub.GetTxTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, stubtest.StartTime.Add(time.Second).Unix(), timestamp.Seconds)
+	transient, err := stub.GetTransient()
+	require.NoError(t, err)
+	require.Empty(t, transient, "transient data belongs to a single transaction")
+
+	transfer := time.Date(2020, time.October, 1, 12, 0, 0, 0, time.UTC)
+	stub.SetTxID("transfer")
+	stub.SetTxTimestamp(transfer)
+	require.Equal(t, "transfer", stub.GetTxID())
+	timestamp, err = stub.GetTxTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, transfer.Unix(), timestamp.Seconds)
+
+	stub.SetArgs("TransferAsset", "asset1", "Max")
+	function, parameters := stub.GetFunctionAndParameters()
+	require.Equal(t, "TransferAsset", function)
+	require.Equal(t, []string{"asset1", "Max"}, parameters)
+	argsSlice, err := stub.GetArgsSlice()
+	require.NoError(t, err)
+	require.Equal(t, []byte("TransferAssetasset1Max"), argsSlice)
+
+	require.NoError(t, stub.SetCreator("Org1MSP", []byte("certificate")))
+	creator, err := stub.GetCreator()
+	require.NoError(t, err)
+	identity := &msp.SerializedIdentity{}
+	require.NoError(t, proto.Unmarshal(creator, identity))
+	require.Equal(t, "Org1MSP", identity.Mspid)
+	require.Equal(t, []byte("certificate"), identity.IdBytes)
+}
+
+func TestRangeQueries(t *testing.T) {
+	stub := stubtest.New()
+	for _, key := range []string{"asset3", "asset1", "asset10", "asset2", "car1"} {
+		require.NoError(t, stub.PutState(key, []byte(key)))
+	}
+	colorKey, err := stub.CreateCompositeKey("color~name", []string{"blue", "asset1"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(colorKey, []byte{0}))
+	stub.Commit()
+
+	iterator, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset10", "asset2", "asset3", "car1"}, keys(t, iterator), "composite keys are not in the simple key range")
+
+	iterator, err = stub.GetStateByRange("asset10", "asset3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset10", "asset2"}, keys(t, iterator))
+
+	_, err = stub.GetStateByRange(colorKey, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "contains a null character which is not allowed")
+
+	iterator, err = stub.GetStateByRange("asset", "asset1")
+	require.NoError(t, err)
+	require.False(t, iterator.HasNext())
+	_, err = iterator.Next()
+	require.EqualError(t, err, "no more results")
+}
+
+func TestCompositeKeys(t *testing.T) {
+	stub := stubtest.New()
+	for _, attributes := range [][]string{{"blue", "asset2"}, {"blue", "asset1"}, {"red", "asset3"}, {"bluegreen", "asset4"}} {
+		key, err := stub.CreateCompositeKey("color~name", attributes)
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState(key, []byte{0}))
+	}
+	stub.Commit()
+
+	iterator, err := stub.GetStateByPartialCompositeKey("color~name", []string{"blue"})
+	require.NoError(t, err)
+	var names []string
+	for _, key := range keys(t, iterator) {
+		objectType, attributes, err := stub.SplitCompositeKey(key)
+		require.NoError(t, err)
+		require.Equal(t, "color~name", objectType)
+		names = append(names, attributes[1])
+	}
+	require.Equal(t, []string{"asset1", "asset2"}, names)
+
+	iterator, err = stub.GetStateByPartialCompositeKey("color~name", nil)
+	require.NoError(t, err)
+	require.Len(t, keys(t, iterator), 4)
+
+	_, _, err = stub.SplitCompositeKey("asset1")
+	require.EqualError(t, err, `"asset1" is not a composite key`)
+}
+
+func TestPagination(t *testing.T) {
+	stub := stubtest.New()
+	for _, key := range []string{"asset1", "asset2", "asset3", "asset4", "asset5"} {
+		require.NoError(t, stub.PutState(key, []byte(key)))
+		ownerKey, err := stub.CreateCompositeKey("owner~name", []string{"Tomoko", key})
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState(ownerKey, []byte{0}))
+	}
+	stub.Commit()
+
+	var pages [][]string
+	bookmark := ""
+	for {
+		iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, bookmark)
+		require.NoError(t, err)
+		page := keys(t, iterator)
+		require.EqualValues(t, len(page), metadata.FetchedRecordsCount)
+		pages = append(pages, page)
+
+		bookmark = metadata.Bookmark
+		if bookmark == "" {
+			break
+		}
+	}
+	require.Equal(t, [][]string{{"asset1", "asset2"}, {"asset3", "asset4"}, {"asset5"}}, pages)
+
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination("owner~name", []string{"Tomoko"}, 4, "")
+	require.NoError(t, err)
+	require.Len(t, keys(t, iterator), 4)
+	iterator, _, err = stub.GetStateByPartialCompositeKeyWithPagination("owner~name", []string{"Tomoko"}, 4, metadata.Bookmark)
+	require.NoError(t, err)
+	require.Len(t, keys(t, iterator), 1)
+
+	_, _, err = stub.GetStateByRangeWithPagination("", "", 0, "")
+	require.EqualError(t, err, "page size must be greater than zero, got 0")
+}
+
+func TestPaginatedQueriesAndWritesAreExclusive(t *testing.T) {
+	stub := stubtest.New()
+	_, _, err := stub.GetStateByRangeWithPagination("", "", 10, "")
+	require.NoError(t, err)
+	require.EqualError(t, stub.PutState("asset1", []byte("blue")), "transaction tx1 has performed a paginated query, so it cannot write")
+
+	stub.Commit()
+	require.NoError(t, stub.PutState("asset1", []byte("blue")))
+	_, _, err = stub.GetStateByRangeWithPagination("", "", 10, "")
+	require.EqualError(t, err, "transaction tx2 has performed a write, so it cannot run paginated queries")
+}
+
+func TestPrivateData(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset1", []byte(`{"appraisedValue":100}`)))
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset2", []byte(`{"appraisedValue":200}`)))
+
+	value, err := stub.GetPrivateData("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+	stub.Commit()
+
+	value, err = stub.GetPrivateData("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"appraisedValue":100}`), value)
+
+	hash, err := stub.GetPrivateDataHash("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	expected := sha256.Sum256(value)
+	require.Equal(t, expected[:], hash)
+
+	value, err = stub.GetPrivateData("Org2MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "collections are separate")
+	hash, err = stub.GetPrivateDataHash("Org2MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, hash)
+
+	iterator, err := stub.GetPrivateDataByRange("Org1MSPPrivateCollection", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset2"}, keys(t, iterator))
+
+	value, err = stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "private data is not in the public state")
+
+	require.NoError(t, stub.DelPrivateData("Org1MSPPrivateCollection", "asset1"))
+	stub.Commit()
+	value, err = stub.GetPrivateData("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	_, err = stub.GetPrivateData("", "asset1")
+	require.EqualError(t, err, "collection must not be an empty string")
+}
+
+func TestHistory(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.PutState("asset1", []byte("Tomoko")))
+	stub.Commit()
+	require.NoError(t, stub.PutState("asset1", []byte("Max")))
+	stub.Commit()
+	require.NoError(t, stub.DelState("asset1"))
+	stub.Commit()
+
+	iterator, err := stub.GetHistoryForKey("asset1")
+	require.NoError(t, err)
+	defer iterator.Close()
+
+	var history []*queryresult.KeyModification
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		require.NoError(t, err)
+		history = append(history, modification)
+	}
+
+	at := func(seconds int) *timestamp.Timestamp {
+		ts, err := ptypes.TimestampProto(stubtest.StartTime.Add(time.Duration(seconds) * time.Second))
+		require.NoError(t, err)
+		return ts
+	}
+	require.Equal(t, []*queryresult.KeyModification{
+		{TxId: "tx3", Timestamp: at(2), IsDelete: true},
+		{TxId: "tx2", Value: []byte("Max"), Timestamp: at(1)},
+		{TxId: "tx1", Value: []byte("Tomoko"), Timestamp: at(0)},
+	}, history)
+}
+
+func TestEventsAndValidationParameters(t *testing.T) {
+	stub := stubtest.New()
+	require.NoError(t, stub.SetEvent("CreateAsset", []byte("asset1")))
+	require.NoError(t, stub.SetEvent("TransferAsset", []byte("asset1")))
+	require.NoError(t, stub.SetStateValidationParameter("asset1", []byte("policy")))
+	require.NoError(t, stub.SetPrivateDataValidationParameter("Org1MSPPrivateCollection", "asset1", []byte("org1")))
+	require.Empty(t, stub.Events())
+
+	stub.Commit()
+	require.Equal(t, []*pb.ChaincodeEvent{
+		{ChaincodeId: stubtest.DefaultChaincodeName, TxId: "tx1", EventName: "TransferAsset", Payload: []byte("asset1")},
+	}, stub.Events())
+
+	ep, err := stub.GetStateValidationParameter("asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("policy"), ep)
+	ep, err = stub.GetPrivateDataValidationParameter("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("org1"), ep)
+
+	require.EqualError(t, stub.SetEvent("", nil), "event name can not be empty string")
+}
+
+// ledgerChaincode writes its first argument to the key given by the second
+type ledgerChaincode struct{}
+
+func (ledgerChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (ledgerChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	args := stub.GetArgs()
+	if err := stub.PutState(string(args[1]), args[0]); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(stub.GetTxID()))
+}
+
+func TestInvokeChaincode(t *testing.T) {
+	stub := stubtest.New()
+	token := stub.RegisterChaincode("token", ledgerChaincode{})
+
+	response := stub.InvokeChaincode("token", [][]byte{[]byte("100"), []byte("balance")}, "")
+	require.EqualValues(t, shim.OK, response.Status)
+	require.Equal(t, []byte("tx1"), response.Payload, "the called chaincode runs in the same transaction")
+
+	stub.Commit()
+	value, err := token.GetState("balance")
+	require.NoError(t, err)
+	require.Equal(t, []byte("100"), value)
+	value, err = stub.GetState("balance")
+	require.NoError(t, err)
+	require.Nil(t, value, "each chaincode has its own namespace")
+
+	response = stub.InvokeChaincode("unknown", nil, "")
+	require.EqualValues(t, shim.ERROR, response.Status)
+	require.Equal(t, "chaincode unknown is not registered", response.Message)
+
+	response = stub.InvokeChaincode("token", nil, "otherchannel")
+	require.EqualValues(t, shim.ERROR, response.Status)
+}
@@ -7,18 +7,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/serverconfig"
 )
 
-type ServerConfig struct {
-	CCID    string
-	Address string
-}
-
 // SmartContract provides functions for managing a car
 type SmartContract struct {
 	contractapi.Contract
@@ -144,29 +141,28 @@ func (s *SmartContract) ChangeCarOwner(ctx contractapi.TransactionContextInterfa
 }
 
 func main() {
-	// See chaincode.env.example
-	config := ServerConfig{
-		CCID:    os.Getenv("CHAINCODE_ID"),
-		Address: os.Getenv("CHAINCODE_SERVER_ADDRESS"),
+	config, err := serverconfig.Load(os.Getenv)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		log.Fatalf("Error loading fabcar chaincode configuration: %s", err)
 	}
 
 	chaincode, err := contractapi.NewChaincode(new(SmartContract))
 
 	if err != nil {
-		fmt.Printf("Error create fabcar chaincode: %s", err.Error())
-		return
+		log.Fatalf("Error create fabcar chaincode: %s", err)
 	}
 
 	server := &shim.ChaincodeServer{
-		CCID:    config.CCID,
-		Address: config.Address,
-		CC:      chaincode,
-		TLSProps: shim.TLSProperties{
-			Disabled: true,
-		},
+		CCID:     config.CCID,
+		Address:  config.Address,
+		CC:       chaincode,
+		TLSProps: config.TLSProperties(),
 	}
 
 	if err := server.Start(); err != nil {
-		fmt.Printf("Error starting fabcar chaincode: %s", err.Error())
+		log.Fatalf("Error starting fabcar chaincode: %s", err)
 	}
 }
@@ -5,4 +5,7 @@ go 1.13
 require (
 	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
 	github.com/hyperledger/fabric-contract-api-go v1.1.0
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
 )
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../../chaincode-shared/go
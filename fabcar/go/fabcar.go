@@ -7,59 +7,28 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
-	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 
-	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
-	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"github.com/hyperledger/fabric-samples/test-application/go/gatewayutil"
 )
 
 func main() {
-	os.Setenv("DISCOVERY_AS_LOCALHOST", "true")
-	wallet, err := gateway.NewFileSystemWallet("wallet")
+	cfg, err := gatewayutil.Load(flag.CommandLine, os.Args[1:], gatewayutil.DefaultConfig("fabcar"))
 	if err != nil {
-		fmt.Printf("Failed to create wallet: %s\n", err)
+		fmt.Printf("Failed to load configuration: %s\n", err)
 		os.Exit(1)
 	}
 
-	if !wallet.Exists("appUser") {
-		err = populateWallet(wallet)
-		if err != nil {
-			fmt.Printf("Failed to populate wallet contents: %s\n", err)
-			os.Exit(1)
-		}
-	}
-
-	ccpPath := filepath.Join(
-		"..",
-		"..",
-		"test-network",
-		"organizations",
-		"peerOrganizations",
-		"org1.example.com",
-		"connection-org1.yaml",
-	)
-
-	gw, err := gateway.Connect(
-		gateway.WithConfig(config.FromFile(filepath.Clean(ccpPath))),
-		gateway.WithIdentity(wallet, "appUser"),
-	)
+	conn, err := gatewayutil.Connect(cfg)
 	if err != nil {
-		fmt.Printf("Failed to connect to gateway: %s\n", err)
+		fmt.Printf("Failed to connect: %s\n", err)
 		os.Exit(1)
 	}
-	defer gw.Close()
+	defer conn.Close()
 
-	network, err := gw.GetNetwork("mychannel")
-	if err != nil {
-		fmt.Printf("Failed to get network: %s\n", err)
-		os.Exit(1)
-	}
-
-	contract := network.GetContract("fabcar")
+	contract := conn.Contract
 
 	result, err := contract.EvaluateTransaction("queryAllCars")
 	if err != nil {
@@ -95,47 +64,3 @@ func main() {
 	}
 	fmt.Println(string(result))
 }
-
-func populateWallet(wallet *gateway.Wallet) error {
-	credPath := filepath.Join(
-		"..",
-		"..",
-		"test-network",
-		"organizations",
-		"peerOrganizations",
-		"org1.example.com",
-		"users",
-		"User1@org1.example.com",
-		"msp",
-	)
-
-	certPath := filepath.Join(credPath, "signcerts", "cert.pem")
-	// read the certificate pem
-	cert, err := ioutil.ReadFile(filepath.Clean(certPath))
-	if err != nil {
-		return err
-	}
-
-	keyDir := filepath.Join(credPath, "keystore")
-	// there's a single file in this dir containing the private key
-	files, err := ioutil.ReadDir(keyDir)
-	if err != nil {
-		return err
-	}
-	if len(files) != 1 {
-		return errors.New("keystore folder should have contain one file")
-	}
-	keyPath := filepath.Join(keyDir, files[0].Name())
-	key, err := ioutil.ReadFile(filepath.Clean(keyPath))
-	if err != nil {
-		return err
-	}
-
-	identity := gateway.NewX509Identity("Org1MSP", string(cert), string(key))
-
-	err = wallet.Put("appUser", identity)
-	if err != nil {
-		return err
-	}
-	return nil
-}
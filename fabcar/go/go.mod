@@ -2,4 +2,8 @@ module fabcar
 
 go 1.14
 
-require github.com/hyperledger/fabric-sdk-go v1.0.0-beta2
+require github.com/hyperledger/fabric-samples/test-application/go v0.0.0
+
+replace github.com/hyperledger/fabric-samples/test-application/go => ../../test-application/go
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -7,61 +7,76 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 
 	f "github.com/hyperledger/fabric-samples/high-throughput/application-go/functions"
+	"github.com/hyperledger/fabric-samples/test-application/go/gatewayutil"
 )
 
 func main() {
 
 	var function, variableName, change, sign string
 
-	if len(os.Args) <= 2 {
-		log.Println("Usage: function variableName")
+	cfg, err := gatewayutil.Load(flag.CommandLine, os.Args[1:], gatewayutil.DefaultConfig("bigdatacc"))
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	args := flag.Args()
+
+	if len(args) <= 1 {
+		log.Println("Usage: [flags] function variableName")
 		log.Fatalf("functions: update manyUpdates manyUpdatesTraditional get prune delete")
-	} else if (os.Args[1] == "update" || os.Args[1] == "manyUpdates" || os.Args[1] == "manyUpdatesTraditional") && len(os.Args) < 5 {
+	} else if (args[0] == "update" || args[0] == "manyUpdates" || args[0] == "manyUpdatesTraditional") && len(args) < 4 {
 		log.Fatalf("error: provide value and operation")
-	} else if len(os.Args) == 3 {
-		function = os.Args[1]
-		variableName = os.Args[2]
-	} else if len(os.Args) == 5 {
-		function = os.Args[1]
-		variableName = os.Args[2]
-		change = os.Args[3]
-		sign = os.Args[4]
+	} else if len(args) == 2 {
+		function = args[0]
+		variableName = args[1]
+	} else if len(args) == 4 {
+		function = args[0]
+		variableName = args[1]
+		change = args[2]
+		sign = args[3]
+	}
+
+	conn, err := gatewayutil.Connect(cfg)
+	if err != nil {
+		log.Fatalf("error: %v", err)
 	}
+	defer conn.Close()
+	contract := conn.Contract
 
 	// Handle different functions
 	if function == "update" {
-		result, err := f.Update(function, variableName, change, sign)
+		result, err := f.Update(contract, function, variableName, change, sign)
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
 		log.Println("Value of variable", string(variableName), ": ", string(result))
 
 	} else if function == "delete" || function == "prune" || function == "delstandard" {
-		result, err := f.DeletePrune(function, variableName)
+		result, err := f.DeletePrune(contract, function, variableName)
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
 		log.Println(string(result))
 	} else if function == "get" || function == "getstandard" {
-		result, err := f.Query(function, variableName)
+		result, err := f.Query(contract, function, variableName)
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
 		log.Println("Value of variable", string(variableName), ": ", string(result))
 	} else if function == "manyUpdates" {
 		log.Println("submitting 1000 concurrent updates...")
-		result, err := f.ManyUpdates("update", variableName, change, sign)
+		result, err := f.ManyUpdates(contract, "update", variableName, change, sign)
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
 		log.Println("Final value of variable", string(variableName), ": ", string(result))
 	} else if function == "manyUpdatesTraditional" {
 		log.Println("submitting 1000 concurrent updates...")
-		result, err := f.ManyUpdates("putstandard", variableName, change, sign)
+		result, err := f.ManyUpdates(contract, "putstandard", variableName, change, sign)
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
@@ -2,4 +2,11 @@ module github.com/hyperledger/fabric-samples/high-throughput/application-go
 
 go 1.14
 
-require github.com/hyperledger/fabric-sdk-go v1.0.0-beta3.0.20201006151309-9c426dcc5096
+require (
+	github.com/hyperledger/fabric-samples/test-application/go v0.0.0
+	github.com/hyperledger/fabric-sdk-go v1.0.0-beta3.0.20201006151309-9c426dcc5096
+)
+
+replace github.com/hyperledger/fabric-samples/test-application/go => ../../test-application/go
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -0,0 +1,73 @@
+/*
+ * Copyright IBM Corp All Rights Reserved
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"testing"
+
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/stretchr/testify/require"
+)
+
+// submitMany endorses count copies of an invocation in blocks of ten, as when
+// many clients submit them at the same time
+func submitMany(stub *stubtest.ChaincodeStub, count int, args ...string) *stubtest.SimulationResult {
+	invocations := make([]stubtest.Invocation, count)
+	for i := range invocations {
+		invocations[i] = stubtest.Invoke(&SmartContract{}, args...)
+	}
+
+	simulator := &stubtest.Simulator{Stub: stub, BlockSize: 10}
+	return simulator.Run(invocations...)
+}
+
+func TestUpdateDoesNotConflict(t *testing.T) {
+	stub := stubtest.New()
+
+	result := submitMany(stub, 100, "update", "testvar1", "100", "+")
+	require.Zero(t, result.EndorsementFailures)
+	require.Zero(t, result.ConflictRate())
+
+	response := (&SmartContract{}).Invoke(withArgs(stub, "get", "testvar1"))
+	require.EqualValues(t, OK, response.Status, response.Message)
+	require.Equal(t, "10000", string(response.Payload))
+}
+
+func TestPutStandardConflicts(t *testing.T) {
+	stub := stubtest.New()
+
+	result := submitMany(stub, 100, "putstandard", "testvar2", "100")
+	require.Zero(t, result.EndorsementFailures)
+	require.Equal(t, 10, result.Count(pb.TxValidationCode_VALID), "only the first update of the key in each block is valid")
+	require.Equal(t, 90, result.Count(pb.TxValidationCode_MVCC_READ_CONFLICT))
+	require.InDelta(t, 0.9, result.ConflictRate(), 0.0001)
+}
+
+func TestPruneConflictsWithConcurrentUpdates(t *testing.T) {
+	stub := stubtest.New()
+	submitMany(stub, 5, "update", "testvar3", "1", "+")
+
+	contract := &SmartContract{}
+	require.NoError(t, stubtest.Invoke(contract, "update", "testvar3", "1", "+")(stub))
+	update := stub.Endorse()
+	require.NoError(t, stubtest.Invoke(contract, "prune", "testvar3")(stub))
+	prune := stub.Endorse()
+
+	codes := stub.CommitBlock(update, prune)
+	require.Equal(t, []pb.TxValidationCode{pb.TxValidationCode_VALID, pb.TxValidationCode_PHANTOM_READ_CONFLICT}, codes,
+		"prune must not delete a delta it did not read")
+
+	response := contract.Invoke(withArgs(stub, "get", "testvar3"))
+	require.EqualValues(t, OK, response.Status, response.Message)
+	require.Equal(t, "6", string(response.Payload))
+}
+
+func withArgs(stub *stubtest.ChaincodeStub, args ...string) *stubtest.ChaincodeStub {
+	stub.SetArgs(args...)
+	return stub
+}
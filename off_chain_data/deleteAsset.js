@@ -9,7 +9,7 @@
  *
  * deleteAsset.js will delete a specified asset. Example:
  *
- *    $ node deleteAsset.js asset100
+ *    $ node deleteAsset.js asset100 [reason]
  *
  *  The utility is meant to demonstrate delete block events.
  */
@@ -26,11 +26,12 @@ const channelid = config.channelid;
 async function main() {
 
     if (process.argv[2] == undefined) {
-        console.log("Usage: node deleteAsset AssetId");
+        console.log("Usage: node deleteAsset AssetId [Reason]");
         process.exit(1);
     }
 
     const deletekey = process.argv[2];
+    const reason = process.argv[3] || '';
 
     try {
 
@@ -54,7 +55,7 @@ async function main() {
         // Get the smart contract from the network channel.
         const contract = network.getContract('basic');
 
-        await contract.submitTransaction('DeleteAsset', deletekey);
+        await contract.submitTransaction('DeleteAsset', deletekey, reason);
         console.log("Deleted asset: " + deletekey);
 
         await gateway.disconnect();
@@ -0,0 +1,170 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gatewayutil holds the gateway setup shared by the Go sample
+// applications: locating the connection profile and user credentials,
+// populating the wallet and connecting to a contract.
+package gatewayutil
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileEnv names the environment variable that points at a YAML
+// configuration file when the -config flag is not given.
+const ConfigFileEnv = "FABRIC_APP_CONFIG"
+
+// Config describes how an application connects to a contract
+type Config struct {
+	ConnectionProfile string `yaml:"connectionProfile"`
+	MSPDir            string `yaml:"mspDir"`
+	MSPID             string `yaml:"mspID"`
+	Wallet            string `yaml:"wallet"`
+	Identity          string `yaml:"identity"`
+	Channel           string `yaml:"channel"`
+	Chaincode         string `yaml:"chaincode"`
+	AsLocalhost       bool   `yaml:"asLocalhost"`
+}
+
+// DefaultConfig returns the configuration for User1 of Org1 on the test
+// network, as seen from a sample application two directories below the
+// repository root.
+func DefaultConfig(chaincode string) Config {
+	org1 := filepath.Join("..", "..", "test-network", "organizations", "peerOrganizations", "org1.example.com")
+
+	return Config{
+		ConnectionProfile: filepath.Join(org1, "connection-org1.yaml"),
+		MSPDir:            filepath.Join(org1, "users", "User1@org1.example.com", "msp"),
+		MSPID:             "Org1MSP",
+		Wallet:            "wallet",
+		Identity:          "appUser",
+		Channel:           "mychannel",
+		Chaincode:         chaincode,
+		AsLocalhost:       true,
+	}
+}
+
+// setting is a configuration value that can be given as a flag or an
+// environment variable
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value *string
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"connection-profile", "FABRIC_CONNECTION_PROFILE", "path to the connection profile", &c.ConnectionProfile},
+		{"msp-dir", "FABRIC_MSP_DIR", "MSP directory holding the identity's signcerts and keystore", &c.MSPDir},
+		{"msp-id", "FABRIC_MSP_ID", "MSP ID of the identity's organization", &c.MSPID},
+		{"wallet", "FABRIC_WALLET", "wallet directory", &c.Wallet},
+		{"identity", "FABRIC_IDENTITY", "label of the identity in the wallet", &c.Identity},
+		{"channel", "FABRIC_CHANNEL", "channel name", &c.Channel},
+		{"chaincode", "FABRIC_CHAINCODE", "chaincode name", &c.Chaincode},
+	}
+}
+
+// Load builds a configuration from, in increasing order of precedence, the
+// given defaults, the YAML file named by the -config flag or FABRIC_APP_CONFIG,
+// FABRIC_* environment variables and command line flags. The flags are
+// registered on fs and parsed from args; remaining arguments are left in fs.Args().
+func Load(fs *flag.FlagSet, args []string, defaults Config) (*Config, error) {
+	flagged := defaults
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "YAML configuration file")
+	for _, s := range flagged.settings() {
+		fs.StringVar(s.value, s.flag, *s.value, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	fs.BoolVar(&flagged.AsLocalhost, "as-localhost", flagged.AsLocalhost, "map discovered peer addresses to localhost (env DISCOVERY_AS_LOCALHOST)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaults
+	if *configFile != "" {
+		err = config.LoadFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = config.LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	flaggedSettings := make(map[string]string)
+	for _, s := range flagged.settings() {
+		flaggedSettings[s.flag] = *s.value
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range config.settings() {
+			if s.flag == f.Name {
+				*s.value = flaggedSettings[f.Name]
+			}
+		}
+		if f.Name == "as-localhost" {
+			config.AsLocalhost = flagged.AsLocalhost
+		}
+	})
+
+	return &config, config.Validate()
+}
+
+// LoadFile overrides the configuration with the values set in a YAML file
+func (c *Config) LoadFile(path string) error {
+	contents, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %v", err)
+	}
+
+	err = yaml.UnmarshalStrict(contents, c)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// LoadEnv overrides the configuration with the FABRIC_* environment variables
+// that are set, and with DISCOVERY_AS_LOCALHOST
+func (c *Config) LoadEnv() error {
+	for _, s := range c.settings() {
+		if value, ok := os.LookupEnv(s.env); ok {
+			*s.value = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("DISCOVERY_AS_LOCALHOST"); ok {
+		asLocalhost, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid DISCOVERY_AS_LOCALHOST value %q: %v", value, err)
+		}
+		c.AsLocalhost = asLocalhost
+	}
+
+	return nil
+}
+
+// Validate checks that every setting needed to connect has a value
+func (c *Config) Validate() error {
+	for _, s := range c.settings() {
+		if *s.value == "" {
+			return fmt.Errorf("%s must be set with -%s or %s", s.usage, s.flag, s.env)
+		}
+	}
+
+	return nil
+}
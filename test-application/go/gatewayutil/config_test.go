@@ -0,0 +1,69 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewayutil_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/test-application/go/gatewayutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config, err := gatewayutil.Load(fs, []string{"get", "myvar"}, gatewayutil.DefaultConfig("basic"))
+	require.NoError(t, err)
+	require.Equal(t, gatewayutil.DefaultConfig("basic"), *config)
+	require.Equal(t, []string{"get", "myvar"}, fs.Args())
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gatewayutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(configFile, []byte("channel: filechannel\nchaincode: filecc\nidentity: fileuser\n"), 0600)
+	require.NoError(t, err)
+
+	os.Setenv("FABRIC_CHAINCODE", "envcc")
+	os.Setenv("FABRIC_IDENTITY", "envuser")
+	defer os.Unsetenv("FABRIC_CHAINCODE")
+	defer os.Unsetenv("FABRIC_IDENTITY")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config, err := gatewayutil.Load(fs, []string{"-config", configFile, "-identity", "flaguser", "-as-localhost=false"}, gatewayutil.DefaultConfig("basic"))
+	require.NoError(t, err)
+	require.Equal(t, "filechannel", config.Channel)
+	require.Equal(t, "envcc", config.Chaincode)
+	require.Equal(t, "flaguser", config.Identity)
+	require.Equal(t, "Org1MSP", config.MSPID)
+	require.False(t, config.AsLocalhost)
+}
+
+func TestLoadErrors(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err := gatewayutil.Load(fs, []string{"-chaincode", ""}, gatewayutil.DefaultConfig("basic"))
+	require.EqualError(t, err, "chaincode name must be set with -chaincode or FABRIC_CHAINCODE")
+
+	dir, err := ioutil.TempDir("", "gatewayutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(configFile, []byte("channels: typo\n"), 0600)
+	require.NoError(t, err)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err = gatewayutil.Load(fs, []string{"-config", configFile}, gatewayutil.DefaultConfig("basic"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse configuration file")
+}
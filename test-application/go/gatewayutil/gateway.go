@@ -0,0 +1,102 @@
+/*
+Copyright 2020 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewayutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// Connection is an open gateway connection to a contract
+type Connection struct {
+	Gateway  *gateway.Gateway
+	Network  *gateway.Network
+	Contract *gateway.Contract
+}
+
+// Close disconnects from the gateway
+func (c *Connection) Close() {
+	c.Gateway.Close()
+}
+
+// Connect populates the wallet from the configured MSP directory if the
+// identity is not there yet, connects to the gateway and returns the
+// configured contract.
+func Connect(cfg *Config) (*Connection, error) {
+	err := os.Setenv("DISCOVERY_AS_LOCALHOST", strconv.FormatBool(cfg.AsLocalhost))
+	if err != nil {
+		return nil, fmt.Errorf("error setting DISCOVERY_AS_LOCALHOST environment variable: %v", err)
+	}
+
+	wallet, err := gateway.NewFileSystemWallet(cfg.Wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %v", err)
+	}
+
+	if !wallet.Exists(cfg.Identity) {
+		err = PopulateWallet(wallet, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to populate wallet contents: %v", err)
+		}
+	}
+
+	gw, err := gateway.Connect(
+		gateway.WithConfig(config.FromFile(filepath.Clean(cfg.ConnectionProfile))),
+		gateway.WithIdentity(wallet, cfg.Identity),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gateway: %v", err)
+	}
+
+	network, err := gw.GetNetwork(cfg.Channel)
+	if err != nil {
+		gw.Close()
+		return nil, fmt.Errorf("failed to get network: %v", err)
+	}
+
+	return &Connection{
+		Gateway:  gw,
+		Network:  network,
+		Contract: network.GetContract(cfg.Chaincode),
+	}, nil
+}
+
+// PopulateWallet stores the X.509 identity found in the configured MSP
+// directory in the wallet under the configured identity label.
+func PopulateWallet(wallet *gateway.Wallet, cfg *Config) error {
+	certPath := filepath.Join(cfg.MSPDir, "signcerts", "cert.pem")
+	// read the certificate pem
+	cert, err := ioutil.ReadFile(filepath.Clean(certPath))
+	if err != nil {
+		return err
+	}
+
+	keyDir := filepath.Join(cfg.MSPDir, "keystore")
+	// there's a single file in this dir containing the private key
+	files, err := ioutil.ReadDir(keyDir)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("keystore folder should have contain one file")
+	}
+	keyPath := filepath.Join(keyDir, files[0].Name())
+	key, err := ioutil.ReadFile(filepath.Clean(keyPath))
+	if err != nil {
+		return err
+	}
+
+	identity := gateway.NewX509Identity(cfg.MSPID, string(cert), string(key))
+
+	return wallet.Put(cfg.Identity, identity)
+}
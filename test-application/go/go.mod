@@ -0,0 +1,12 @@
+module github.com/hyperledger/fabric-samples/test-application/go
+
+go 1.14
+
+require (
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
+	github.com/hyperledger/fabric-sdk-go v1.0.0-beta3.0.20201006151309-9c426dcc5096
+	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.3.0
+)
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -0,0 +1,89 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger/fabric-samples/test-application/go/gatewayutil"
+	"github.com/hyperledger/fabric-samples/test-application/go/restgateway"
+)
+
+func main() {
+	listen := flag.String("listen", "localhost:8080", "address to serve the REST API on; use :8080 to accept connections from other hosts")
+	printOpenAPI := flag.Bool("openapi", false, "print the OpenAPI document and exit")
+	metadataFile := flag.String("metadata", "", "read the contract metadata from a file instead of the chaincode")
+
+	defaults := gatewayutil.DefaultConfig("basic")
+	// the server is one directory deeper than the sample applications
+	defaults.ConnectionProfile = filepath.Join("..", defaults.ConnectionProfile)
+	defaults.MSPDir = filepath.Join("..", defaults.MSPDir)
+
+	cfg, err := gatewayutil.Load(flag.CommandLine, os.Args[1:], defaults)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	var metadata *restgateway.Metadata
+	if *metadataFile != "" {
+		data, err := ioutil.ReadFile(filepath.Clean(*metadataFile))
+		if err != nil {
+			log.Fatalf("failed to read metadata file: %v", err)
+		}
+		metadata, err = restgateway.ParseMetadata(data)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+
+	if *printOpenAPI && metadata != nil {
+		writeOpenAPI(metadata)
+		return
+	}
+
+	conn, err := gatewayutil.Connect(cfg)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	defer conn.Close()
+	contract := restgateway.GatewayContract{Contract: conn.Contract}
+
+	if metadata == nil {
+		metadata, err = restgateway.LoadMetadata(contract)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+
+	if *printOpenAPI {
+		writeOpenAPI(metadata)
+		return
+	}
+
+	server, err := restgateway.NewServer(contract, metadata)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	log.Printf("Serving chaincode %s on channel %s at %s", cfg.Chaincode, cfg.Channel, *listen)
+	log.Printf("The OpenAPI document is at %s", restgateway.OpenAPIPath)
+	if err := http.ListenAndServe(*listen, server); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+}
+
+func writeOpenAPI(metadata *restgateway.Metadata) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(restgateway.NewDocument(metadata)); err != nil {
+		log.Fatalf("failed to write OpenAPI document: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MetadataFunction is the system function that returns the metadata of a
+// contractapi chaincode
+const MetadataFunction = "org.hyperledger.fabric:GetMetadata"
+
+// systemContract is the contract that contractapi adds to every chaincode
+const systemContract = "org.hyperledger.fabric"
+
+// Schema is a JSON schema
+type Schema map[string]interface{}
+
+// Metadata is the contract metadata returned by MetadataFunction
+type Metadata struct {
+	Info       *Info                       `json:"info,omitempty"`
+	Contracts  map[string]ContractMetadata `json:"contracts"`
+	Components struct {
+		Schemas map[string]Schema `json:"schemas,omitempty"`
+	} `json:"components"`
+}
+
+// Info describes a chaincode or a contract
+type Info struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+}
+
+// ContractMetadata describes a contract in the chaincode
+type ContractMetadata struct {
+	Info         *Info         `json:"info,omitempty"`
+	Name         string        `json:"name"`
+	Transactions []Transaction `json:"transactions"`
+	Default      bool          `json:"default"`
+}
+
+// Transaction is a transaction function of a contract
+type Transaction struct {
+	Name       string      `json:"name"`
+	Tag        []string    `json:"tag,omitempty"`
+	Parameters []Parameter `json:"parameters,omitempty"`
+	Returns    Schema      `json:"returns,omitempty"`
+}
+
+// Parameter is a positional argument of a transaction function
+type Parameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// Function is a transaction function with the name a client invokes it by,
+// which is qualified with the contract name unless the contract is the default
+type Function struct {
+	Name     string
+	Contract string
+	Transaction
+}
+
+// ParseMetadata parses the JSON returned by MetadataFunction
+func ParseMetadata(data []byte) (*Metadata, error) {
+	var metadata Metadata
+	err := json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse contract metadata: %v", err)
+	}
+	if len(metadata.Contracts) == 0 {
+		return nil, fmt.Errorf("contract metadata does not contain any contracts")
+	}
+	return &metadata, nil
+}
+
+// Functions returns the transaction functions of every contract except the
+// system contract, ordered by name
+func (m *Metadata) Functions() []Function {
+	var functions []Function
+	for name, contract := range m.Contracts {
+		if name == systemContract {
+			continue
+		}
+		for _, tx := range contract.Transactions {
+			fn := Function{Name: name + ":" + tx.Name, Contract: name, Transaction: tx}
+			if contract.Default {
+				fn.Name = tx.Name
+			}
+			functions = append(functions, fn)
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+// Function returns the transaction function invoked by name
+func (m *Metadata) Function(name string) (Function, bool) {
+	for _, fn := range m.Functions() {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+	return Function{}, false
+}
+
+// IsEvaluate returns whether the contract marks the function as a query that
+// clients should evaluate rather than submit
+func (t Transaction) IsEvaluate() bool {
+	for _, tag := range t.Tag {
+		if strings.EqualFold(tag, "evaluate") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnsString returns whether the function returns a plain string, which the
+// chaincode sends without JSON encoding
+func (t Transaction) ReturnsString() bool {
+	return t.Returns["type"] == "string"
+}
@@ -0,0 +1,298 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restgateway
+
+import (
+	"strings"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification of the generated
+// documents
+const OpenAPIVersion = "3.0.3"
+
+// schemaPrefix is where the contract's component schemas are referenced from
+const schemaPrefix = "#/components/schemas/"
+
+// errorSchema is the component schema of error responses, named so that it
+// does not clash with the schemas of the contract
+const errorSchema = "GatewayError"
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Components holds the schemas referenced by a Document
+type Components struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// PathItem holds the operations on a path
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+// Operation is an HTTP operation that invokes a transaction function
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []OperationParameter `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// OperationParameter is a query string parameter of an operation. Parameters
+// with structured values are sent as JSON, and have Content instead of Schema.
+type OperationParameter struct {
+	Name        string               `json:"name"`
+	In          string               `json:"in"`
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Schema      Schema               `json:"schema,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// RequestBody is the body of an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a request or response in one encoding
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// NewDocument returns the OpenAPI document of the REST gateway serving a
+// chaincode. Every transaction function can be submitted with POST
+// /transactions/{fn} and evaluated with GET /query/{fn}.
+func NewDocument(metadata *Metadata) *Document {
+	doc := &Document{
+		OpenAPI: OpenAPIVersion,
+		Info:    metadata.info(),
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: map[string]Schema{
+				errorSchema: {
+					"type": "object",
+					"properties": map[string]interface{}{
+						"error": map[string]interface{}{"type": "string"},
+						"code":  map[string]interface{}{"type": "string"},
+					},
+					"required": []interface{}{"error"},
+				},
+			},
+		},
+	}
+
+	for name, schema := range metadata.Components.Schemas {
+		schema = normalize(schema).(map[string]interface{})
+		delete(schema, "$id")
+		schema["type"] = "object"
+		doc.Components.Schemas[name] = schema
+	}
+
+	for _, fn := range metadata.Functions() {
+		doc.Paths[TransactionsPath+fn.Name] = &PathItem{Post: submitOperation(fn)}
+		doc.Paths[QueryPath+fn.Name] = &PathItem{Get: evaluateOperation(fn)}
+	}
+
+	return doc
+}
+
+// info returns the chaincode info, falling back to the name of the default
+// contract when contractapi did not set a title
+func (m *Metadata) info() Info {
+	info := Info{Version: "latest"}
+	if m.Info != nil {
+		info = *m.Info
+	}
+	if info.Title == "" || info.Title == "undefined" {
+		info.Title = "chaincode"
+		for name, contract := range m.Contracts {
+			if contract.Default {
+				info.Title = name
+			}
+		}
+	}
+	return info
+}
+
+func submitOperation(fn Function) *Operation {
+	arguments := Schema{"type": "object", "properties": map[string]interface{}{}}
+	var required []interface{}
+	for _, param := range fn.Parameters {
+		arguments["properties"].(map[string]interface{})[param.Name] = normalize(param.Schema)
+		required = append(required, param.Name)
+	}
+	if len(required) > 0 {
+		arguments["required"] = required
+	}
+
+	body := Schema{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"arguments": map[string]interface{}(arguments),
+			"transient": map[string]interface{}{
+				"type":                 "object",
+				"description":          "transient data, where strings are sent as is and other values as JSON",
+				"additionalProperties": map[string]interface{}{},
+			},
+		},
+	}
+	if len(required) > 0 {
+		body["required"] = []interface{}{"arguments"}
+	}
+
+	return &Operation{
+		OperationID: "submit" + operationName(fn.Name),
+		Summary:     "Submit " + fn.Name,
+		Tags:        []string{fn.Contract},
+		RequestBody: &RequestBody{
+			Required: len(required) > 0,
+			Content:  map[string]MediaType{"application/json": {Schema: body}},
+		},
+		Responses: responses(fn),
+	}
+}
+
+func evaluateOperation(fn Function) *Operation {
+	op := &Operation{
+		OperationID: "evaluate" + operationName(fn.Name),
+		Summary:     "Evaluate " + fn.Name,
+		Tags:        []string{fn.Contract},
+		Responses:   responses(fn),
+	}
+
+	for _, param := range fn.Parameters {
+		schema := normalize(param.Schema).(map[string]interface{})
+		p := OperationParameter{Name: param.Name, In: "query", Description: param.Description, Required: true}
+		if isScalar(schema) {
+			p.Schema = schema
+		} else {
+			p.Content = map[string]MediaType{"application/json": {Schema: schema}}
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+
+	return op
+}
+
+func responses(fn Function) map[string]*Response {
+	responses := map[string]*Response{
+		"default": {
+			Description: "the transaction failed",
+			Content: map[string]MediaType{
+				"application/json": {Schema: Schema{"$ref": schemaPrefix + errorSchema}},
+			},
+		},
+	}
+	if fn.Returns == nil {
+		responses["204"] = &Response{Description: "the transaction succeeded"}
+	} else {
+		responses["200"] = &Response{
+			Description: "the result of the transaction",
+			Content: map[string]MediaType{
+				"application/json": {Schema: normalize(fn.Returns).(map[string]interface{})},
+			},
+		}
+	}
+	return responses
+}
+
+// operationName turns a function name into the suffix of an operation ID
+func operationName(name string) string {
+	parts := strings.Split(name, ":")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func isScalar(schema map[string]interface{}) bool {
+	switch schema["type"] {
+	case "string", "integer", "number", "boolean":
+		return true
+	}
+	return false
+}
+
+// normalize returns a copy of a contractapi schema that is valid in an OpenAPI
+// document. contractapi references component schemas by their bare name, and
+// names properties after the whole json struct tag, such as "owner,omitempty".
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Schema:
+		return normalize(map[string]interface{}(v))
+	case map[string]interface{}:
+		schema := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			schema[key] = normalize(child)
+		}
+
+		if ref, ok := schema["$ref"].(string); ok && !strings.HasPrefix(ref, "#") {
+			schema["$ref"] = schemaPrefix + ref
+		}
+
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			optional := make(map[string]bool)
+			renamed := make(map[string]interface{}, len(properties))
+			for tag, property := range properties {
+				name, options := splitTag(tag)
+				renamed[name] = property
+				if strings.Contains(options, "omitempty") {
+					optional[tag] = true
+				}
+			}
+			schema["properties"] = renamed
+
+			if required, ok := schema["required"].([]interface{}); ok {
+				var names []interface{}
+				for _, tag := range required {
+					tag, _ := tag.(string)
+					if !optional[tag] {
+						name, _ := splitTag(tag)
+						names = append(names, name)
+					}
+				}
+				if len(names) == 0 {
+					delete(schema, "required")
+				} else {
+					schema["required"] = names
+				}
+			}
+		}
+		return schema
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, child := range v {
+			values[i] = normalize(child)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+// splitTag splits a json struct tag into the property name and its options
+func splitTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
@@ -0,0 +1,165 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restgateway_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/test-application/go/restgateway"
+	"github.com/stretchr/testify/require"
+)
+
+// metadata is trimmed from the metadata of asset-transfer-basic, with a
+// second contract added
+const metadata = `{
+	"info": {"title": "undefined", "version": "latest"},
+	"contracts": {
+		"SmartContract": {
+			"name": "SmartContract",
+			"default": true,
+			"transactions": [
+				{"name": "AssetExists", "tag": ["submit"], "parameters": [{"name": "param0", "schema": {"type": "string"}}], "returns": {"type": "boolean"}},
+				{"name": "CreateAsset", "tag": ["submit"], "parameters": [
+					{"name": "param0", "schema": {"type": "string"}},
+					{"name": "param1", "schema": {"type": "integer", "format": "int64"}},
+					{"name": "param2", "schema": {"$ref": "#/components/schemas/Reservation"}}
+				]},
+				{"name": "GetAllAssets", "tag": ["evaluate"], "returns": {"type": "array", "items": {"$ref": "#/components/schemas/Asset"}}},
+				{"name": "ReadAsset", "tag": ["submit"], "parameters": [{"name": "param0", "schema": {"type": "string"}}], "returns": {"$ref": "#/components/schemas/Asset"}},
+				{"name": "Version", "tag": ["submit"], "returns": {"type": "string"}}
+			]
+		},
+		"Admin": {
+			"name": "Admin",
+			"default": false,
+			"transactions": [
+				{"name": "Pause", "tag": ["submit"], "parameters": [{"name": "param0", "schema": {"type": "boolean"}}]}
+			]
+		},
+		"org.hyperledger.fabric": {
+			"name": "org.hyperledger.fabric",
+			"default": false,
+			"transactions": [{"name": "GetMetadata", "tag": ["evaluate"], "returns": {"type": "string"}}]
+		}
+	},
+	"components": {
+		"schemas": {
+			"Asset": {
+				"$id": "Asset",
+				"properties": {
+					"ID": {"type": "string"},
+					"size": {"type": "integer", "format": "int64"},
+					"reservation,omitempty": {"$ref": "Reservation"}
+				},
+				"required": ["ID", "size", "reservation,omitempty"],
+				"additionalProperties": false
+			},
+			"Reservation": {
+				"$id": "Reservation",
+				"properties": {"holder": {"type": "string"}},
+				"required": ["holder"],
+				"additionalProperties": false
+			}
+		}
+	}
+}`
+
+func parseMetadata(t *testing.T) *restgateway.Metadata {
+	m, err := restgateway.ParseMetadata([]byte(metadata))
+	require.NoError(t, err)
+	return m
+}
+
+// toJSON round trips a value through JSON to compare it with a literal
+func toJSON(t *testing.T, value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func fromJSON(t *testing.T, literal string) interface{} {
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal([]byte(literal), &decoded))
+	return decoded
+}
+
+func TestParseMetadata(t *testing.T) {
+	m := parseMetadata(t)
+
+	var names []string
+	for _, fn := range m.Functions() {
+		names = append(names, fn.Name)
+	}
+	require.Equal(t, []string{"Admin:Pause", "AssetExists", "CreateAsset", "GetAllAssets", "ReadAsset", "Version"}, names)
+
+	fn, ok := m.Function("GetAllAssets")
+	require.True(t, ok)
+	require.True(t, fn.IsEvaluate())
+	require.Equal(t, "SmartContract", fn.Contract)
+
+	_, ok = m.Function("org.hyperledger.fabric:GetMetadata")
+	require.False(t, ok, "the system contract is not exposed")
+	_, ok = m.Function("Pause")
+	require.False(t, ok, "functions of other contracts are qualified")
+
+	_, err := restgateway.ParseMetadata([]byte(`{"contracts": {}}`))
+	require.EqualError(t, err, "contract metadata does not contain any contracts")
+	_, err = restgateway.ParseMetadata([]byte(`[]`))
+	require.Error(t, err)
+}
+
+func TestNewDocument(t *testing.T) {
+	doc := restgateway.NewDocument(parseMetadata(t))
+
+	require.Equal(t, restgateway.OpenAPIVersion, doc.OpenAPI)
+	require.Equal(t, "SmartContract", doc.Info.Title)
+	require.Len(t, doc.Paths, 12)
+
+	require.Equal(t, fromJSON(t, `{
+		"type": "object",
+		"properties": {
+			"ID": {"type": "string"},
+			"size": {"type": "integer", "format": "int64"},
+			"reservation": {"$ref": "#/components/schemas/Reservation"}
+		},
+		"required": ["ID", "size"],
+		"additionalProperties": false
+	}`), toJSON(t, doc.Components.Schemas["Asset"]))
+	require.Contains(t, doc.Components.Schemas, "GatewayError")
+
+	create := doc.Paths["/transactions/CreateAsset"].Post
+	require.Equal(t, "submitCreateAsset", create.OperationID)
+	require.True(t, create.RequestBody.Required)
+	require.Equal(t, fromJSON(t, `{
+		"type": "object",
+		"properties": {
+			"param0": {"type": "string"},
+			"param1": {"type": "integer", "format": "int64"},
+			"param2": {"$ref": "#/components/schemas/Reservation"}
+		},
+		"required": ["param0", "param1", "param2"]
+	}`), toJSON(t, create.RequestBody.Content["application/json"].Schema["properties"].(map[string]interface{})["arguments"]))
+	require.Contains(t, create.Responses, "204")
+	require.NotContains(t, create.Responses, "200")
+
+	query := doc.Paths["/query/CreateAsset"].Get
+	require.Equal(t, "evaluateCreateAsset", query.OperationID)
+	require.Equal(t, fromJSON(t, `[
+		{"name": "param0", "in": "query", "required": true, "schema": {"type": "string"}},
+		{"name": "param1", "in": "query", "required": true, "schema": {"type": "integer", "format": "int64"}},
+		{"name": "param2", "in": "query", "required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Reservation"}}}}
+	]`), toJSON(t, query.Parameters))
+
+	read := doc.Paths["/query/ReadAsset"].Get
+	require.Equal(t, fromJSON(t, `{"$ref": "#/components/schemas/Asset"}`), toJSON(t, read.Responses["200"].Content["application/json"].Schema))
+
+	pause := doc.Paths["/transactions/Admin:Pause"].Post
+	require.Equal(t, "submitAdminPause", pause.OperationID)
+	require.Equal(t, []string{"Admin"}, pause.Tags)
+	require.NotContains(t, doc.Paths, "/query/org.hyperledger.fabric:GetMetadata")
+}
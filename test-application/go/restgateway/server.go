@@ -0,0 +1,296 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package restgateway exposes the transaction functions of a contractapi
+// chaincode over HTTP. It reads the contract metadata, describes the API in an
+// OpenAPI 3 document and maps POST /transactions/{fn} to submit and GET
+// /query/{fn} to evaluate.
+package restgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// Paths served by a Server
+const (
+	TransactionsPath = "/transactions/"
+	QueryPath        = "/query/"
+	OpenAPIPath      = "/openapi.json"
+)
+
+// maxRequestBytes is the largest submit request body a Server reads
+const maxRequestBytes = 1 << 20
+
+// Contract submits and evaluates transaction functions
+type Contract interface {
+	Submit(name string, transient map[string][]byte, args ...string) ([]byte, error)
+	Evaluate(name string, transient map[string][]byte, args ...string) ([]byte, error)
+}
+
+// GatewayContract is a Contract reached through the Fabric gateway
+type GatewayContract struct {
+	Contract *gateway.Contract
+}
+
+// Submit submits a transaction to the orderer and waits for it to commit
+func (c GatewayContract) Submit(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	tx, err := c.transaction(name, transient)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Submit(args...)
+}
+
+// Evaluate runs a transaction on a peer without submitting it
+func (c GatewayContract) Evaluate(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	tx, err := c.transaction(name, transient)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Evaluate(args...)
+}
+
+func (c GatewayContract) transaction(name string, transient map[string][]byte) (*gateway.Transaction, error) {
+	var opts []gateway.TransactionOption
+	if len(transient) > 0 {
+		opts = append(opts, gateway.WithTransient(transient))
+	}
+	tx, err := c.Contract.CreateTransaction(name, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+	return tx, nil
+}
+
+// LoadMetadata evaluates MetadataFunction to read the contract metadata
+func LoadMetadata(contract Contract) (*Metadata, error) {
+	data, err := contract.Evaluate(MetadataFunction, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract metadata: %v", err)
+	}
+	return ParseMetadata(data)
+}
+
+// SubmitRequest is the body of a request to submit a transaction. Arguments
+// are named after the parameters in the contract metadata. String arguments
+// and transient values are sent as is, and other values as JSON.
+type SubmitRequest struct {
+	Arguments map[string]json.RawMessage `json:"arguments,omitempty"`
+	Transient map[string]json.RawMessage `json:"transient,omitempty"`
+}
+
+// ErrorResponse is the body of a failed request
+type ErrorResponse struct {
+	Error string         `json:"error"`
+	Code  errorcode.Code `json:"code,omitempty"`
+}
+
+// Server is an http.Handler that invokes the functions of a contract
+type Server struct {
+	contract Contract
+	metadata *Metadata
+	document []byte
+}
+
+// NewServer returns a Server for a contract with the given metadata
+func NewServer(contract Contract, metadata *Metadata) (*Server, error) {
+	document, err := json.MarshalIndent(NewDocument(metadata), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenAPI document: %v", err)
+	}
+	return &Server{contract: contract, metadata: metadata, document: document}, nil
+}
+
+// ServeHTTP routes a request to the OpenAPI document or a transaction function
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == OpenAPIPath:
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(s.document)
+	case strings.HasPrefix(r.URL.Path, TransactionsPath):
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		s.submit(w, r, strings.TrimPrefix(r.URL.Path, TransactionsPath))
+	case strings.HasPrefix(r.URL.Path, QueryPath):
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		s.evaluate(w, r, strings.TrimPrefix(r.URL.Path, QueryPath))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route for %s", r.URL.Path))
+	}
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, name string) {
+	fn, ok := s.metadata.Function(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("the contract does not have a function %s", name))
+		return
+	}
+
+	var request SubmitRequest
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			// MaxBytesReader fails with an untyped error before Go 1.19
+			if strings.Contains(err.Error(), "request body too large") {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the request body is larger than %d bytes", maxRequestBytes))
+				return
+			}
+			writeError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request body: %v", err))
+			return
+		}
+	}
+
+	args := make(map[string]string, len(request.Arguments))
+	for name, value := range request.Arguments {
+		args[name] = argument(value)
+	}
+	ordered, err := orderArguments(fn, args)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var transient map[string][]byte
+	if len(request.Transient) > 0 {
+		transient = make(map[string][]byte, len(request.Transient))
+		for key, value := range request.Transient {
+			transient[key] = []byte(argument(value))
+		}
+	}
+
+	result, err := s.contract.Submit(fn.Name, transient, ordered...)
+	s.respond(w, fn, result, err)
+}
+
+func (s *Server) evaluate(w http.ResponseWriter, r *http.Request, name string) {
+	fn, ok := s.metadata.Function(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("the contract does not have a function %s", name))
+		return
+	}
+
+	args := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		if len(values) != 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("argument %s must be given once", name))
+			return
+		}
+		args[name] = values[0]
+	}
+	ordered, err := orderArguments(fn, args)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := s.contract.Evaluate(fn.Name, nil, ordered...)
+	s.respond(w, fn, result, err)
+}
+
+// respond writes the result of a transaction as JSON. Functions returning a
+// string send it without encoding, so it is quoted here.
+func (s *Server) respond(w http.ResponseWriter, fn Function, result []byte, err error) {
+	if err != nil {
+		if coded, ok := errorcode.FromError(err); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusOf(coded.Code))
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: coded.Message, Code: coded.Code})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if fn.Returns == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if fn.ReturnsString() || !json.Valid(result) {
+		result, err = json.Marshal(string(result))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(result)
+}
+
+// orderArguments returns the arguments in the order of the function's
+// parameters
+func orderArguments(fn Function, args map[string]string) ([]string, error) {
+	ordered := make([]string, 0, len(fn.Parameters))
+	for _, param := range fn.Parameters {
+		value, ok := args[param.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing argument %s", param.Name)
+		}
+		ordered = append(ordered, value)
+		delete(args, param.Name)
+	}
+	if len(args) > 0 {
+		unknown := make([]string, 0, len(args))
+		for name := range args {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s does not have a parameter %s", fn.Name, unknown[0])
+	}
+	return ordered, nil
+}
+
+// argument returns the chaincode argument for a JSON value, which is the
+// string itself for a JSON string
+func argument(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+func statusOf(code errorcode.Code) int {
+	switch code {
+	case errorcode.NotFound:
+		return http.StatusNotFound
+	case errorcode.AlreadyExists, errorcode.Conflict:
+		return http.StatusConflict
+	case errorcode.Unauthorized:
+		return http.StatusForbidden
+	case errorcode.Validation:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed on %s", r.Method, r.URL.Path))
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,179 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restgateway_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/test-application/go/restgateway"
+	"github.com/stretchr/testify/require"
+)
+
+// invocation is a call received by fakeContract
+type invocation struct {
+	submit    bool
+	name      string
+	transient map[string][]byte
+	args      []string
+}
+
+type fakeContract struct {
+	invocations []invocation
+	result      []byte
+	err         error
+}
+
+func (c *fakeContract) Submit(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	c.invocations = append(c.invocations, invocation{submit: true, name: name, transient: transient, args: args})
+	return c.result, c.err
+}
+
+func (c *fakeContract) Evaluate(name string, transient map[string][]byte, args ...string) ([]byte, error) {
+	if name == restgateway.MetadataFunction {
+		return []byte(metadata), nil
+	}
+	c.invocations = append(c.invocations, invocation{name: name, transient: transient, args: args})
+	return c.result, c.err
+}
+
+func newServer(t *testing.T) (*restgateway.Server, *fakeContract) {
+	contract := &fakeContract{}
+	m, err := restgateway.LoadMetadata(contract)
+	require.NoError(t, err)
+	server, err := restgateway.NewServer(contract, m)
+	require.NoError(t, err)
+	return server, contract
+}
+
+func serve(server http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestSubmit(t *testing.T) {
+	server, contract := newServer(t)
+
+	response := serve(server, http.MethodPost, "/transactions/CreateAsset", `{
+		"arguments": {"param0": "asset1", "param1": 5, "param2": {"holder": "Tom"}},
+		"transient": {"secret": "value", "properties": {"price": 10}}
+	}`)
+	require.Equal(t, http.StatusNoContent, response.Code, response.Body.String())
+	require.Equal(t, []invocation{{
+		submit:    true,
+		name:      "CreateAsset",
+		transient: map[string][]byte{"secret": []byte("value"), "properties": []byte(`{"price": 10}`)},
+		args:      []string{"asset1", "5", `{"holder": "Tom"}`},
+	}}, contract.invocations)
+
+	contract.result = []byte(`{"ID":"asset1","size":5}`)
+	response = serve(server, http.MethodPost, "/transactions/ReadAsset", `{"arguments": {"param0": "asset1"}}`)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"ID":"asset1","size":5}`, response.Body.String())
+	require.Nil(t, contract.invocations[1].transient)
+
+	contract.result = []byte("v1.0")
+	response = serve(server, http.MethodPost, "/transactions/Version", "")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, `"v1.0"`, response.Body.String())
+
+	response = serve(server, http.MethodPost, "/transactions/Admin:Pause", `{"arguments": {"param0": true}}`)
+	require.Equal(t, http.StatusNoContent, response.Code)
+	require.Equal(t, []string{"true"}, contract.invocations[3].args)
+}
+
+func TestEvaluate(t *testing.T) {
+	server, contract := newServer(t)
+
+	contract.result = []byte("true")
+	response := serve(server, http.MethodGet, "/query/AssetExists?param0=asset1", "")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, "true", response.Body.String())
+	require.Equal(t, []invocation{{name: "AssetExists", args: []string{"asset1"}}}, contract.invocations)
+
+	contract.result = []byte("[]")
+	response = serve(server, http.MethodGet, "/query/GetAllAssets", "")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, "[]", response.Body.String())
+}
+
+func TestInvalidRequests(t *testing.T) {
+	server, contract := newServer(t)
+
+	for _, tc := range []struct {
+		method  string
+		target  string
+		body    string
+		status  int
+		message string
+	}{
+		{http.MethodPost, "/transactions/Unknown", "", http.StatusNotFound, "the contract does not have a function Unknown"},
+		{http.MethodGet, "/query/Pause", "", http.StatusNotFound, "the contract does not have a function Pause"},
+		{http.MethodGet, "/transactions/ReadAsset", "", http.StatusMethodNotAllowed, "GET is not allowed on /transactions/ReadAsset"},
+		{http.MethodPost, "/query/ReadAsset", "", http.StatusMethodNotAllowed, "POST is not allowed on /query/ReadAsset"},
+		{http.MethodGet, "/assets", "", http.StatusNotFound, "no route for /assets"},
+		{http.MethodPost, "/transactions/ReadAsset", `{}`, http.StatusBadRequest, "missing argument param0"},
+		{http.MethodPost, "/transactions/ReadAsset", `{"arguments": {"param0": "a", "id": "a"}}`, http.StatusBadRequest, "ReadAsset does not have a parameter id"},
+		{http.MethodPost, "/transactions/ReadAsset", `{"args": {}}`, http.StatusBadRequest, `failed to parse request body: json: unknown field "args"`},
+		{http.MethodGet, "/query/ReadAsset?param0=a&param0=b", "", http.StatusBadRequest, "argument param0 must be given once"},
+	} {
+		response := serve(server, tc.method, tc.target, tc.body)
+		require.Equal(t, tc.status, response.Code, tc.target)
+		var body restgateway.ErrorResponse
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		require.Equal(t, tc.message, body.Error)
+	}
+	require.Empty(t, contract.invocations)
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	server, contract := newServer(t)
+
+	body := `{"arguments": {"param0": "` + strings.Repeat("a", 1<<20) + `"}}`
+	response := serve(server, http.MethodPost, "/transactions/ReadAsset", body)
+	require.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	var errorBody restgateway.ErrorResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &errorBody))
+	require.Equal(t, "the request body is larger than 1048576 bytes", errorBody.Error)
+	require.Empty(t, contract.invocations)
+}
+
+func TestErrors(t *testing.T) {
+	server, contract := newServer(t)
+
+	contract.err = fmt.Errorf("Failed to evaluate: %v", errorcode.New(errorcode.NotFound, "the asset asset1 does not exist"))
+	response := serve(server, http.MethodGet, "/query/ReadAsset?param0=asset1", "")
+	require.Equal(t, http.StatusNotFound, response.Code)
+	require.JSONEq(t, `{"error": "the asset asset1 does not exist", "code": "NOT_FOUND"}`, response.Body.String())
+
+	contract.err = errorcode.New(errorcode.AlreadyExists, "the asset asset1 already exists")
+	response = serve(server, http.MethodPost, "/transactions/ReadAsset", `{"arguments": {"param0": "asset1"}}`)
+	require.Equal(t, http.StatusConflict, response.Code)
+
+	contract.err = errors.New("endorsement failure")
+	response = serve(server, http.MethodPost, "/transactions/ReadAsset", `{"arguments": {"param0": "asset1"}}`)
+	require.Equal(t, http.StatusInternalServerError, response.Code)
+	require.JSONEq(t, `{"error": "endorsement failure"}`, response.Body.String())
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	server, _ := newServer(t)
+
+	response := serve(server, http.MethodGet, restgateway.OpenAPIPath, "")
+	require.Equal(t, http.StatusOK, response.Code)
+	var doc restgateway.Document
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &doc))
+	require.Equal(t, restgateway.OpenAPIVersion, doc.OpenAPI)
+	require.Contains(t, doc.Paths, "/transactions/CreateAsset")
+}
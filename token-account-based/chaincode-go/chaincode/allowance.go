@@ -0,0 +1,123 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+)
+
+// allowancePrefix is the object type of the allowance keys, which are keyed by owner and spender
+const allowancePrefix = "allowance"
+
+// Approve allows spender to transfer up to amount tokens from the client's account,
+// replacing any earlier allowance of the spender
+func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, spender string, amount int) error {
+	if amount < 0 {
+		return errorcode.New(errorcode.Validation, "allowance cannot be negative")
+	}
+
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	err = writeAllowance(ctx, owner, spender, amount)
+	if err != nil {
+		return err
+	}
+
+	logger.ForTransaction(ctx).Infof("client %s approved %d tokens for spender %s", owner, amount, spender)
+	return nil
+}
+
+// Allowance returns the amount of tokens spender may still transfer from owner's account
+func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, error) {
+	allowance, exists, err := readAllowance(ctx, owner, spender)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errorcode.New(errorcode.NotFound, "spender %s has no allowance from %s", spender, owner)
+	}
+
+	return allowance, nil
+}
+
+// TransferFrom transfers tokens from sender's account to recipient's account on behalf of the sender,
+// and decreases the allowance the sender gave the client.
+// It can be invoked by another chaincode, in which case the client is the one that submitted the transaction.
+func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface, sender string, recipient string, amount int) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.transferFrom(ctx, sender, recipient, amount)
+	})
+}
+
+func (s *SmartContract) transferFrom(ctx contractapi.TransactionContextInterface, sender string, recipient string, amount int) error {
+	if amount < 0 {
+		return errorcode.New(errorcode.Validation, "transfer amount cannot be negative")
+	}
+
+	spender, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	allowance, exists, err := readAllowance(ctx, sender, spender)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errorcode.New(errorcode.Unauthorized, "spender %s has no allowance from %s", spender, sender)
+	}
+	if allowance < amount {
+		return errorcode.New(errorcode.Conflict, "spender %s has an allowance of %d from %s, which is less than %d", spender, allowance, sender, amount)
+	}
+
+	err = moveBalance(ctx, sender, recipient, amount)
+	if err != nil {
+		return err
+	}
+
+	err = writeAllowance(ctx, sender, spender, allowance-amount)
+	if err != nil {
+		return err
+	}
+
+	logger.ForTransaction(ctx).Infof("spender %s allowance from %s updated from %d to %d", spender, sender, allowance, allowance-amount)
+	return nil
+}
+
+// readAllowance returns the allowance owner gave spender, and whether there is one
+func readAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, bool, error) {
+	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	allowanceBytes, err := ctx.GetStub().GetState(allowanceKey)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read allowance: %v", err)
+	}
+	if allowanceBytes == nil {
+		return 0, false, nil
+	}
+
+	allowance, err := strconv.Atoi(string(allowanceBytes))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid allowance %q: %v", allowanceBytes, err)
+	}
+
+	return allowance, true, nil
+}
+
+func writeAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string, allowance int) error {
+	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(allowanceKey, []byte(strconv.Itoa(allowance)))
+}
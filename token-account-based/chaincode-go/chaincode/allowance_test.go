@@ -0,0 +1,127 @@
+package chaincode_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/hyperledger/fabric-samples/token-account-based/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// newCertificate returns a self-signed certificate for a client
+func newCertificate(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestApprove(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+
+	require.NoError(t, token.Approve(transactionContext, "spender", 40))
+	chaincodeStub.Commit()
+	allowance, err := token.Allowance(transactionContext, minter, "spender")
+	require.NoError(t, err)
+	require.Equal(t, 40, allowance)
+
+	require.NoError(t, token.Approve(transactionContext, "spender", 25))
+	chaincodeStub.Commit()
+	allowance, err = token.Allowance(transactionContext, minter, "spender")
+	require.NoError(t, err)
+	require.Equal(t, 25, allowance, "an approval replaces the earlier allowance")
+
+	err = token.Approve(transactionContext, "spender", -1)
+	require.Equal(t, errorcode.Validation, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+
+	_, err = token.Allowance(transactionContext, minter, "bob")
+	require.Equal(t, errorcode.NotFound, errorcode.CodeOf(err))
+}
+
+func TestTransferFrom(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+
+	require.NoError(t, token.Approve(transactionContext, "spender", 40))
+	chaincodeStub.Commit()
+
+	asClient(transactionContext, "spender", "Org2MSP")
+	require.NoError(t, token.TransferFrom(transactionContext, minter, "bob", 30))
+	chaincodeStub.Commit()
+	requireBalance(t, transactionContext, minter, 70)
+	requireBalance(t, transactionContext, "bob", 30)
+	allowance, err := token.Allowance(transactionContext, minter, "spender")
+	require.NoError(t, err)
+	require.Equal(t, 10, allowance, "the allowance is decremented")
+
+	err = token.TransferFrom(transactionContext, minter, "bob", 20)
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+
+	asClient(transactionContext, "mallory", "Org2MSP")
+	err = token.TransferFrom(transactionContext, minter, "mallory", 1)
+	require.Equal(t, errorcode.Unauthorized, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+
+	requireBalance(t, transactionContext, minter, 70)
+	requireBalance(t, transactionContext, "bob", 30)
+}
+
+func TestTransferFromInvokedByChaincode(t *testing.T) {
+	caller := stubtest.New()
+	tokenCC, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+	tokenStub := caller.RegisterChaincode("token", tokenCC)
+
+	transactionContext := &contractapi.TransactionContext{}
+	transactionContext.SetStub(tokenStub)
+	asClient(transactionContext, minter, "Org1MSP")
+	token := chaincode.SmartContract{}
+	require.NoError(t, token.Mint(transactionContext, 100))
+	caller.Commit()
+
+	// the token chaincode sees the client that submitted the calling transaction
+	require.NoError(t, caller.SetCreator("Org2MSP", newCertificate(t, "seller")))
+	spender, err := cid.GetID(tokenStub)
+	require.NoError(t, err)
+	require.NoError(t, token.Approve(transactionContext, spender, 40))
+	caller.Commit()
+
+	response := caller.InvokeChaincode("token", [][]byte{[]byte("TransferFrom"), []byte(minter), []byte("bob"), []byte("30")}, "")
+	require.EqualValues(t, shim.OK, response.Status, response.Message)
+	caller.Commit()
+	requireBalance(t, transactionContext, minter, 70)
+	requireBalance(t, transactionContext, "bob", 30)
+	allowance, err := token.Allowance(transactionContext, minter, spender)
+	require.NoError(t, err)
+	require.Equal(t, 10, allowance)
+
+	response = caller.InvokeChaincode("token", [][]byte{[]byte("TransferFrom"), []byte("bob"), []byte("carol"), []byte("1")}, "")
+	require.EqualValues(t, shim.ERROR, response.Status)
+	require.Contains(t, response.Message, "has no allowance from bob")
+	caller.Rollback()
+	requireBalance(t, transactionContext, "bob", 30)
+}
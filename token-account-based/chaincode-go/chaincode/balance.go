@@ -0,0 +1,32 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// readBalance returns the balance of an account, and whether the account exists.
+// Balances are stored as plain integers.
+func readBalance(ctx contractapi.TransactionContextInterface, account string) (int, bool, error) {
+	balanceBytes, err := ctx.GetStub().GetState(account)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	if balanceBytes == nil {
+		return 0, false, nil
+	}
+
+	balance, err := strconv.Atoi(string(balanceBytes))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid balance of account %s: %v", account, err)
+	}
+
+	return balance, true, nil
+}
+
+// writeBalance stores the balance of an account
+func writeBalance(ctx contractapi.TransactionContextInterface, account string, balance int) error {
+	return ctx.GetStub().PutState(account, []byte(strconv.Itoa(balance)))
+}
@@ -0,0 +1,32 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/token-account-based/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceEncoding(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+
+	require.NoError(t, chaincodeStub.PutState("alice", []byte("25")))
+	require.NoError(t, chaincodeStub.PutState("carol", []byte("not a number")))
+	chaincodeStub.Commit()
+	requireBalance(t, transactionContext, "alice", 25)
+
+	asClient(transactionContext, "alice", "Org1MSP")
+	require.NoError(t, token.Transfer(transactionContext, "bob", 5))
+	chaincodeStub.Commit()
+
+	balanceBytes, err := chaincodeStub.GetState("bob")
+	require.NoError(t, err)
+	require.Equal(t, "5", string(balanceBytes))
+	balanceBytes, err = chaincodeStub.GetState("alice")
+	require.NoError(t, err)
+	require.Equal(t, "20", string(balanceBytes))
+
+	_, err = token.BalanceOf(transactionContext, "carol")
+	require.EqualError(t, err, `invalid balance of account carol: strconv.Atoi: parsing "not a number": invalid syntax`)
+}
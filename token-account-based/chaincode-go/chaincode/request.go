@@ -0,0 +1,36 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+)
+
+// requestRetention is how long the outcome of a request submitted with a
+// request ID is kept, so that clients can retry it safely
+const requestRetention = 7 * 24 * time.Hour
+
+// pruneBatchSize is the most request records PruneRequests deletes at once
+const pruneBatchSize = 100
+
+// GetRequest returns the recorded outcome of a request that the calling
+// client submitted with a request ID in its transient data
+func (s *SmartContract) GetRequest(ctx contractapi.TransactionContextInterface, requestID string) (*idempotency.Record, error) {
+	record, err := idempotency.Get(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errorcode.New(errorcode.NotFound, "the request %s has no record", requestID)
+	}
+
+	return record, nil
+}
+
+// PruneRequests deletes up to 100 request records older than the retention
+// window and returns how many it deleted
+func (s *SmartContract) PruneRequests(ctx contractapi.TransactionContextInterface) (int, error) {
+	return idempotency.Prune(ctx.GetStub(), requestRetention, pruneBatchSize)
+}
@@ -0,0 +1,67 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/hyperledger/fabric-samples/token-account-based/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func withRequestID(chaincodeStub *stubtest.ChaincodeStub, requestID string, args ...string) {
+	chaincodeStub.SetArgs(args...)
+	chaincodeStub.SetTransient(map[string][]byte{idempotency.TransientKey: []byte(requestID)})
+}
+
+func TestTransferRetry(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+
+	withRequestID(chaincodeStub, "transfer-0001", "Transfer", "alice", "10")
+	require.NoError(t, token.Transfer(transactionContext, "alice", 10))
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "transfer-0001", "Transfer", "alice", "10")
+	require.NoError(t, token.Transfer(transactionContext, "alice", 10), "a retry succeeds")
+	chaincodeStub.Commit()
+	requireBalance(t, transactionContext, minter, 90)
+	requireBalance(t, transactionContext, "alice", 10)
+
+	withRequestID(chaincodeStub, "transfer-0001", "Transfer", "alice", "20")
+	err := token.Transfer(transactionContext, "alice", 20)
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err), "the request ID was used for a different transfer")
+	chaincodeStub.Rollback()
+
+	record, err := token.GetRequest(transactionContext, "transfer-0001")
+	require.NoError(t, err)
+	require.Equal(t, minter, record.ClientID)
+	require.Equal(t, "Transfer", record.Function)
+}
+
+func TestTransferRequestIDPerClient(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+	require.NoError(t, token.Transfer(transactionContext, "bob", 50))
+	chaincodeStub.Commit()
+
+	withRequestID(chaincodeStub, "1", "Transfer", "alice", "10")
+	require.NoError(t, token.Transfer(transactionContext, "alice", 10))
+	chaincodeStub.Commit()
+
+	asClient(transactionContext, "bob", "Org2MSP")
+	withRequestID(chaincodeStub, "1", "Transfer", "alice", "10")
+	require.NoError(t, token.Transfer(transactionContext, "alice", 10))
+	chaincodeStub.Commit()
+
+	requireBalance(t, transactionContext, minter, 40)
+	requireBalance(t, transactionContext, "bob", 40)
+	requireBalance(t, transactionContext, "alice", 20)
+
+	_, err := token.GetRequest(transactionContext, "2")
+	require.Equal(t, errorcode.NotFound, errorcode.CodeOf(err))
+	record, err := token.GetRequest(transactionContext, "1")
+	require.NoError(t, err)
+	require.Equal(t, "bob", record.ClientID)
+}
@@ -2,15 +2,19 @@ package chaincode
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/idempotency"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/logging"
 )
 
 // Define key names for options
 const totalSupplyKey = "totalSupply"
 
+var logger = logging.New("token-account-based")
+
 // SmartContract provides functions for transferring tokens between accounts
 type SmartContract struct {
 	contractapi.Contract
@@ -18,6 +22,12 @@ type SmartContract struct {
 
 // Mint creates new tokens and adds them to minter's account balance
 func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.mint(ctx, amount)
+	})
+}
+
+func (s *SmartContract) mint(ctx contractapi.TransactionContextInterface, amount int) error {
 
 	// Check minter authorization - this sample assumes Org1 is the central banker with privilege to mint new tokens
 	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
@@ -25,7 +35,7 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		return fmt.Errorf("failed to get MSPID: %v", err)
 	}
 	if clientMSPID != "Org1MSP" {
-		return fmt.Errorf("client is not authorized to mint new tokens")
+		return errorcode.New(errorcode.Unauthorized, "client is not authorized to mint new tokens")
 	}
 
 	// Get ID of submitting client identity
@@ -35,31 +45,23 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 	}
 
 	if amount <= 0 {
-		return fmt.Errorf("mint amount must be a positive integer")
+		return errorcode.New(errorcode.Validation, "mint amount must be a positive integer")
 	}
 
-	currentBalanceBytes, err := ctx.GetStub().GetState(minter)
-	if err != nil {
-		return fmt.Errorf("failed to read minter account %s from world state: %v", minter, err)
-	}
-
-	var currentBalance int
-
 	// If minter current balance doesn't yet exist, we'll create it with a current balance of 0
-	if currentBalanceBytes == nil {
-		currentBalance = 0
-	} else {
-		currentBalance, _ = strconv.Atoi(string(currentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
+	currentBalance, _, err := readBalance(ctx, minter)
+	if err != nil {
+		return err
 	}
 
 	updatedBalance := currentBalance + amount
 
-	err = ctx.GetStub().PutState(minter, []byte(strconv.Itoa(updatedBalance)))
+	err = writeBalance(ctx, minter, updatedBalance)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
+	logger.ForTransaction(ctx).Infof("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
 
 	// Update the totalSupply
 	totalSupplyBytes, err := ctx.GetStub().GetState(totalSupplyKey)
@@ -85,10 +87,17 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 
 // Transfer transfers tokens from client account to recipient account.
 // recipient account must be a valid clientID as returned by the ClientID() function.
+// A retry with the request ID of a transfer that succeeded does not transfer the tokens again.
 func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, recipient string, amount int) error {
+	return idempotency.Do(ctx, nil, func() error {
+		return s.transfer(ctx, recipient, amount)
+	})
+}
+
+func (s *SmartContract) transfer(ctx contractapi.TransactionContextInterface, recipient string, amount int) error {
 
 	if amount < 0 { // transfer of 0 is allowed in ERC20, so just validate against negative amounts
-		return fmt.Errorf("transfer amount cannot be negative")
+		return errorcode.New(errorcode.Validation, "transfer amount cannot be negative")
 	}
 
 	// Get ID of submitting client identity
@@ -97,65 +106,63 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, re
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	clientCurrentBalanceBytes, err := ctx.GetStub().GetState(clientID)
-	if err != nil {
-		return fmt.Errorf("failed to read client account %s from world state: %v", clientID, err)
-	}
+	return moveBalance(ctx, clientID, recipient, amount)
+}
 
-	if clientCurrentBalanceBytes == nil {
-		return fmt.Errorf("client account %s has no balance", clientID)
+// moveBalance debits the sender's account and credits the recipient's account
+func moveBalance(ctx contractapi.TransactionContextInterface, sender string, recipient string, amount int) error {
+	if sender == recipient {
+		return errorcode.New(errorcode.Validation, "cannot transfer to and from the same account %s", sender)
 	}
 
-	clientCurrentBalance, _ := strconv.Atoi(string(clientCurrentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
-
-	if clientCurrentBalance < amount {
-		return fmt.Errorf("client account %s has insufficient funds", clientID)
+	senderCurrentBalance, exists, err := readBalance(ctx, sender)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errorcode.New(errorcode.NotFound, "client account %s has no balance", sender)
 	}
 
-	recipientCurrentBalanceBytes, err := ctx.GetStub().GetState(recipient)
-	if err != nil {
-		return fmt.Errorf("failed to read recipient account %s from world state: %v", recipient, err)
+	if senderCurrentBalance < amount {
+		return errorcode.New(errorcode.Conflict, "client account %s has insufficient funds", sender)
 	}
 
-	var recipientCurrentBalance int
 	// If recipient current balance doesn't yet exist, we'll create it with a current balance of 0
-	if recipientCurrentBalanceBytes == nil {
-		recipientCurrentBalance = 0
-	} else {
-		recipientCurrentBalance, _ = strconv.Atoi(string(recipientCurrentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
+	recipientCurrentBalance, _, err := readBalance(ctx, recipient)
+	if err != nil {
+		return err
 	}
 
-	clientUpdatedBalance := clientCurrentBalance - amount
+	senderUpdatedBalance := senderCurrentBalance - amount
 	recipientUpdatedBalance := recipientCurrentBalance + amount
 
-	err = ctx.GetStub().PutState(clientID, []byte(strconv.Itoa(clientUpdatedBalance)))
+	err = writeBalance(ctx, sender, senderUpdatedBalance)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.GetStub().PutState(recipient, []byte(strconv.Itoa(recipientUpdatedBalance)))
+	err = writeBalance(ctx, recipient, recipientUpdatedBalance)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("client %s balance updated from %d to %d", clientID, clientCurrentBalance, clientUpdatedBalance)
-	log.Printf("recipient %s balance updated from %d to %d", recipient, recipientCurrentBalance, recipientUpdatedBalance)
+	log := logger.ForTransaction(ctx)
+	log.Infof("client %s balance updated from %d to %d", sender, senderCurrentBalance, senderUpdatedBalance)
+	log.Infof("recipient %s balance updated from %d to %d", recipient, recipientCurrentBalance, recipientUpdatedBalance)
 
 	return nil
 }
 
 // BalanceOf returns the balance of the given account
 func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, account string) (int, error) {
-	balanceBytes, err := ctx.GetStub().GetState(account)
+	balance, exists, err := readBalance(ctx, account)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read from world state: %v", err)
+		return 0, err
 	}
-	if balanceBytes == nil {
-		return 0, fmt.Errorf("the account %s does not exist", account)
+	if !exists {
+		return 0, errorcode.New(errorcode.NotFound, "the account %s does not exist", account)
 	}
 
-	balance, _ := strconv.Atoi(string(balanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
-
 	return balance, nil
 }
 
@@ -168,16 +175,14 @@ func (s *SmartContract) ClientAccountBalance(ctx contractapi.TransactionContextI
 		return 0, fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	balanceBytes, err := ctx.GetStub().GetState(clientID)
+	balance, exists, err := readBalance(ctx, clientID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read from world state: %v", err)
+		return 0, err
 	}
-	if balanceBytes == nil {
-		return 0, fmt.Errorf("the account %s does not exist", clientID)
+	if !exists {
+		return 0, errorcode.New(errorcode.NotFound, "the account %s does not exist", clientID)
 	}
 
-	balance, _ := strconv.Atoi(string(balanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
-
 	return balance, nil
 }
 
@@ -213,7 +218,7 @@ func (s *SmartContract) TotalSupply(ctx contractapi.TransactionContextInterface)
 		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
 	}
 
-	log.Printf("The totalSupply was queried: %d total tokens", totalSupply)
+	logger.ForTransaction(ctx).Debugf("The totalSupply was queried: %d total tokens", totalSupply)
 	return totalSupply, nil
 
 }
@@ -0,0 +1,72 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/stubtest"
+	"github.com/hyperledger/fabric-samples/token-account-based/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const minter = "minter"
+
+type clientIdentity struct {
+	cid.ClientIdentity
+	id    string
+	mspID string
+}
+
+func (c clientIdentity) GetID() (string, error)    { return c.id, nil }
+func (c clientIdentity) GetMSPID() (string, error) { return c.mspID, nil }
+
+// prepLedger returns a transaction context backed by an in-memory world state
+// in which the Org1 minter holds balance tokens
+func prepLedger(t *testing.T, balance int) (*contractapi.TransactionContext, *stubtest.ChaincodeStub) {
+	chaincodeStub := stubtest.New()
+	transactionContext := &contractapi.TransactionContext{}
+	transactionContext.SetStub(chaincodeStub)
+
+	asClient(transactionContext, minter, "Org1MSP")
+	require.NoError(t, (&chaincode.SmartContract{}).Mint(transactionContext, balance))
+	chaincodeStub.Commit()
+
+	return transactionContext, chaincodeStub
+}
+
+// asClient makes the client with the given ID and MSP submit the later transactions
+func asClient(transactionContext *contractapi.TransactionContext, clientID string, mspID string) {
+	transactionContext.SetClientIdentity(clientIdentity{id: clientID, mspID: mspID})
+}
+
+// requireBalance checks the balance of an account
+func requireBalance(t *testing.T, transactionContext *contractapi.TransactionContext, account string, expected int) {
+	balance, err := (&chaincode.SmartContract{}).BalanceOf(transactionContext, account)
+	require.NoError(t, err)
+	require.Equal(t, expected, balance, account)
+}
+
+func TestTransfer(t *testing.T) {
+	transactionContext, chaincodeStub := prepLedger(t, 100)
+	token := chaincode.SmartContract{}
+
+	require.NoError(t, token.Transfer(transactionContext, "alice", 30))
+	chaincodeStub.Commit()
+	requireBalance(t, transactionContext, minter, 70)
+	requireBalance(t, transactionContext, "alice", 30)
+
+	err := token.Transfer(transactionContext, "alice", 80)
+	require.Equal(t, errorcode.Conflict, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+
+	err = token.Transfer(transactionContext, minter, 10)
+	require.Equal(t, errorcode.Validation, errorcode.CodeOf(err))
+	chaincodeStub.Rollback()
+	requireBalance(t, transactionContext, minter, 70)
+
+	asClient(transactionContext, "bob", "Org2MSP")
+	err = token.Transfer(transactionContext, "alice", 1)
+	require.Equal(t, errorcode.NotFound, errorcode.CodeOf(err))
+}
@@ -2,4 +2,11 @@ module github.com/hyperledger/fabric-samples/token-account-based/chaincode-go
 
 go 1.14
 
-require github.com/hyperledger/fabric-contract-api-go v1.1.0
+require (
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
+	github.com/hyperledger/fabric-contract-api-go v1.1.0
+	github.com/hyperledger/fabric-samples/chaincode-shared/go v0.0.0
+	github.com/stretchr/testify v1.5.1
+)
+
+replace github.com/hyperledger/fabric-samples/chaincode-shared/go => ../../chaincode-shared/go
@@ -2,12 +2,15 @@ package chaincode
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/errorcode"
+	"github.com/hyperledger/fabric-samples/chaincode-shared/go/logging"
 )
 
+var logger = logging.New("token-utxo")
+
 // SmartContract provides functions for transferring tokens using UTXO transactions
 type SmartContract struct {
 	contractapi.Contract
@@ -29,7 +32,7 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		return nil, fmt.Errorf("failed to get MSPID: %v", err)
 	}
 	if clientMSPID != "Org1MSP" {
-		return nil, fmt.Errorf("client is not authorized to mint new tokens")
+		return nil, errorcode.New(errorcode.Unauthorized, "client is not authorized to mint new tokens")
 	}
 
 	// Get ID of submitting client identity
@@ -39,7 +42,7 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 	}
 
 	if amount <= 0 {
-		return nil, fmt.Errorf("mint amount must be a positive integer")
+		return nil, errorcode.New(errorcode.Validation, "mint amount must be a positive integer")
 	}
 
 	utxo := UTXO{}
@@ -55,7 +58,7 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		return nil, err
 	}
 
-	log.Printf("utxo minted: %+v", utxo)
+	logger.ForTransaction(ctx).Infof("utxo minted: %+v", utxo)
 
 	return &utxo, nil
 }
@@ -85,7 +88,7 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 		}
 
 		if valueBytes == nil {
-			return nil, fmt.Errorf("utxoInput %s not found for client %s", utxoInputKey, clientID)
+			return nil, errorcode.New(errorcode.NotFound, "utxoInput %s not found for client %s", utxoInputKey, clientID)
 		}
 
 		amount, _ := strconv.Atoi(string(valueBytes)) // Error handling not needed since Itoa() was used when setting the utxo amount, guaranteeing it was an integer.
@@ -106,7 +109,7 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 	for i, utxoOutput := range utxoOutputs {
 
 		if utxoOutput.Amount <= 0 {
-			return nil, fmt.Errorf("utxo output amount must be a positive integer")
+			return nil, errorcode.New(errorcode.Validation, "utxo output amount must be a positive integer")
 		}
 
 		utxoOutputs[i].Key = fmt.Sprintf("%s.%d", txID, i)
@@ -116,9 +119,11 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 
 	// Validate total inputs equals total outputs
 	if totalInputAmount != totalOutputAmount {
-		return nil, fmt.Errorf("total utxoInput amount %d does not equal total utxoOutput amount %d", totalInputAmount, totalOutputAmount)
+		return nil, errorcode.New(errorcode.Validation, "total utxoInput amount %d does not equal total utxoOutput amount %d", totalInputAmount, totalOutputAmount)
 	}
 
+	log := logger.ForTransaction(ctx)
+
 	// Since the transaction is valid, now delete utxo inputs from owner's state
 	for _, utxoInput := range utxoInputs {
 
@@ -131,7 +136,7 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 		if err != nil {
 			return nil, err
 		}
-		log.Printf("utxoInput deleted: %+v", utxoInput)
+		log.Infof("utxoInput deleted: %+v", utxoInput)
 	}
 
 	// Create utxo outputs using a composite key based on the owner and utxo key
@@ -145,7 +150,7 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 		if err != nil {
 			return nil, err
 		}
-		log.Printf("utxoOutput created: %+v", utxoOutput)
+		log.Infof("utxoOutput created: %+v", utxoOutput)
 	}
 
 	return utxoOutputs, nil